@@ -0,0 +1,101 @@
+// Package codec translates the API's JSON documents to and from the other
+// wire formats clients may negotiate.
+//
+// Every format goes through JSON: responses are encoded from the JSON the
+// handlers produce, and request bodies are decoded into JSON before the
+// handlers unmarshal them. A type's json tags therefore name its fields in
+// every format, and nothing outside this package needs to know more than
+// one encoding.
+package codec
+
+import (
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Codec is one wire format
+type Codec interface {
+	// ContentType is the media type of encoded documents
+	ContentType() string
+	// Encode writes the JSON document data in this format
+	Encode(w io.Writer, data []byte) error
+	// Decode translates body into JSON. Formats without JSON's value types
+	// use v, the value the JSON will be unmarshaled into, to decide which
+	// values are numbers and booleans.
+	Decode(body []byte, v interface{}) ([]byte, error)
+}
+
+var (
+	// JSON is the API's native format; it passes documents through
+	JSON Codec = jsonCodec{}
+	// XML represents objects as elements named by their keys and arrays as
+	// repeated <item> elements, see xmlCodec
+	XML Codec = xmlCodec{}
+	// MsgPack is binary MessagePack, for responses only, see msgpackCodec
+	MsgPack Codec = msgpackCodec{}
+)
+
+// byMediaType maps the media types clients may ask for to their codec
+var byMediaType = map[string]Codec{
+	"application/json":      JSON,
+	"application/xml":       XML,
+	"text/xml":              XML,
+	"application/msgpack":   MsgPack,
+	"application/x-msgpack": MsgPack,
+}
+
+// Negotiate picks the codec for an Accept header: the supported media type
+// with the highest quality, the first listed on a tie. Headers that name no
+// supported type, including an empty one, get JSON.
+func Negotiate(accept string) Codec {
+	best, bestQ := JSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		c, ok := byMediaType[mediaType]
+		if mediaType == "*/*" || mediaType == "application/*" {
+			c, ok = JSON, true
+		}
+		if ok && q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+// ForContentType returns the codec for a request's Content-Type, or JSON
+// when it is missing or not a format this package translates
+func ForContentType(contentType string) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return JSON
+	}
+	if c, ok := byMediaType[mediaType]; ok {
+		return c
+	}
+	return JSON
+}
+
+// jsonCodec passes JSON through unchanged
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Encode(w io.Writer, data []byte) error {
+	_, err := w.Write(data)
+	return err
+}
+
+func (jsonCodec) Decode(body []byte, v interface{}) ([]byte, error) {
+	return body, nil
+}
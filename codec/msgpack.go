@@ -0,0 +1,157 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+)
+
+// msgpackCodec encodes responses as MessagePack (msgpack.org), which is
+// smaller and quicker to decode than JSON for clients syncing many tasks.
+// JSON numbers become the smallest MessagePack integer that holds them, or
+// a float64 when they have a fraction or exponent. Timestamps stay strings,
+// or integers with X-Timestamp-Format: epoch_ms.
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Encode(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := writeMsgpackValue(&buf, dec); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Decode refuses MessagePack request bodies; the format is only offered
+// for responses
+func (msgpackCodec) Decode(body []byte, v interface{}) ([]byte, error) {
+	return nil, errors.New("MessagePack request bodies are not supported; send JSON")
+}
+
+// writeMsgpackValue writes the next JSON value from dec
+func writeMsgpackValue(buf *bytes.Buffer, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := tok.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case string:
+		writeMsgpackString(buf, v)
+	case json.Number:
+		return writeMsgpackNumber(buf, v)
+	case json.Delim:
+		// Containers are written after their elements are counted
+		var elems bytes.Buffer
+		n := 0
+		for dec.More() {
+			if v == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				writeMsgpackString(&elems, key.(string))
+			}
+			if err := writeMsgpackValue(&elems, dec); err != nil {
+				return err
+			}
+			n++
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		if v == '{' {
+			writeMsgpackHeader(buf, n, 0x80, 0xde)
+		} else {
+			writeMsgpackHeader(buf, n, 0x90, 0xdc)
+		}
+		buf.Write(elems.Bytes())
+	}
+	return nil
+}
+
+// writeMsgpackHeader writes a map or array header: the fix format when n
+// fits in four bits, otherwise the 16 or 32 bit format following code16
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix, code16 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code16 + 1)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackString(buf *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+func writeMsgpackNumber(buf *bytes.Buffer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		switch {
+		case i >= 0 && i <= 127:
+			buf.WriteByte(byte(i))
+		case i < 0 && i >= -32:
+			buf.WriteByte(byte(int8(i)))
+		case i >= math.MinInt8 && i <= math.MaxInt8:
+			buf.WriteByte(0xd0)
+			buf.WriteByte(byte(int8(i)))
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			buf.WriteByte(0xd1)
+			binary.Write(buf, binary.BigEndian, int16(i))
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			buf.WriteByte(0xd2)
+			binary.Write(buf, binary.BigEndian, int32(i))
+		default:
+			buf.WriteByte(0xd3)
+			binary.Write(buf, binary.BigEndian, i)
+		}
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, u)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(0xcb)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	return nil
+}
@@ -0,0 +1,294 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// xmlRoot names the document element of encoded responses
+const xmlRoot = "response"
+
+// xmlItem names the elements of an array
+const xmlItem = "item"
+
+// xmlName matches the keys that can be used as element names as they are.
+// Other keys, such as dates in a stats map, become <entry key="...">.
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// xmlCodec maps JSON onto XML and back:
+//
+//	{"data": {"id": 1, "tags": ["a"], "due_date": null}}
+//	<response><data><id>1</id><tags><item>a</item></tags><due_date nil="true"/></data></response>
+//
+// Request bodies may use any name for the document element and for array
+// elements.
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string { return "application/xml" }
+
+func (xmlCodec) Encode(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := writeXMLValue(&buf, dec, xmlRoot); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeXMLValue writes the next JSON value from dec as an element
+func writeXMLValue(buf *bytes.Buffer, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	open := "<" + name
+	closing := "</" + name + ">"
+	if !xmlName.MatchString(name) || strings.HasPrefix(strings.ToLower(name), "xml") {
+		var key bytes.Buffer
+		xml.EscapeText(&key, []byte(name))
+		open = `<entry key="` + key.String() + `"`
+		closing = "</entry>"
+	}
+
+	switch v := tok.(type) {
+	case nil:
+		buf.WriteString(open + ` nil="true"/>`)
+	case json.Delim:
+		buf.WriteString(open + ">")
+		for dec.More() {
+			child := xmlItem
+			if v == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = key.(string)
+			}
+			if err := writeXMLValue(buf, dec, child); err != nil {
+				return err
+			}
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		buf.WriteString(closing)
+	default:
+		buf.WriteString(open + ">")
+		xml.EscapeText(buf, []byte(fmt.Sprint(v)))
+		buf.WriteString(closing)
+	}
+	return nil
+}
+
+// xmlNode is a parsed element
+type xmlNode struct {
+	name     string
+	isNil    bool
+	text     strings.Builder
+	children []*xmlNode
+}
+
+func (xmlCodec) Decode(body []byte, v interface{}) ([]byte, error) {
+	root, err := parseXML(body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(xmlToJSON(root, reflect.TypeOf(v)))
+}
+
+// parseXML reads the document element of body
+func parseXML(body []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			for _, attr := range t.Attr {
+				switch {
+				case t.Name.Local == "entry" && attr.Name.Local == "key":
+					node.name = attr.Value
+				case attr.Name.Local == "nil" && attr.Value == "true":
+					node.isNil = true
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root != nil {
+				return nil, errors.New("XML document has more than one root element")
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("XML document is empty")
+	}
+	return root, nil
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// xmlToJSON converts node into a value that marshals to the JSON that t
+// expects. Types that decode themselves, interface{} and fields t doesn't
+// know are inferred from the XML: elements with children become objects
+// (or arrays when every child is an <item>), and text that reads as a JSON
+// number or boolean becomes one.
+func xmlToJSON(node *xmlNode, t reflect.Type) interface{} {
+	if node.isNil {
+		return nil
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t == timeType || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return inferJSON(node)
+	}
+
+	text := node.text.String()
+	switch t.Kind() {
+	case reflect.String:
+		return text
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		// Text that isn't a literal of the right kind is passed on as a
+		// string for json.Unmarshal to reject with a type error
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			return nil
+		}
+		if json.Valid([]byte(trimmed)) && trimmed[0] != '"' && trimmed[0] != '{' && trimmed[0] != '[' {
+			return json.RawMessage(trimmed)
+		}
+		return text
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, len(node.children))
+		for i, child := range node.children {
+			items[i] = xmlToJSON(child, t.Elem())
+		}
+		return items
+	case reflect.Map:
+		object := make(map[string]interface{}, len(node.children))
+		for _, child := range node.children {
+			object[child.name] = xmlToJSON(child, t.Elem())
+		}
+		return object
+	case reflect.Struct:
+		fields := jsonFields(t)
+		object := make(map[string]interface{}, len(node.children))
+		for _, child := range node.children {
+			object[child.name] = xmlToJSON(child, fields[child.name])
+		}
+		return object
+	}
+	return inferJSON(node)
+}
+
+// inferJSON converts node without knowing the type it is decoded into
+func inferJSON(node *xmlNode) interface{} {
+	if node.isNil {
+		return nil
+	}
+	if len(node.children) == 0 {
+		text := node.text.String()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "true" || trimmed == "false" {
+			return trimmed == "true"
+		}
+		var n json.Number
+		if json.Unmarshal([]byte(trimmed), &n) == nil && trimmed != "" && trimmed[0] != '"' {
+			return n
+		}
+		return text
+	}
+
+	list := true
+	for _, child := range node.children {
+		if child.name != xmlItem {
+			list = false
+			break
+		}
+	}
+	if list {
+		items := make([]interface{}, len(node.children))
+		for i, child := range node.children {
+			items[i] = inferJSON(child)
+		}
+		return items
+	}
+	object := make(map[string]interface{}, len(node.children))
+	for _, child := range node.children {
+		object[child.name] = inferJSON(child)
+	}
+	return object
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types,
+// following encoding/json's rules for tags and embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range jsonFields(embedded) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// anonymizedColumns lists the free-text columns scrambled by Anonymize
+var anonymizedColumns = []struct {
+	table, column string
+	scramble      func(*rand.Rand, string) string
+}{
+	{"tasks", "title", scramble},
+	{"tasks", "description", scramble},
+	{"tasks", "external_id", scramble},
+	{"tasks", "place", scramble},
+	{"task_links", "title", scramble},
+	{"task_links", "url", scrambleURL},
+	{"task_notes", "body", scramble},
+	{"schedules", "title", scramble},
+	{"schedules", "description", scramble},
+	{"webhooks", "url", scrambleURL},
+	{"webhooks", "secret", scramble},
+}
+
+// Anonymize writes a copy of the SQLite database at srcPath to destPath with
+// every free-text field scrambled. Row counts, IDs, statuses, dates and the
+// byte length of each scrambled value are preserved, so the copy reproduces
+// size- and shape-dependent behaviour without revealing any content.
+// destPath must not already exist.
+func Anonymize(srcPath, destPath string) error {
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("%s already exists", destPath)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	src, err := sql.Open(driverName, srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Bring older databases up to date so every anonymized table exists
+	if err := createTables(src); err != nil {
+		return err
+	}
+
+	if _, err := src.Exec(`VACUUM INTO ?`, destPath); err != nil {
+		return err
+	}
+
+	dest, err := sql.Open(driverName, destPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	tx, err := dest.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Coarsen coordinates to whole degrees (about 100 km) so locations
+	// can't be traced to an address
+	if _, err := tx.Exec(`UPDATE tasks SET latitude = ROUND(latitude), longitude = ROUND(longitude) WHERE latitude IS NOT NULL`); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, c := range anonymizedColumns {
+		if err := scrambleColumn(tx, rng, c.table, c.column, c.scramble); err != nil {
+			return fmt.Errorf("scrambling %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Drop freed pages that could still hold the original text
+	_, err = dest.Exec(`VACUUM`)
+	return err
+}
+
+// scrambleColumn replaces every non-empty value in table.column
+func scrambleColumn(tx *sql.Tx, rng *rand.Rand, table, column string, fn func(*rand.Rand, string) string) error {
+	rows, err := tx.Query(`SELECT id, ` + column + ` FROM ` + table + ` WHERE ` + column + ` IS NOT NULL AND ` + column + ` != ''`)
+	if err != nil {
+		return err
+	}
+	values := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		values[id] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`UPDATE ` + table + ` SET ` + column + ` = ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for id, value := range values {
+		if _, err := stmt.Exec(fn(rng, value), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scramble replaces letters and digits with random ones of the same kind and
+// any other non-ASCII character with as many random letters as it has bytes.
+// ASCII whitespace and punctuation are kept so Markdown, URL and sentence
+// structure survive and the byte length is unchanged.
+func scramble(rng *rand.Rand, s string) string {
+	const (
+		lower  = "abcdefghijklmnopqrstuvwxyz"
+		upper  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		digits = "0123456789"
+	)
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r >= 'a' && r <= 'z':
+			out = append(out, lower[rng.Intn(len(lower))])
+		case r >= 'A' && r <= 'Z':
+			out = append(out, upper[rng.Intn(len(upper))])
+		case r >= '0' && r <= '9':
+			out = append(out, digits[rng.Intn(len(digits))])
+		case r < utf8.RuneSelf:
+			out = append(out, byte(r))
+		default:
+			for ; size > 0; size-- {
+				out = append(out, lower[rng.Intn(len(lower))])
+			}
+		}
+	}
+	return string(out)
+}
+
+// scrambleURL scrambles a URL but keeps its scheme, so links stay valid
+func scrambleURL(rng *rand.Rand, s string) string {
+	if i := strings.Index(s, "://"); i >= 0 {
+		return s[:i+3] + scramble(rng, s[i+3:])
+	}
+	return scramble(rng, s)
+}
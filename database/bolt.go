@@ -0,0 +1,53 @@
+package database
+
+import (
+	"log"
+	"os"
+	"time"
+	"to-do-api/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// InitBolt opens the bbolt key-value store used by the bolt storage backend
+// and creates its buckets
+func InitBolt() (*bolt.DB, error) {
+	// Get database path from environment variable or use default
+	boltPath := os.Getenv("BOLT_PATH")
+	if boltPath == "" {
+		boltPath = "./tasks.bolt"
+	}
+
+	db, err := bolt.Open(boltPath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{"tasks", "idx_status", "idx_due_date", "links", "notes", "idx_external", "idx_uuid", "schedules", "webhooks", "changes", "idx_changes", "meta"} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if err := models.BackfillBoltChanges(tx); err != nil {
+			return err
+		}
+		return models.BackfillBoltPosition(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("Bolt database initialized successfully")
+	return db, nil
+}
+
+// CloseBolt closes the bbolt store gracefully
+func CloseBolt(db *bolt.DB) {
+	if err := db.Close(); err != nil {
+		log.Printf("Error closing bolt database: %v", err)
+	} else {
+		log.Println("Bolt database closed")
+	}
+}
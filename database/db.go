@@ -5,19 +5,24 @@ import (
 	"log"
 	"os"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the SQLite database connection and creates tables
+// Path returns the local SQLite database file: DB_PATH or ./tasks.db
+func Path() string {
+	if dbPath := os.Getenv("DB_PATH"); dbPath != "" {
+		return dbPath
+	}
+	return "./tasks.db"
+}
+
+// InitDB initializes the SQLite database connection and creates tables.
+// When LIBSQL_URL is set, a remote libSQL (Turso) database is used instead.
 func InitDB() (*sql.DB, error) {
-	// Get database path from environment variable or use default
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./tasks.db"
+	if url := os.Getenv("LIBSQL_URL"); url != "" {
+		return initLibSQL(url, os.Getenv("LIBSQL_AUTH_TOKEN"))
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(driverName, Path())
 	if err != nil {
 		return nil, err
 	}
@@ -64,13 +69,125 @@ func createTables(db *sql.DB) error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		title TEXT NOT NULL,
 		description TEXT,
+		start_date DATETIME,
 		due_date DATETIME,
 		status TEXT NOT NULL DEFAULT 'pending',
+		progress INTEGER NOT NULL DEFAULT 0,
+		position INTEGER NOT NULL DEFAULT 0,
+		pinned INTEGER NOT NULL DEFAULT 0,
+		archived INTEGER NOT NULL DEFAULT 0,
+		color TEXT NOT NULL DEFAULT '',
+		encryption_key_id TEXT NOT NULL DEFAULT '',
+		encryption_algorithm TEXT NOT NULL DEFAULT '',
 		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL
+		updated_at DATETIME NOT NULL,
+		completed_at DATETIME,
+		source TEXT NOT NULL DEFAULT '',
+		external_id TEXT NOT NULL DEFAULT '',
+		latitude REAL,
+		longitude REAL,
+		place TEXT NOT NULL DEFAULT '',
+		uuid TEXT NOT NULL DEFAULT ''
+	);
+	`
+
+	// Links attached to tasks; removed along with their task
+	createLinksTable := `
+	CREATE TABLE IF NOT EXISTS task_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		title TEXT NOT NULL DEFAULT '',
+		url TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	`
+
+	createLinksTaskIndex := `
+	CREATE INDEX IF NOT EXISTS idx_task_links_task_id ON task_links(task_id);
+	`
+
+	// Append-only journal entries attached to tasks
+	createNotesTable := `
+	CREATE TABLE IF NOT EXISTS task_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+		body TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	`
+
+	createNotesTaskIndex := `
+	CREATE INDEX IF NOT EXISTS idx_task_notes_task_id ON task_notes(task_id, created_at);
+	`
+
+	// Recurring task schedules; generated counts the occurrences already
+	// materialized as tasks
+	createSchedulesTable := `
+	CREATE TABLE IF NOT EXISTS schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		color TEXT NOT NULL DEFAULT '',
+		frequency TEXT NOT NULL,
+		interval INTEGER NOT NULL DEFAULT 1,
+		start_date DATETIME NOT NULL,
+		end_date DATETIME,
+		generated INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
 	);
 	`
 
+	// Outbound webhooks; events is a comma-separated list, empty for all
+	createWebhooksTable := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '',
+		secret TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	`
+
+	// The changes feed: each task's latest change, under a sequence number
+	// that is renewed on every change. Triggers keep it, so every write to
+	// tasks is recorded, batch and restore included; deleted tasks stay as
+	// tombstones.
+	createChangesTable := `
+	CREATE TABLE IF NOT EXISTS task_changes (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL UNIQUE,
+		kind TEXT NOT NULL,
+		changed_at DATETIME NOT NULL
+	);
+	`
+
+	// A statement's conflict clause overrides one in a trigger, so an upsert
+	// into tasks (as restores do) would turn INSERT OR REPLACE here into a
+	// failing INSERT; the old row is deleted explicitly instead
+	createChangesTriggers := []string{
+		`DROP TRIGGER IF EXISTS trg_tasks_changes_insert;`,
+		`DROP TRIGGER IF EXISTS trg_tasks_changes_update;`,
+		`DROP TRIGGER IF EXISTS trg_tasks_changes_delete;`,
+		`CREATE TRIGGER trg_tasks_changes_insert AFTER INSERT ON tasks BEGIN
+			DELETE FROM task_changes WHERE task_id = NEW.id;
+			INSERT INTO task_changes (task_id, kind, changed_at) VALUES (NEW.id, 'created', strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END;`,
+		`CREATE TRIGGER trg_tasks_changes_update AFTER UPDATE ON tasks BEGIN
+			DELETE FROM task_changes WHERE task_id = NEW.id;
+			INSERT INTO task_changes (task_id, kind, changed_at) VALUES (NEW.id, 'updated', strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END;`,
+		`CREATE TRIGGER trg_tasks_changes_delete AFTER DELETE ON tasks BEGIN
+			DELETE FROM task_changes WHERE task_id = OLD.id;
+			INSERT INTO task_changes (task_id, kind, changed_at) VALUES (OLD.id, 'deleted', strftime('%Y-%m-%d %H:%M:%f', 'now'));
+		END;`,
+	}
+
+	// Tasks stored before the changes feed existed enter it as created
+	backfillChanges := `
+	INSERT INTO task_changes (task_id, kind, changed_at)
+	SELECT id, 'created', updated_at FROM tasks WHERE id NOT IN (SELECT task_id FROM task_changes) ORDER BY id;
+	`
+
 	// Create index on status for better query performance
 	createStatusIndex := `
 	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
@@ -81,11 +198,56 @@ func createTables(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON tasks(created_at);
 	`
 
+	// Create index on start_date for scheduling window queries
+	createStartDateIndex := `
+	CREATE INDEX IF NOT EXISTS idx_tasks_start_date ON tasks(start_date);
+	`
+
+	// Create index on due_date for overdue and due date range queries
+	createDueDateIndex := `
+	CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON tasks(due_date);
+	`
+
+	// Create index on completed_at for completion statistics
+	createCompletedAtIndex := `
+	CREATE INDEX IF NOT EXISTS idx_tasks_completed_at ON tasks(completed_at);
+	`
+
+	// External IDs are unique per source; tasks without one are not indexed
+	createExternalIndex := `
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_source_external_id ON tasks(source, external_id) WHERE external_id != '';
+	`
+
+	// Client-generated UUIDs are unique; tasks without one are not indexed
+	createUUIDIndex := `
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_uuid ON tasks(uuid) WHERE uuid != '';
+	`
+
+	// Create index on position for manual ordering
+	createPositionIndex := `
+	CREATE INDEX IF NOT EXISTS idx_tasks_position ON tasks(position);
+	`
+
+	// Indexes backing the sort keys; pinned tasks always sort first, so each
+	// leads with pinned. The expressions must match models.orderByClause for
+	// SQLite to use them.
+	createSortIndexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_tasks_pinned_created_at ON tasks(pinned, created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_pinned_title ON tasks(pinned DESC, title COLLATE NOCASE);`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_pinned_status_rank ON tasks(pinned DESC, (CASE status WHEN 'pending' THEN 0 WHEN 'in_progress' THEN 1 ELSE 2 END));`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_pinned_due_date ON tasks(pinned DESC, due_date IS NULL, due_date);`,
+	}
+
 	// Execute table creation
 	if _, err := db.Exec(createTasksTable); err != nil {
 		return err
 	}
 
+	// Bring databases created by older versions up to date
+	if err := migrateTables(db); err != nil {
+		return err
+	}
+
 	// Execute index creation
 	if _, err := db.Exec(createStatusIndex); err != nil {
 		return err
@@ -95,10 +257,206 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	if _, err := db.Exec(createPositionIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createStartDateIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createDueDateIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createCompletedAtIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createExternalIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createUUIDIndex); err != nil {
+		return err
+	}
+
+	for _, index := range createSortIndexes {
+		if _, err := db.Exec(index); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec(createLinksTable); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createLinksTaskIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createNotesTable); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createNotesTaskIndex); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createSchedulesTable); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createWebhooksTable); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createChangesTable); err != nil {
+		return err
+	}
+
+	for _, trigger := range createChangesTriggers {
+		if _, err := db.Exec(trigger); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec(backfillChanges); err != nil {
+		return err
+	}
+
 	log.Println("Database tables created successfully")
 	return nil
 }
 
+// migrateTables adds columns introduced after the initial schema
+func migrateTables(db *sql.DB) error {
+	added, err := addColumnIfMissing(db, "tasks", "position", "INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return err
+	}
+	if added {
+		// Seed the manual ordering from insertion order
+		if _, err := db.Exec(`UPDATE tasks SET position = id`); err != nil {
+			return err
+		}
+	}
+
+	// Deletes used to leave gaps in the manual ordering; renumber once
+	var gaps bool
+	if err := db.QueryRow(`SELECT COUNT(*) <> COALESCE(MAX(position), 0) OR COUNT(DISTINCT position) <> COUNT(*) FROM tasks`).Scan(&gaps); err != nil {
+		return err
+	}
+	if gaps {
+		if _, err := db.Exec(`UPDATE tasks SET position = (SELECT COUNT(*) FROM tasks AS t WHERE t.position < tasks.position OR (t.position = tasks.position AND t.id <= tasks.id))`); err != nil {
+			return err
+		}
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "start_date", "DATETIME"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "pinned", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "progress", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "archived", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "color", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "encryption_key_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "encryption_algorithm", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	added, err = addColumnIfMissing(db, "tasks", "completed_at", "DATETIME")
+	if err != nil {
+		return err
+	}
+	if added {
+		// The completion time of existing tasks is unknown; their last
+		// update is the closest estimate
+		if _, err := db.Exec(`UPDATE tasks SET completed_at = updated_at WHERE status = 'completed'`); err != nil {
+			return err
+		}
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "source", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "external_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "latitude", "REAL"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "longitude", "REAL"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "place", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	if _, err := addColumnIfMissing(db, "tasks", "uuid", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists and
+// reports whether the column was added
+func addColumnIfMissing(db *sql.DB, table string, column string, definition string) (bool, error) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return false, err
+	}
+
+	log.Printf("Added column %s.%s", table, column)
+	return true, nil
+}
+
 // CloseDB closes the database connection gracefully
 func CloseDB(db *sql.DB) {
 	if err := db.Close(); err != nil {
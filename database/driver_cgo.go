@@ -0,0 +1,11 @@
+//go:build !purego
+
+package database
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// driverName is the database/sql driver used by InitDB. The default build
+// uses mattn/go-sqlite3, which requires CGO.
+const driverName = "sqlite3"
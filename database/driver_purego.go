@@ -0,0 +1,11 @@
+//go:build purego
+
+package database
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// driverName is the database/sql driver used by InitDB. Building with
+// -tags purego selects modernc.org/sqlite so the binary needs no CGO.
+const driverName = "sqlite"
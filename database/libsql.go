@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/tursodatabase/libsql-client-go/libsql"
+)
+
+// initLibSQL connects to a remote libSQL server (e.g. Turso) over HTTP or
+// WebSockets and creates tables. Local PRAGMA tuning does not apply to
+// remote databases and is skipped.
+func initLibSQL(url string, authToken string) (*sql.DB, error) {
+	var opts []libsql.Option
+	if authToken != "" {
+		opts = append(opts, libsql.WithAuthToken(authToken))
+	}
+
+	connector, err := libsql.NewConnector(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+
+	// Test the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Create tables if they don't exist
+	if err := createTables(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Println("Remote libSQL database initialized successfully")
+	return db, nil
+}
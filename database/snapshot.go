@@ -0,0 +1,44 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// sqliteHeader starts every SQLite database file
+const sqliteHeader = "SQLite format 3\x00"
+
+// IsSQLiteFile reports whether data starts like a SQLite database file
+func IsSQLiteFile(data []byte) bool {
+	return len(data) >= len(sqliteHeader) && string(data[:len(sqliteHeader)]) == sqliteHeader
+}
+
+// Snapshot writes a consistent copy of db to destPath, which must not
+// already exist. Writers are only blocked while the copy is made.
+func Snapshot(db *sql.DB, destPath string) error {
+	_, err := db.Exec(`VACUUM INTO ?`, destPath)
+	return err
+}
+
+// OpenSnapshot opens a SQLite database file, such as one written by
+// Snapshot, bringing older schemas up to date so it can be read like the
+// live database
+func OpenSnapshot(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("not a SQLite database: %w", err)
+	}
+	if err := createTables(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("not a to-do-api database: %w", err)
+	}
+	return db, nil
+}
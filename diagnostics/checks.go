@@ -0,0 +1,92 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"to-do-api/events"
+)
+
+// WAL size thresholds. The write-ahead log is normally checkpointed back to
+// a few megabytes; steady growth means checkpoints are being starved.
+const (
+	walWarnBytes = 64 << 20
+	walFailBytes = 512 << 20
+)
+
+// Database checks that the database answers
+func Database(ping func() error) Check {
+	return Check{Name: "database", Run: func() Result {
+		if err := ping(); err != nil {
+			return Result{Status: Fail, Message: err.Error(), Remediation: "Check that the database file or libSQL endpoint is reachable and not locked by another process"}
+		}
+		return Result{Status: Pass, Message: "database is reachable"}
+	}}
+}
+
+// Schema checks that every migration has been applied, using a probe that
+// fails when a table or column is missing
+func Schema(probe func() error) Check {
+	return Check{Name: "migrations", Run: func() Result {
+		if err := probe(); err != nil {
+			return Result{Status: Fail, Message: "schema is out of date: " + err.Error(), Remediation: "Restart the server to apply pending migrations; if it keeps failing, restore from backup and check the startup log"}
+		}
+		return Result{Status: Pass, Message: "all migrations applied"}
+	}}
+}
+
+// WALSize checks the size of a SQLite database's write-ahead log
+func WALSize(dbPath string) Check {
+	return Check{Name: "wal_size", Run: func() Result {
+		info, err := os.Stat(dbPath + "-wal")
+		if os.IsNotExist(err) {
+			return Result{Status: Pass, Message: "no write-ahead log present"}
+		}
+		if err != nil {
+			return Result{Status: Warn, Message: err.Error(), Remediation: "Check permissions on the database directory"}
+		}
+
+		size := info.Size()
+		message := fmt.Sprintf("write-ahead log is %.1f MiB", float64(size)/(1<<20))
+		remediation := "A long-running reader is starving checkpoints; find it, or run PRAGMA wal_checkpoint(TRUNCATE) during a quiet period"
+		switch {
+		case size >= walFailBytes:
+			return Result{Status: Fail, Message: message, Remediation: remediation}
+		case size >= walWarnBytes:
+			return Result{Status: Warn, Message: message, Remediation: remediation}
+		}
+		return Result{Status: Pass, Message: message}
+	}}
+}
+
+// EventBus checks for event subscribers whose queue is nearly full or that
+// dropped events since the previous run. Dropped events are the bus's dead
+// letters.
+func EventBus(bus *events.Bus) Check {
+	var mu sync.Mutex
+	var lastDropped uint64
+	return Check{Name: "event_bus", Run: func() Result {
+		var dropped uint64
+		lagging := 0
+		for _, sub := range bus.Stats() {
+			dropped += sub.Dropped
+			if sub.Lag*10 >= sub.Buffer*8 {
+				lagging++
+			}
+		}
+
+		mu.Lock()
+		recent := uint64(0)
+		if dropped > lastDropped {
+			recent = dropped - lastDropped
+		}
+		lastDropped = dropped
+		mu.Unlock()
+
+		message := fmt.Sprintf("%d events dropped since the last check, %d subscribers over 80%% of their buffer", recent, lagging)
+		if recent > 0 || lagging > 0 {
+			return Result{Status: Warn, Message: message, Remediation: "See /debug/events for the lagging subscriber; a slow webhook endpoint or stuck stream client is the usual cause"}
+		}
+		return Result{Status: Pass, Message: message}
+	}}
+}
@@ -0,0 +1,108 @@
+// Package diagnostics runs live health checks for the operational runbook
+// and reports each as pass, warn or fail with a remediation hint.
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// rank orders statuses from best to worst
+func (s Status) rank() int {
+	switch s {
+	case Pass:
+		return 0
+	case Warn:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Result is the outcome of one check. Remediation says what to do about a
+// warn or fail.
+type Result struct {
+	Name        string `json:"name"`
+	Status      Status `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+	DurationMS  int64  `json:"duration_ms"`
+}
+
+// Check is a named live check
+type Check struct {
+	Name string
+	Run  func() Result
+}
+
+// Report is the outcome of running every check. Status is the worst status
+// of any check.
+type Report struct {
+	Status    Status    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Runner holds the registered checks
+type Runner struct {
+	mu     sync.Mutex
+	checks []Check
+}
+
+// NewRunner creates a runner with no checks
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+// Add registers a check
+func (r *Runner) Add(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+// Run runs every check in the order they were added
+func (r *Runner) Run() Report {
+	r.mu.Lock()
+	checks := append([]Check(nil), r.checks...)
+	r.mu.Unlock()
+
+	report := Report{Status: Pass, CheckedAt: time.Now().UTC(), Checks: make([]Result, 0, len(checks))}
+	for _, check := range checks {
+		start := time.Now()
+		result := check.Run()
+		result.Name = check.Name
+		result.DurationMS = time.Since(start).Milliseconds()
+		if result.Status.rank() > report.Status.rank() {
+			report.Status = result.Status
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+// Handler runs the checks on every request and serves the report as JSON.
+// The status code is 503 when any check fails, so monitoring can alert on
+// it without parsing the body; warnings still return 200.
+func (r *Runner) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status == Fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
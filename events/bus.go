@@ -0,0 +1,339 @@
+// Package events is the in-process bus that carries task changes to
+// streaming clients, webhooks and other consumers.
+//
+// Publishing never waits on a slow consumer for long. Every subscriber has
+// its own bounded buffer and a policy for what happens when it fills up, so
+// one stuck consumer loses its own events instead of backing up task writes
+// or other subscribers.
+package events
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"to-do-api/models"
+)
+
+// busStats exposes published and dropped event counters under /debug/vars
+var busStats = expvar.NewMap("events")
+
+// Event types
+const (
+	TaskCreated = "task.created"
+	TaskUpdated = "task.updated"
+	TaskDeleted = "task.deleted"
+	// TaskRestored reports a task put back by undo, whether it had been
+	// deleted or changed
+	TaskRestored = "task.restored"
+	// TasksChanged reports a batch change; Count holds the number of tasks
+	// affected, which may not be known individually
+	TasksChanged = "tasks.changed"
+	// QuotaWarning reports that the workspace is close to a quota; Message
+	// says which
+	QuotaWarning = "quota.warning"
+	// TaskCompleted is never published; consumers derive it with
+	// CompletesTask
+	TaskCompleted = "task.completed"
+)
+
+// Event is a single change published on the bus
+type Event struct {
+	// Seq increases by one for every event published on the bus
+	Seq        uint64       `json:"seq"`
+	Type       string       `json:"type"`
+	TaskID     int          `json:"task_id,omitempty"`
+	Task       *models.Task `json:"task,omitempty"`
+	Count      int          `json:"count,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// CompletesTask reports whether e is a create or update that completed its
+// task. completed_at is only set, to the time of the write, when a task
+// becomes completed, so it matches updated_at exactly on that write.
+func (e Event) CompletesTask() bool {
+	if e.Type != TaskCreated && e.Type != TaskUpdated {
+		return false
+	}
+	return e.Task != nil && e.Task.CompletedAt != nil && e.Task.CompletedAt.Equal(e.Task.UpdatedAt)
+}
+
+// Policy decides what happens to an event when a subscriber's buffer is full
+type Policy string
+
+const (
+	// DropOldest discards the oldest queued event to make room. Suits
+	// consumers that only care about recent state, such as live views.
+	DropOldest Policy = "drop_oldest"
+	// DropNewest discards the incoming event and keeps the queue intact
+	DropNewest Policy = "drop_newest"
+	// Block makes the publisher wait up to BlockTimeout for room, then drops
+	// the event. Use sparingly: the wait is spent inside a task write.
+	Block Policy = "block"
+)
+
+// Default subscriber settings
+const (
+	DefaultBuffer       = 256
+	DefaultBlockTimeout = 100 * time.Millisecond
+)
+
+// HistorySize is the number of recent events the bus keeps so streaming
+// clients can resume after a disconnect
+const HistorySize = 1024
+
+// Options configure a subscription
+type Options struct {
+	// Name identifies the subscriber in stats, e.g. "sse" or "webhooks"
+	Name string
+	// Buffer is the number of events queued before Policy applies
+	Buffer int
+	Policy Policy
+	// BlockTimeout bounds the wait under the Block policy
+	BlockTimeout time.Duration
+}
+
+// Bus fans published events out to subscribers
+type Bus struct {
+	seq  atomic.Uint64
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	// history holds the most recent events, oldest first
+	history []Event
+}
+
+// NewBus creates a bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives events from a bus until closed
+type Subscription struct {
+	bus          *Bus
+	name         string
+	policy       Policy
+	blockTimeout time.Duration
+	ch           chan Event
+	created      time.Time
+
+	// mu serializes deliveries so DropOldest can make room atomically
+	mu       sync.Mutex
+	closed   bool
+	received atomic.Uint64
+	dropped  atomic.Uint64
+	lastDrop atomic.Int64
+}
+
+// Subscribe registers a subscriber. Zero options fall back to the defaults
+// and DropOldest.
+func (b *Bus) Subscribe(opts Options) *Subscription {
+	s := newSubscription(b, opts)
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// SubscribeFrom registers a subscriber like Subscribe and also returns the
+// events published after seq that are still in the history, so a client can
+// resume where it left off without missing or repeating events. complete is
+// false when some of those events have already left the history, or seq is
+// ahead of the bus, as happens with an ID from before a restart.
+func (b *Bus) SubscribeFrom(opts Options, seq uint64) (s *Subscription, missed []Event, complete bool) {
+	s = newSubscription(b, opts)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = struct{}{}
+
+	latest := b.seq.Load()
+	switch {
+	case seq > latest:
+		return s, nil, false
+	case seq == latest:
+		return s, nil, true
+	}
+	// history is contiguous and ends at latest, so it covers seq+1 onwards
+	// exactly when it holds at least latest-seq events
+	n := int(latest - seq)
+	if n > len(b.history) {
+		return s, append([]Event(nil), b.history...), false
+	}
+	return s, append([]Event(nil), b.history[len(b.history)-n:]...), true
+}
+
+// newSubscription creates a subscription that is not yet registered
+func newSubscription(b *Bus, opts Options) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	if opts.Policy == "" {
+		opts.Policy = DropOldest
+	}
+	if opts.BlockTimeout <= 0 {
+		opts.BlockTimeout = DefaultBlockTimeout
+	}
+
+	s := &Subscription{
+		bus:          b,
+		name:         opts.Name,
+		policy:       opts.Policy,
+		blockTimeout: opts.BlockTimeout,
+		ch:           make(chan Event, opts.Buffer),
+		created:      time.Now().UTC(),
+	}
+	return s
+}
+
+// Publish stamps e with the next sequence number and the current time, if
+// unset, records it in the history and delivers it to every subscriber
+func (b *Bus) Publish(e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+	busStats.Add("published", 1)
+
+	// Numbering, recording and taking the subscriber list happen together
+	// so SubscribeFrom sees every event either in the history or on the
+	// channel, never both or neither
+	b.mu.Lock()
+	e.Seq = b.seq.Add(1)
+	b.history = append(b.history, e)
+	if len(b.history) > HistorySize {
+		b.history = b.history[len(b.history)-HistorySize:]
+	}
+	subs := make([]*Subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.deliver(e)
+	}
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close unsubscribes and closes the events channel
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s)
+	s.bus.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// deliver queues e according to the subscriber's policy
+func (s *Subscription) deliver(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.received.Add(1)
+
+	select {
+	case s.ch <- e:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case DropOldest:
+		select {
+		case <-s.ch:
+			s.drop()
+		default:
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.drop()
+		}
+	case Block:
+		timer := time.NewTimer(s.blockTimeout)
+		defer timer.Stop()
+		select {
+		case s.ch <- e:
+		case <-timer.C:
+			s.drop()
+		}
+	default:
+		s.drop()
+	}
+}
+
+func (s *Subscription) drop() {
+	s.dropped.Add(1)
+	s.lastDrop.Store(time.Now().UnixNano())
+	busStats.Add("dropped", 1)
+}
+
+// SubscriberStats describes one subscriber's backlog
+type SubscriberStats struct {
+	Name   string `json:"name"`
+	Policy Policy `json:"policy"`
+	Buffer int    `json:"buffer"`
+	// Lag is the number of events queued but not yet consumed
+	Lag       int        `json:"lag"`
+	Received  uint64     `json:"received"`
+	Dropped   uint64     `json:"dropped"`
+	LastDrop  *time.Time `json:"last_drop_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Stats reports every subscriber, ordered by name
+func (b *Bus) Stats() []SubscriberStats {
+	b.mu.RLock()
+	stats := make([]SubscriberStats, 0, len(b.subs))
+	for s := range b.subs {
+		st := SubscriberStats{
+			Name:      s.name,
+			Policy:    s.policy,
+			Buffer:    cap(s.ch),
+			Lag:       len(s.ch),
+			Received:  s.received.Load(),
+			Dropped:   s.dropped.Load(),
+			CreatedAt: s.created,
+		}
+		if ns := s.lastDrop.Load(); ns != 0 {
+			t := time.Unix(0, ns).UTC()
+			st.LastDrop = &t
+		}
+		stats = append(stats, st)
+	}
+	b.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Name == stats[j].Name {
+			return stats[i].CreatedAt.Before(stats[j].CreatedAt)
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// Handler serves subscriber stats as JSON, for operators to spot lagging
+// consumers
+func (b *Bus) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Published   uint64            `json:"published"`
+			Subscribers []SubscriberStats `json:"subscribers"`
+		}{b.seq.Load(), b.Stats()})
+	})
+}
@@ -0,0 +1,213 @@
+package events
+
+import (
+	"sync"
+	"time"
+	"to-do-api/models"
+)
+
+// PublishingTaskRepository wraps a TaskRepository and publishes an event
+// after every successful write. Reads pass straight through. Events of
+// writes made in a transaction are held until it commits, and dropped if
+// it rolls back.
+type PublishingTaskRepository struct {
+	models.TaskRepository
+	bus *Bus
+
+	mu sync.Mutex
+	// held queues events while a transaction is open; nil otherwise
+	held []Event
+}
+
+// NewPublishingTaskRepository creates a publishing wrapper around repo
+func NewPublishingTaskRepository(repo models.TaskRepository, bus *Bus) *PublishingTaskRepository {
+	return &PublishingTaskRepository{TaskRepository: repo, bus: bus}
+}
+
+// publish publishes e, or queues it while a transaction is open
+func (r *PublishingTaskRepository) publish(e Event) {
+	r.mu.Lock()
+	if r.held != nil {
+		e.OccurredAt = time.Now().UTC()
+		r.held = append(r.held, e)
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+	r.bus.Publish(e)
+}
+
+// publishTask publishes a copy of task so consumers never share it with
+// the caller
+func (r *PublishingTaskRepository) publishTask(eventType string, task *models.Task) {
+	t := *task
+	r.publish(Event{Type: eventType, TaskID: t.ID, Task: &t})
+}
+
+// publishBatch publishes a batch change unless nothing changed
+func (r *PublishingTaskRepository) publishBatch(count int) {
+	if count > 0 {
+		r.publish(Event{Type: TasksChanged, Count: count})
+	}
+}
+
+// Begin opens a transaction and holds events back until it ends
+func (r *PublishingTaskRepository) Begin() error {
+	if err := r.TaskRepository.Begin(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.held = []Event{}
+	r.mu.Unlock()
+	return nil
+}
+
+// Commit publishes the held events once the transaction has committed
+func (r *PublishingTaskRepository) Commit() error {
+	err := r.TaskRepository.Commit()
+	r.mu.Lock()
+	held := r.held
+	r.held = nil
+	r.mu.Unlock()
+	if err == nil {
+		for _, e := range held {
+			r.bus.Publish(e)
+		}
+	}
+	return err
+}
+
+// Rollback drops the held events along with the transaction's writes
+func (r *PublishingTaskRepository) Rollback() error {
+	r.mu.Lock()
+	r.held = nil
+	r.mu.Unlock()
+	return r.TaskRepository.Rollback()
+}
+
+// Create publishes task.created
+func (r *PublishingTaskRepository) Create(req *models.TaskRequest) (*models.Task, error) {
+	task, err := r.TaskRepository.Create(req)
+	if err == nil && task != nil {
+		r.publishTask(TaskCreated, task)
+	}
+	return task, err
+}
+
+// CreateBatch publishes task.created for each task
+func (r *PublishingTaskRepository) CreateBatch(reqs []*models.TaskRequest) ([]models.Task, error) {
+	tasks, err := r.TaskRepository.CreateBatch(reqs)
+	if err == nil {
+		for i := range tasks {
+			r.publishTask(TaskCreated, &tasks[i])
+		}
+	}
+	return tasks, err
+}
+
+// UpdateBatch publishes tasks.changed
+func (r *PublishingTaskRepository) UpdateBatch(ids []int, filter models.TaskFilter, changes models.TaskChanges) (int, error) {
+	n, err := r.TaskRepository.UpdateBatch(ids, filter, changes)
+	if err == nil {
+		r.publishBatch(n)
+	}
+	return n, err
+}
+
+// DeleteBatch publishes tasks.changed unless dryRun is set
+func (r *PublishingTaskRepository) DeleteBatch(ids []int, filter models.TaskFilter, dryRun bool) (int, error) {
+	n, err := r.TaskRepository.DeleteBatch(ids, filter, dryRun)
+	if err == nil && !dryRun {
+		r.publishBatch(n)
+	}
+	return n, err
+}
+
+// Update publishes task.updated
+func (r *PublishingTaskRepository) Update(id int, req *models.TaskRequest, check models.VersionCheck) (*models.Task, error) {
+	task, err := r.TaskRepository.Update(id, req, check)
+	if err == nil && task != nil {
+		r.publishTask(TaskUpdated, task)
+	}
+	return task, err
+}
+
+// Patch publishes task.updated
+func (r *PublishingTaskRepository) Patch(id int, patch *models.TaskPatch, check models.VersionCheck) (*models.Task, error) {
+	task, err := r.TaskRepository.Patch(id, patch, check)
+	if err == nil && task != nil {
+		r.publishTask(TaskUpdated, task)
+	}
+	return task, err
+}
+
+// Delete publishes task.deleted
+func (r *PublishingTaskRepository) Delete(id int, check models.VersionCheck) error {
+	err := r.TaskRepository.Delete(id, check)
+	if err == nil {
+		r.publish(Event{Type: TaskDeleted, TaskID: id})
+	}
+	return err
+}
+
+// Move publishes task.updated for the moved task
+func (r *PublishingTaskRepository) Move(id int, position int) (*models.Task, error) {
+	task, err := r.TaskRepository.Move(id, position)
+	if err == nil && task != nil {
+		r.publishTask(TaskUpdated, task)
+	}
+	return task, err
+}
+
+// TogglePin publishes task.updated
+func (r *PublishingTaskRepository) TogglePin(id int) (*models.Task, error) {
+	task, err := r.TaskRepository.TogglePin(id)
+	if err == nil && task != nil {
+		r.publishTask(TaskUpdated, task)
+	}
+	return task, err
+}
+
+// Reorder publishes tasks.changed
+func (r *PublishingTaskRepository) Reorder(ids []int) error {
+	err := r.TaskRepository.Reorder(ids)
+	if err == nil {
+		r.publishBatch(len(ids))
+	}
+	return err
+}
+
+// UpsertExternal publishes task.created or task.updated
+func (r *PublishingTaskRepository) UpsertExternal(source, externalID string, req *models.TaskRequest) (*models.Task, bool, error) {
+	task, created, err := r.TaskRepository.UpsertExternal(source, externalID, req)
+	if err == nil && task != nil {
+		if created {
+			r.publishTask(TaskCreated, task)
+		} else {
+			r.publishTask(TaskUpdated, task)
+		}
+	}
+	return task, created, err
+}
+
+// RestoreTasks publishes task.restored for each task
+func (r *PublishingTaskRepository) RestoreTasks(snapshots []models.TaskSnapshot) error {
+	err := r.TaskRepository.RestoreTasks(snapshots)
+	if err == nil {
+		for i := range snapshots {
+			r.publishTask(TaskRestored, &snapshots[i].Task)
+		}
+	}
+	return err
+}
+
+// ReplaceAll publishes tasks.changed, counting the restored tasks. It is
+// published even when the backup holds no tasks, since every stored task
+// may have been deleted.
+func (r *PublishingTaskRepository) ReplaceAll(backup *models.Backup) error {
+	err := r.TaskRepository.ReplaceAll(backup)
+	if err == nil {
+		r.publish(Event{Type: TasksChanged, Count: len(backup.Tasks)})
+	}
+	return err
+}
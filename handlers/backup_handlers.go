@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+	"to-do-api/database"
+	"to-do-api/models"
+)
+
+// maxRestoreBytes bounds an uploaded backup
+const maxRestoreBytes = 256 << 20
+
+// SetSQLiteSnapshot enables ?format=sqlite backups. snapshot writes a copy
+// of the live SQLite database to a path that does not exist yet; it is only
+// set for the local sqlite storage backend.
+func (h *TaskHandler) SetSQLiteSnapshot(snapshot func(destPath string) error) {
+	h.sqliteSnapshot = snapshot
+}
+
+// GetBackup handles GET /api/admin/backup
+// It downloads everything stored, including webhook secrets, as JSON, or
+// with format=sqlite as a SQLite database file. Either can be loaded with
+// POST /api/admin/restore, on this or another server and storage backend.
+func (h *TaskHandler) GetBackup(w http.ResponseWriter, r *http.Request) {
+	now := h.clock.Now().UTC()
+	name := "to-do-api-backup-" + now.Format("20060102T150405Z")
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		backup, err := models.ReadBackup(h.repo, now)
+		if err != nil {
+			log.Printf("Error reading backup: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create backup", "")
+			return
+		}
+		data, err := json.Marshal(backup)
+		if err != nil {
+			log.Printf("Error encoding backup: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create backup", "")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	case "sqlite":
+		if h.sqliteSnapshot == nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "SQLite backups are not available", "They need the local sqlite storage backend; use format=json")
+			return
+		}
+		dir, err := os.MkdirTemp("", "to-do-api-backup-")
+		if err != nil {
+			log.Printf("Error creating backup directory: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create backup", "")
+			return
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "backup.db")
+		if err := h.sqliteSnapshot(path); err != nil {
+			log.Printf("Error writing SQLite backup: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create backup", "")
+			return
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			log.Printf("Error reading SQLite backup: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create backup", "")
+			return
+		}
+		defer file.Close()
+		w.Header().Set("Content-Type", "application/vnd.sqlite3")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.db"`)
+		w.Header().Set("Cache-Control", "no-store")
+		if info, err := file.Stat(); err == nil {
+			w.Header().Set("Content-Length", fmt.Sprint(info.Size()))
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, file); err != nil {
+			log.Printf("Error sending SQLite backup: %v", err)
+		}
+	default:
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid format parameter", "format must be json or sqlite, got "+format)
+	}
+}
+
+// RestoreResult reports what POST /api/admin/restore loaded
+type RestoreResult struct {
+	DryRun    bool                `json:"dry_run"`
+	CreatedAt string              `json:"backup_created_at,omitempty"`
+	Counts    models.BackupCounts `json:"counts"`
+}
+
+// RestoreBackup handles POST /api/admin/restore
+// The body is a JSON backup or a SQLite database file from GET
+// /api/admin/backup. It is validated in full, then replaces everything
+// stored in one transaction; nothing changes when it is invalid.
+// dry_run=true only validates it and reports what it holds. The undo
+// history is cleared, since it refers to the replaced data.
+func (h *TaskHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseBoolParam(r.URL.Query().Get("dry_run"))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid dry_run parameter", "dry_run must be true or false")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "Backup too large", fmt.Sprintf("Backups are limited to %d MB", maxRestoreBytes>>20))
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	var backup *models.Backup
+	if database.IsSQLiteFile(data) {
+		backup, err = readSQLiteBackup(data, h.clock.Now())
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid backup", err.Error())
+			return
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&backup); err != nil || backup == nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid backup", "The body must be a JSON or SQLite backup from GET /api/admin/backup")
+			return
+		}
+	}
+	if err := backup.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid backup", err.Error())
+		return
+	}
+
+	result := RestoreResult{DryRun: dryRun, Counts: backup.Counts()}
+	if !backup.CreatedAt.IsZero() {
+		result.CreatedAt = backup.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if dryRun {
+		h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Backup is valid", Data: result})
+		return
+	}
+
+	if err := h.repo.ReplaceAll(backup); err != nil {
+		log.Printf("Error restoring backup: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to restore backup", "")
+		return
+	}
+	h.undo.clear()
+	h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Backup restored successfully", Data: result})
+}
+
+// readSQLiteBackup reads a backup out of an uploaded SQLite database file
+func readSQLiteBackup(data []byte, now time.Time) (*models.Backup, error) {
+	dir, err := os.MkdirTemp("", "to-do-api-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "restore.db")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	db, err := database.OpenSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return models.ReadBackup(models.NewSQLiteTaskRepository(db), now)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Batch limits
+const (
+	maxBatchOperations = 100
+	maxBatchBody       = 10 << 20
+)
+
+// batchBlockedPaths are API paths a batch may not call: nested batches and
+// syncs, streams that never end, and the admin endpoints
+var batchBlockedPaths = []string{"/api/batch", "/api/sync", "/api/ws", "/api/events", "/api/admin/"}
+
+// batchHeaders are the headers an operation may set itself
+var batchHeaders = map[string]bool{"If-Match": true, "If-None-Match": true}
+
+// batchUnlockedPaths are served without BatchLock: the batch endpoint
+// itself, and streams, which stay open indefinitely and never read storage
+var batchUnlockedPaths = map[string]bool{"/api/batch": true, "/api/events": true, "/api/ws": true}
+
+// batchGuard keeps everyone else off the repository while a batch that
+// writes runs in its transaction: requests hold mu for reading (BatchLock),
+// the batch holds it for writing
+type batchGuard struct {
+	mu sync.RWMutex
+}
+
+// SetBatchRouter sets the handler the operations of POST /api/batch are
+// sent to, normally the API's own router without BatchLock
+func (h *TaskHandler) SetBatchRouter(router http.Handler) {
+	h.batchRouter = router
+}
+
+// BatchLock holds requests back while a batch writes, as the repository
+// then runs every call in the batch's transaction. It wraps every handler
+// clients reach storage through.
+func (h *TaskHandler) BatchLock(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !batchUnlockedPaths[r.URL.Path] {
+			h.batch.mu.RLock()
+			defer h.batch.mu.RUnlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BatchOperation is one request within a batch
+type BatchOperation struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// BatchOperationResult is the response to one operation. Body is the
+// operation's JSON response, or a string for other content.
+type BatchOperationResult struct {
+	Index    int             `json:"index"`
+	Status   int             `json:"status"`
+	ETag     string          `json:"etag,omitempty"`
+	Location string          `json:"location,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResult reports a batch. Results holds the operations that ran, up
+// to and including a failed one.
+type BatchResult struct {
+	Committed   bool                   `json:"committed"`
+	FailedIndex *int                   `json:"failed_index,omitempty"`
+	Results     []BatchOperationResult `json:"results"`
+}
+
+// batchFailure is the body of a batch that was rolled back
+type batchFailure struct {
+	ErrorResponse
+	Data BatchResult `json:"data"`
+}
+
+// Batch handles POST /api/batch
+// The body is an array of operations, each a method, an API path (with an
+// optional query) and an optional JSON body, run in order as if sent one by
+// one. Operations that write run in a single storage transaction, with
+// their events held back until it commits. If one fails with a 4xx or 5xx
+// status, the rest are skipped and the transaction is rolled back, so
+// either every operation takes effect or none does; the batch then answers
+// with the failed operation's status. Other requests wait while a batch
+// that writes runs.
+func (h *TaskHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	if h.batchRouter == nil {
+		h.sendErrorResponse(w, http.StatusNotImplemented, "Batch requests are not available", "")
+		return
+	}
+
+	var ops []BatchOperation
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ops); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body too large", fmt.Sprintf("Batches are limited to %d MB", maxBatchBody>>20))
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload", "The body must be an array of operations with method, path and optional body and headers")
+		return
+	}
+	if len(ops) == 0 || len(ops) > maxBatchOperations {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("request must contain between 1 and %d operations", maxBatchOperations))
+		return
+	}
+	writes := false
+	for i := range ops {
+		if err := ops[i].normalize(); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("operations[%d]: %v", i, err))
+			return
+		}
+		if ops[i].Method != http.MethodGet {
+			writes = true
+		}
+	}
+
+	// The undo log as it was, for a rollback; no other request runs
+	// alongside, so only the batch changes it
+	var undo []undoEntry
+	inTx := false
+	if !writes {
+		h.batch.mu.RLock()
+		defer h.batch.mu.RUnlock()
+	} else {
+		h.batch.mu.Lock()
+		defer h.batch.mu.Unlock()
+		if err := h.repo.Begin(); err != nil {
+			log.Printf("Error beginning batch transaction: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to run batch", "")
+			return
+		}
+		inTx = true
+		// An operation that panics must not leave the transaction open
+		defer func() {
+			if inTx {
+				h.repo.Rollback()
+				h.undo.reset(undo)
+			}
+		}()
+		undo = h.undo.save()
+	}
+
+	result := BatchResult{Committed: true, Results: make([]BatchOperationResult, 0, len(ops))}
+	for i, op := range ops {
+		res := h.runBatchOperation(r, op)
+		res.Index = i
+		result.Results = append(result.Results, res)
+		if res.Status < 400 {
+			continue
+		}
+
+		index := i
+		result.Committed, result.FailedIndex = false, &index
+		if writes {
+			inTx = false
+			h.undo.reset(undo)
+			if err := h.repo.Rollback(); err != nil {
+				log.Printf("Error rolling back batch: %v", err)
+				h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to roll back batch", fmt.Sprintf("operations[%d] failed and the batch transaction could not be rolled back", i))
+				return
+			}
+		}
+		h.sendJSONResponse(w, res.Status, batchFailure{
+			ErrorResponse: ErrorResponse{Error: "Batch failed", Message: fmt.Sprintf("operations[%d] failed with status %d; no changes were made", i, res.Status)},
+			Data:          result,
+		})
+		return
+	}
+	if writes {
+		inTx = false
+		if err := h.repo.Commit(); err != nil {
+			log.Printf("Error committing batch: %v", err)
+			h.undo.reset(undo)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to commit batch", "No changes were made")
+			return
+		}
+	}
+	h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Batch completed successfully", Data: result})
+}
+
+// normalize checks an operation and canonicalizes its method and headers
+func (op *BatchOperation) normalize() error {
+	op.Method = strings.ToUpper(op.Method)
+	switch op.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return errors.New("method must be GET, POST, PUT, PATCH or DELETE")
+	}
+	u, err := url.Parse(op.Path)
+	if err != nil || u.IsAbs() || u.Host != "" || !strings.HasPrefix(u.Path, "/api/") {
+		return errors.New("path must be an API path such as /api/tasks")
+	}
+	for _, blocked := range batchBlockedPaths {
+		if u.Path == blocked || (strings.HasSuffix(blocked, "/") && strings.HasPrefix(u.Path, blocked)) {
+			return fmt.Errorf("%s can't be called in a batch", u.Path)
+		}
+	}
+	headers := make(map[string]string, len(op.Headers))
+	for name, value := range op.Headers {
+		name = http.CanonicalHeaderKey(name)
+		if !batchHeaders[name] {
+			return fmt.Errorf("header %s is not allowed; operations may only set If-Match and If-None-Match", name)
+		}
+		headers[name] = value
+	}
+	op.Headers = headers
+	return nil
+}
+
+// runBatchOperation sends one operation through the API router and
+// captures its response
+func (h *TaskHandler) runBatchOperation(r *http.Request, op BatchOperation) BatchOperationResult {
+	req, err := http.NewRequestWithContext(r.Context(), op.Method, op.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		return BatchOperationResult{Status: http.StatusBadRequest, Body: batchErrorBody("Invalid operation", err.Error())}
+	}
+	req.RemoteAddr = r.RemoteAddr
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if len(op.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Plain JSON with untouched timestamps: the batch response as a whole
+	// is encoded for the client
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Timestamp-Format", "rfc3339nano")
+	for name, value := range op.Headers {
+		req.Header.Set(name, value)
+	}
+
+	rec := &batchRecorder{header: http.Header{}}
+	h.batchRouter.ServeHTTP(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	res := BatchOperationResult{Status: rec.status, ETag: rec.header.Get("ETag"), Location: rec.header.Get("Location")}
+	body := bytes.TrimSpace(rec.body.Bytes())
+	if len(body) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(rec.header.Get("Content-Type"))
+		if (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(body) {
+			res.Body = body
+		} else {
+			res.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return res
+}
+
+// batchErrorBody renders an error body for an operation that never ran
+func batchErrorBody(message, detail string) json.RawMessage {
+	body, _ := json.Marshal(ErrorResponse{Error: message, Message: detail})
+	return body
+}
+
+// batchRecorder captures an operation's response
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"to-do-api/models"
+)
+
+// boardStatuses are the board columns in workflow order
+var boardStatuses = []string{"pending", "in_progress", "completed"}
+
+// Board is a kanban view of the task list with one column per status
+type Board struct {
+	Columns []BoardColumn `json:"columns"`
+}
+
+// BoardColumn holds the first tasks of one status in position order. Total
+// counts every matching task, so a client can tell when a column is cut off.
+type BoardColumn struct {
+	Status string        `json:"status"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Tasks  []models.Task `json:"tasks"`
+}
+
+// GetBoard handles GET /api/board
+// It returns tasks grouped into status columns ordered by position (pinned
+// tasks first). The list filters apply to every column; status selects which
+// columns to include. limit caps every column (default 50, max 100) and
+// limit_pending, limit_in_progress and limit_completed override it per column.
+func (h *TaskHandler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params, perr := parseListParams(query, h.clock.Now(), "position", "asc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+	if params.format != "json" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid format", "The board is only available as JSON")
+		return
+	}
+
+	statuses := boardStatuses
+	if len(params.filter.Statuses) > 0 {
+		statuses = nil
+		for _, status := range boardStatuses {
+			for _, wanted := range params.filter.Statuses {
+				if status == wanted {
+					statuses = append(statuses, status)
+					break
+				}
+			}
+		}
+	}
+
+	board := Board{Columns: make([]BoardColumn, 0, len(statuses))}
+	for _, status := range statuses {
+		filter := params.filter
+		filter.Statuses = []string{status}
+		filter.After = nil
+		limit := parseLimit(query.Get("limit_"+status), params.limit)
+
+		tasks, err := h.repo.GetAllPaginated(filter, limit, 0, "position", "asc")
+		if err != nil {
+			log.Printf("Error fetching board: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch board", "")
+			return
+		}
+		total, err := h.repo.Count(filter)
+		if err != nil {
+			log.Printf("Error counting board column: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch board", "")
+			return
+		}
+
+		// Copy before summarizing; the slice may be shared by the
+		// coalescing repository
+		column := BoardColumn{Status: status, Total: total, Limit: limit, Tasks: make([]models.Task, len(tasks))}
+		copy(column.Tasks, tasks)
+		for i := range column.Tasks {
+			column.Tasks[i].Summarize()
+		}
+		board.Columns = append(board.Columns, column)
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Board retrieved successfully", board)
+}
@@ -0,0 +1,729 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"to-do-api/ical"
+	"to-do-api/models"
+)
+
+// CalDAV paths. The root is both the principal and the calendar home, and
+// holds a single calendar collection with every task.
+const (
+	caldavRoot  = "/caldav/"
+	caldavTasks = "/caldav/tasks/"
+	// CalDAVSource is the external source of tasks created by CalDAV
+	// clients; their external ID is the resource name the client chose
+	CalDAVSource = "caldav"
+	// caldavMaxBody bounds request bodies
+	caldavMaxBody = 1 << 20
+)
+
+// XML namespaces of the WebDAV, CalDAV and CalendarServer properties
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// davKind is the kind of a CalDAV resource
+type davKind int
+
+const (
+	davRoot davKind = iota
+	davCalendar
+	davObject
+)
+
+// davObjectRes is a task served as a calendar object resource
+type davObjectRes struct {
+	name string
+	task *models.Task
+	ics  []byte
+	etag string
+}
+
+// newDAVObject renders a task as a calendar object. Tasks created over
+// CalDAV keep the resource name their client chose, which is also their
+// UID; other tasks are named by their ID.
+func newDAVObject(task *models.Task) *davObjectRes {
+	name, uid := task.ExternalID, task.ExternalID
+	if task.Source != CalDAVSource {
+		name = strconv.Itoa(task.ID)
+		uid = "to-do-api-task-" + name
+	}
+	ics := ical.Encode(task, uid)
+	return &davObjectRes{name: name, task: task, ics: ics, etag: etagOf(ics)}
+}
+
+// href is the object's path
+func (o *davObjectRes) href() string {
+	return caldavTasks + url.PathEscape(o.name) + ".ics"
+}
+
+// davServed reports whether a task is exposed over CalDAV. Archived tasks
+// are hidden, and encrypted ones would only show ciphertext.
+func davServed(task *models.Task) bool {
+	return task != nil && !task.Archived && task.Encryption == nil
+}
+
+// CalDAV handles everything under /caldav/, a CalDAV server (RFC 4791)
+// exposing the tasks as VTODOs so clients such as Apple Reminders,
+// Thunderbird and Tasks.org (through DAVx5) can sync them. The server URL
+// to configure is the base URL; /.well-known/caldav redirects to /caldav/.
+func (h *TaskHandler) CalDAV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	path := r.URL.Path
+
+	switch {
+	case path == caldavRoot:
+		h.davCollection(w, r, davRoot, "OPTIONS, PROPFIND")
+	case path == caldavTasks || path == strings.TrimSuffix(caldavTasks, "/"):
+		h.davCollection(w, r, davCalendar, "OPTIONS, PROPFIND, PROPPATCH, REPORT")
+	case strings.HasPrefix(path, caldavTasks) && strings.HasSuffix(path, ".ics") && !strings.Contains(path[len(caldavTasks):], "/"):
+		name := strings.TrimSuffix(path[len(caldavTasks):], ".ics")
+		h.davObject(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// davCollection serves the root or the calendar collection
+func (h *TaskHandler) davCollection(w http.ResponseWriter, r *http.Request, kind davKind, allow string) {
+	switch {
+	case r.Method == http.MethodOptions:
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PROPFIND":
+		h.davPropfind(w, r, kind)
+	case r.Method == "PROPPATCH" && kind == davCalendar:
+		h.davProppatch(w, r)
+	case r.Method == "REPORT" && kind == davCalendar:
+		h.davReport(w, r)
+	default:
+		w.Header().Set("Allow", allow)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// davObject serves one task
+func (h *TaskHandler) davObject(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		h.davGet(w, r, name)
+	case http.MethodPut:
+		h.davPut(w, r, name)
+	case http.MethodDelete:
+		h.davDelete(w, r, name)
+	case "PROPFIND":
+		req, err := parseDAVRequest(r)
+		if err != nil {
+			http.Error(w, "Invalid XML body", http.StatusBadRequest)
+			return
+		}
+		obj, ok := h.davLookup(w, name)
+		if !ok {
+			return
+		}
+		if obj == nil {
+			http.NotFound(w, r)
+			return
+		}
+		writeMultistatus(w, []davResponse{davPropResponse(obj.href(), req, davObject, obj, "")})
+	default:
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// davLookup finds the task behind a resource name: the task a CalDAV
+// client created under that name, or else the task with that ID. It
+// answers 500 itself and returns ok false on error; obj is nil when there
+// is no such task.
+func (h *TaskHandler) davLookup(w http.ResponseWriter, name string) (obj *davObjectRes, ok bool) {
+	filter := models.TaskFilter{External: &models.ExternalRef{Source: CalDAVSource, ExternalID: name}}
+	tasks, err := h.repo.GetAllPaginated(filter, 1, 0, "created_at", "asc")
+	if err != nil {
+		log.Printf("Error fetching task: %v", err)
+		http.Error(w, "Failed to fetch task", http.StatusInternalServerError)
+		return nil, false
+	}
+	var task *models.Task
+	if len(tasks) > 0 {
+		task = &tasks[0]
+	} else if id, err := strconv.Atoi(name); err == nil && id > 0 && strconv.Itoa(id) == name {
+		task, err = h.repo.GetByID(id)
+		if err != nil {
+			log.Printf("Error fetching task: %v", err)
+			http.Error(w, "Failed to fetch task", http.StatusInternalServerError)
+			return nil, false
+		}
+		if task != nil && task.Source == CalDAVSource {
+			// Named by its external ID instead
+			task = nil
+		}
+	}
+	if !davServed(task) {
+		return nil, true
+	}
+	return newDAVObject(task), true
+}
+
+// davObjects lists every task served over CalDAV, with the collection's
+// ctag, which changes whenever any of them does
+func (h *TaskHandler) davObjects() ([]*davObjectRes, string, error) {
+	tasks, err := h.repo.GetAll()
+	if err != nil {
+		return nil, "", err
+	}
+	var objects []*davObjectRes
+	var tags bytes.Buffer
+	for i := range tasks {
+		if !davServed(&tasks[i]) {
+			continue
+		}
+		obj := newDAVObject(&tasks[i])
+		objects = append(objects, obj)
+		tags.WriteString(obj.name + " " + obj.etag + "\n")
+	}
+	return objects, etagOf(tags.Bytes()), nil
+}
+
+// davGet handles GET and HEAD of a calendar object
+func (h *TaskHandler) davGet(w http.ResponseWriter, r *http.Request, name string) {
+	obj, ok := h.davLookup(w, name)
+	if !ok {
+		return
+	}
+	if obj == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Last-Modified", obj.task.UpdatedAt.UTC().Format(http.TimeFormat))
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagListed(inm, obj.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.ics)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(obj.ics)
+}
+
+// davPreconditionsHold checks If-Match and If-None-Match against the
+// current version of a resource, nil when it does not exist
+func davPreconditionsHold(r *http.Request, obj *davObjectRes) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if obj == nil {
+			return false
+		}
+		// Strong comparison: weak tags never match
+		matched := false
+		for _, candidate := range strings.Split(ifMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || candidate == obj.etag {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" && obj != nil && etagListed(inm, obj.etag) {
+		return false
+	}
+	return true
+}
+
+// davVersionCheck makes a conditional write to obj land only on the
+// version its preconditions were checked against
+func davVersionCheck(r *http.Request, obj *davObjectRes) models.VersionCheck {
+	if r.Header.Get("If-Match") == "" && r.Header.Get("If-None-Match") == "" {
+		return nil
+	}
+	return unchangedSince(obj.task)
+}
+
+// davPut handles PUT of a calendar object, creating or replacing a task.
+// Properties the VTODO lacks are cleared on the task.
+func (h *TaskHandler) davPut(w http.ResponseWriter, r *http.Request, name string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, caldavMaxBody))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	todo, err := ical.Parse(body)
+	if errors.Is(err, ical.ErrNoTodo) {
+		writeDAVError(w, http.StatusForbidden, nsCalDAV, "supported-calendar-component")
+		return
+	}
+	if err != nil {
+		log.Printf("Rejected CalDAV object %s: %v", name, err)
+		writeDAVError(w, http.StatusForbidden, nsCalDAV, "valid-calendar-data")
+		return
+	}
+
+	obj, ok := h.davLookup(w, name)
+	if !ok {
+		return
+	}
+	if !davPreconditionsHold(r, obj) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	var task *models.Task
+	status := http.StatusNoContent
+	if obj != nil {
+		patch := todo.Patch()
+		patch.Normalize()
+		if err := patch.Validate(); err != nil {
+			log.Printf("Rejected CalDAV object %s: %v", name, err)
+			writeDAVError(w, http.StatusForbidden, nsCalDAV, "valid-calendar-object-resource")
+			return
+		}
+		task, err = h.repo.Patch(obj.task.ID, &patch, davVersionCheck(r, obj))
+	} else {
+		req := todo.Request()
+		req.Normalize()
+		if err := models.ValidateExternalRef(CalDAVSource, name); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			log.Printf("Rejected CalDAV object %s: %v", name, err)
+			writeDAVError(w, http.StatusForbidden, nsCalDAV, "valid-calendar-object-resource")
+			return
+		}
+		if err := h.encryption.Check(nil); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		quota, err := h.checkTaskQuota(1)
+		if err != nil {
+			log.Printf("Error checking task quota: %v", err)
+			http.Error(w, "Failed to create task", http.StatusInternalServerError)
+			return
+		}
+		if quota.exceeded {
+			// WebDAV's way of saying the store is full
+			http.Error(w, "Task quota reached", http.StatusInsufficientStorage)
+			return
+		}
+		task, _, err = h.repo.UpsertExternal(CalDAVSource, name, &req)
+		status = http.StatusCreated
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+		var verr *models.ValidationError
+		if errors.As(err, &verr) {
+			log.Printf("Rejected CalDAV object %s: %v", name, err)
+			writeDAVError(w, http.StatusForbidden, nsCalDAV, "valid-calendar-object-resource")
+			return
+		}
+		log.Printf("Error saving CalDAV object %s: %v", name, err)
+		http.Error(w, "Failed to save task", http.StatusInternalServerError)
+		return
+	}
+	if task == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", newDAVObject(task).etag)
+	w.WriteHeader(status)
+}
+
+// davDelete handles DELETE of a calendar object. It can be undone like any
+// other delete.
+func (h *TaskHandler) davDelete(w http.ResponseWriter, r *http.Request, name string) {
+	obj, ok := h.davLookup(w, name)
+	if !ok {
+		return
+	}
+	if obj == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !davPreconditionsHold(r, obj) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+	recordUndo, err := h.prepareUndo("delete", []int{obj.task.ID}, models.TaskFilter{}, true)
+	if err != nil {
+		log.Printf("Error deleting task: %v", err)
+		http.Error(w, "Failed to delete task", http.StatusInternalServerError)
+		return
+	}
+	if err := h.repo.Delete(obj.task.ID, davVersionCheck(r, obj)); err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		if errors.Is(err, models.ErrVersionMismatch) {
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+		log.Printf("Error deleting task: %v", err)
+		http.Error(w, "Failed to delete task", http.StatusInternalServerError)
+		return
+	}
+	recordUndo()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// davPropfind handles PROPFIND on the root or the calendar. Depth 1, the
+// default, includes the members; deeper requests are treated as Depth 1.
+func (h *TaskHandler) davPropfind(w http.ResponseWriter, r *http.Request, kind davKind) {
+	req, err := parseDAVRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid XML body", http.StatusBadRequest)
+		return
+	}
+	objects, ctag, err := h.davObjects()
+	if err != nil {
+		log.Printf("Error listing tasks: %v", err)
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+	members := r.Header.Get("Depth") != "0"
+
+	var responses []davResponse
+	if kind == davRoot {
+		responses = append(responses, davPropResponse(caldavRoot, req, davRoot, nil, ""))
+		if members {
+			responses = append(responses, davPropResponse(caldavTasks, req, davCalendar, nil, ctag))
+		}
+	} else {
+		responses = append(responses, davPropResponse(caldavTasks, req, davCalendar, nil, ctag))
+		if members {
+			for _, obj := range objects {
+				responses = append(responses, davPropResponse(obj.href(), req, davObject, obj, ""))
+			}
+		}
+	}
+	writeMultistatus(w, responses)
+}
+
+// davProppatch refuses every property change on the calendar; its name
+// and color are fixed
+func (h *TaskHandler) davProppatch(w http.ResponseWriter, r *http.Request) {
+	req, err := parseDAVRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid XML body", http.StatusBadRequest)
+		return
+	}
+	stat := davPropstat{status: http.StatusForbidden}
+	for _, name := range req.props {
+		stat.props = append(stat.props, emptyElement(name))
+	}
+	writeMultistatus(w, []davResponse{{href: caldavTasks, propstats: []davPropstat{stat}}})
+}
+
+// davReport handles the calendar-query and calendar-multiget reports.
+// Queries return every task unless they only ask for other components;
+// time-range and property filters are not applied.
+func (h *TaskHandler) davReport(w http.ResponseWriter, r *http.Request) {
+	req, err := parseDAVRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid XML body", http.StatusBadRequest)
+		return
+	}
+	objects, _, err := h.davObjects()
+	if err != nil {
+		log.Printf("Error listing tasks: %v", err)
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	responses := []davResponse{}
+	switch req.root {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		for _, comp := range req.comps {
+			if comp != "VCALENDAR" && comp != "VTODO" {
+				objects = nil
+			}
+		}
+		for _, obj := range objects {
+			responses = append(responses, davPropResponse(obj.href(), req, davObject, obj, ""))
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		byHref := make(map[string]*davObjectRes, len(objects))
+		for _, obj := range objects {
+			byHref[obj.href()] = obj
+		}
+		for _, href := range req.hrefs {
+			path := href
+			if u, err := url.Parse(href); err == nil {
+				path = u.EscapedPath()
+			}
+			if obj, ok := byHref[path]; ok {
+				responses = append(responses, davPropResponse(obj.href(), req, davObject, obj, ""))
+			} else {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+			}
+		}
+	default:
+		writeDAVError(w, http.StatusForbidden, nsDAV, "supported-report")
+		return
+	}
+	writeMultistatus(w, responses)
+}
+
+// davRequest is the parsed body of a PROPFIND, PROPPATCH or REPORT
+type davRequest struct {
+	root    xml.Name
+	allProp bool
+	props   []xml.Name
+	hrefs   []string
+	comps   []string
+}
+
+// parseDAVRequest reads the requested properties, hrefs and component
+// filters from a request body. An empty body asks for all properties.
+func parseDAVRequest(r *http.Request) (*davRequest, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, caldavMaxBody))
+	if err != nil {
+		return nil, err
+	}
+	req := &davRequest{}
+	if len(bytes.TrimSpace(body)) == 0 {
+		req.allProp = true
+		return req, nil
+	}
+
+	propName := xml.Name{Space: nsDAV, Local: "prop"}
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var stack []xml.Name
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				req.root = t.Name
+			} else if stack[len(stack)-1] == propName {
+				req.props = append(req.props, t.Name)
+			}
+			switch t.Name {
+			case xml.Name{Space: nsDAV, Local: "allprop"}, xml.Name{Space: nsDAV, Local: "propname"}:
+				req.allProp = true
+			case xml.Name{Space: nsCalDAV, Local: "comp-filter"}:
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "name" {
+						req.comps = append(req.comps, strings.ToUpper(attr.Value))
+					}
+				}
+			}
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 && stack[len(stack)-1] == (xml.Name{Space: nsDAV, Local: "href"}) {
+				req.hrefs = append(req.hrefs, strings.TrimSpace(string(t)))
+			}
+		}
+	}
+	if req.root == (xml.Name{Space: nsDAV, Local: "propfind"}) && len(req.props) == 0 {
+		req.allProp = true
+	}
+	return req, nil
+}
+
+// davAllProps are returned for allprop requests
+var davAllProps = []xml.Name{
+	{Space: nsDAV, Local: "resourcetype"},
+	{Space: nsDAV, Local: "displayname"},
+	{Space: nsDAV, Local: "getetag"},
+	{Space: nsDAV, Local: "getcontenttype"},
+	{Space: nsDAV, Local: "getcontentlength"},
+	{Space: nsDAV, Local: "getlastmodified"},
+	{Space: nsCS, Local: "getctag"},
+	{Space: nsCalDAV, Local: "supported-calendar-component-set"},
+}
+
+// davPropstat is a group of rendered properties sharing a status
+type davPropstat struct {
+	status int
+	props  []string
+}
+
+// davResponse is one response of a multistatus. A non-zero status stands
+// for the whole resource, without properties.
+type davResponse struct {
+	href      string
+	status    int
+	propstats []davPropstat
+}
+
+// davPropResponse answers the requested properties of one resource.
+// Unknown properties are reported as 404, except for allprop requests.
+func davPropResponse(href string, req *davRequest, kind davKind, obj *davObjectRes, ctag string) davResponse {
+	names := req.props
+	if req.allProp {
+		names = davAllProps
+	}
+	found := davPropstat{status: http.StatusOK}
+	missing := davPropstat{status: http.StatusNotFound}
+	for _, name := range names {
+		value, ok := davProp(name, kind, obj, ctag)
+		if ok {
+			found.props = append(found.props, value)
+		} else if !req.allProp {
+			missing.props = append(missing.props, emptyElement(name))
+		}
+	}
+	res := davResponse{href: href}
+	for _, stat := range []davPropstat{found, missing} {
+		if len(stat.props) > 0 {
+			res.propstats = append(res.propstats, stat)
+		}
+	}
+	return res
+}
+
+// davProp renders one property of a resource, reporting false when the
+// resource doesn't have it
+func davProp(name xml.Name, kind davKind, obj *davObjectRes, ctag string) (string, bool) {
+	home := "<d:href>" + caldavRoot + "</d:href>"
+	switch name.Space + " " + name.Local {
+	case nsDAV + " resourcetype":
+		switch kind {
+		case davRoot:
+			return "<d:resourcetype><d:collection/><d:principal/></d:resourcetype>", true
+		case davCalendar:
+			return "<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>", true
+		}
+		return "<d:resourcetype/>", true
+	case nsDAV + " displayname":
+		switch kind {
+		case davRoot:
+			return "<d:displayname>to-do-api</d:displayname>", true
+		case davCalendar:
+			return "<d:displayname>Tasks</d:displayname>", true
+		}
+	case nsDAV + " current-user-principal":
+		return "<d:current-user-principal>" + home + "</d:current-user-principal>", true
+	case nsDAV + " principal-URL":
+		return "<d:principal-URL>" + home + "</d:principal-URL>", true
+	case nsDAV + " owner":
+		return "<d:owner>" + home + "</d:owner>", true
+	case nsCalDAV + " calendar-home-set":
+		return "<c:calendar-home-set>" + home + "</c:calendar-home-set>", true
+	case nsDAV + " current-user-privilege-set":
+		var b strings.Builder
+		b.WriteString("<d:current-user-privilege-set>")
+		for _, p := range []string{"read", "write", "write-properties", "write-content", "bind", "unbind", "read-current-user-privilege-set"} {
+			b.WriteString("<d:privilege><d:" + p + "/></d:privilege>")
+		}
+		b.WriteString("</d:current-user-privilege-set>")
+		return b.String(), true
+	case nsDAV + " supported-report-set":
+		if kind == davCalendar {
+			return "<d:supported-report-set>" +
+				"<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+				"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>" +
+				"</d:supported-report-set>", true
+		}
+	case nsCalDAV + " supported-calendar-component-set":
+		if kind == davCalendar {
+			return `<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`, true
+		}
+	case nsCS + " getctag":
+		if kind == davCalendar {
+			return "<cs:getctag>" + xmlText(ctag) + "</cs:getctag>", true
+		}
+	case nsDAV + " getetag":
+		switch kind {
+		case davCalendar:
+			return "<d:getetag>" + xmlText(ctag) + "</d:getetag>", true
+		case davObject:
+			return "<d:getetag>" + xmlText(obj.etag) + "</d:getetag>", true
+		}
+	case nsDAV + " getcontenttype":
+		if kind == davObject {
+			return "<d:getcontenttype>" + ical.ContentType + "</d:getcontenttype>", true
+		}
+	case nsDAV + " getcontentlength":
+		if kind == davObject {
+			return "<d:getcontentlength>" + strconv.Itoa(len(obj.ics)) + "</d:getcontentlength>", true
+		}
+	case nsDAV + " getlastmodified":
+		if kind == davObject {
+			return "<d:getlastmodified>" + obj.task.UpdatedAt.UTC().Format(http.TimeFormat) + "</d:getlastmodified>", true
+		}
+	case nsCalDAV + " calendar-data":
+		if kind == davObject {
+			return "<c:calendar-data>" + xmlText(string(obj.ics)) + "</c:calendar-data>", true
+		}
+	}
+	return "", false
+}
+
+// emptyElement renders a property name as an empty element in its own
+// namespace
+func emptyElement(name xml.Name) string {
+	return "<" + name.Local + ` xmlns="` + xmlText(name.Space) + `"/>`
+}
+
+// xmlText escapes s for XML character data and attributes
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeMultistatus writes a 207 Multi-Status response
+func writeMultistatus(w http.ResponseWriter, responses []davResponse) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCS + `">`)
+	for _, res := range responses {
+		b.WriteString("<d:response><d:href>" + xmlText(res.href) + "</d:href>")
+		if res.status != 0 {
+			b.WriteString("<d:status>" + davStatus(res.status) + "</d:status>")
+		}
+		for _, stat := range res.propstats {
+			b.WriteString("<d:propstat><d:prop>" + strings.Join(stat.props, "") + "</d:prop>")
+			b.WriteString("<d:status>" + davStatus(stat.status) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write([]byte(b.String()))
+}
+
+// davStatus renders a status line for a multistatus
+func davStatus(code int) string {
+	return "HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code)
+}
+
+// writeDAVError answers with a WebDAV error body naming the failed
+// precondition
+func writeDAVError(w http.ResponseWriter, status int, space, condition string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header + `<d:error xmlns:d="DAV:">` + emptyElement(xml.Name{Space: space, Local: condition}) + `</d:error>`))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// changeItem is a change as the feed renders it, with the task's _links
+type changeItem struct {
+	Seq       int64       `json:"seq"`
+	Type      string      `json:"type"`
+	TaskID    int         `json:"task_id"`
+	Task      interface{} `json:"task,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+// ChangesPage is a page of the changes feed. NextCursor is the since to
+// poll with next; it is returned even when there are no changes, so a
+// client can always carry it over.
+type ChangesPage struct {
+	Changes    []changeItem `json:"changes"`
+	NextCursor string       `json:"next_cursor"`
+	HasMore    bool         `json:"has_more"`
+}
+
+// GetChanges handles GET /api/changes?since=<cursor>. It lists the tasks
+// created, updated or deleted after the cursor, oldest change first, each
+// once with its current state; deleted tasks are tombstones with only
+// their ID. Without since the feed starts from the beginning, which a
+// client uses for its first full sync.
+func (h *TaskHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	since, ok := parseCursor(r.URL.Query().Get("since"))
+	if !ok {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid since", "since must be a cursor returned as next_cursor")
+		return
+	}
+
+	page, err := h.changesPage(since, parseLimit(r.URL.Query().Get("limit"), 100))
+	if err != nil {
+		log.Printf("Error listing changes: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve changes", "")
+		return
+	}
+	h.sendSuccessResponse(w, http.StatusOK, "Changes retrieved successfully", page)
+}
+
+// changesPage reads up to limit changes after since
+func (h *TaskHandler) changesPage(since int64, limit int) (ChangesPage, error) {
+	// One more than asked tells whether there is another page
+	changes, err := h.repo.ListChanges(since, limit+1)
+	if err != nil {
+		return ChangesPage{}, err
+	}
+
+	page := ChangesPage{Changes: make([]changeItem, 0, len(changes)), NextCursor: strconv.FormatInt(since, 10)}
+	if len(changes) > limit {
+		changes, page.HasMore = changes[:limit], true
+	}
+	for _, change := range changes {
+		item := changeItem{Seq: change.Seq, Type: change.Type, TaskID: change.TaskID, ChangedAt: change.ChangedAt}
+		if change.Task != nil {
+			item.Task = withTaskLinks(change.Task)
+		}
+		page.Changes = append(page.Changes, item)
+		page.NextCursor = strconv.FormatInt(change.Seq, 10)
+	}
+	return page, nil
+}
+
+// parseCursor parses a changes cursor; empty means the beginning
+func parseCursor(v string) (int64, bool) {
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil && n >= 0
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+	"to-do-api/models"
+)
+
+// cursorToken is the JSON payload behind an opaque cursor
+type cursorToken struct {
+	Pinned    bool      `json:"p,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	ID        int       `json:"i"`
+}
+
+// encodeCursor returns the cursor pointing just past task
+func encodeCursor(task models.Task) string {
+	data, _ := json.Marshal(cursorToken{Pinned: task.Pinned, CreatedAt: task.CreatedAt, ID: task.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(s string, desc bool) (*models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("cursor is malformed")
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || token.ID <= 0 {
+		return nil, errors.New("cursor is malformed")
+	}
+	return &models.Cursor{Pinned: token.Pinned, CreatedAt: token.CreatedAt, ID: token.ID, Desc: desc}, nil
+}
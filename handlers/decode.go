@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"to-do-api/codec"
+	"to-do-api/models"
+)
+
+// immutableFields are server-managed task fields that clients may not set
+var immutableFields = []string{"id", "created_at", "updated_at", "completed_at"}
+
+// decodeTaskBody decodes a task payload (an object, or an array of objects for
+// bulk requests) into v, rejecting any attempt to set an immutable field.
+// XML bodies are translated to JSON first, and a JSON:API document is read
+// as its resource's attributes.
+func decodeTaskBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	format := codec.ForContentType(r.Header.Get("Content-Type"))
+	if body, err = format.Decode(body, v); err != nil {
+		return &bodyFormatError{err: err}
+	}
+	if isJSONAPIBody(r) {
+		if body, err = unwrapJSONAPIBody(body); err != nil {
+			return err
+		}
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err == nil {
+			for _, item := range items {
+				if err := checkImmutableFields(item); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &fields); err == nil {
+			if err := checkImmutableFields(fields); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil && format != codec.JSON {
+		// Type errors in translated bodies are not about JSON the client sent
+		return &bodyFormatError{err: err}
+	}
+	return err
+}
+
+// bodyFormatError reports a request body that could not be translated from
+// its declared format into JSON
+type bodyFormatError struct {
+	err error
+}
+
+func (e *bodyFormatError) Error() string {
+	return e.err.Error()
+}
+
+// checkImmutableFields reports the first immutable field present in fields
+func checkImmutableFields(fields map[string]json.RawMessage) error {
+	for _, name := range immutableFields {
+		if _, ok := fields[name]; ok {
+			return &models.ValidationError{Field: name, Message: name + " is read-only and cannot be set"}
+		}
+	}
+	return nil
+}
+
+// sendDecodeError reports a failed decodeTaskBody call
+func (h *TaskHandler) sendDecodeError(w http.ResponseWriter, err error) {
+	if verr, ok := err.(*models.ValidationError); ok {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+		return
+	}
+	if _, ok := err.(*bodyFormatError); ok {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"to-do-api/middleware"
+	"to-do-api/models"
+)
+
+// etagOf returns a strong entity tag for data
+func etagOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// taskETag identifies a version of a task. It hashes the task's JSON, so a
+// change to any stored field gives a new tag, including changes that leave
+// updated_at alone.
+func taskETag(task *models.Task) string {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return ""
+	}
+	return etagOf(data)
+}
+
+// variantETag derives the tag of another representation of the resource
+// tagged etag, such as a task with ?fields=, from the parameters that set
+// it apart. Different representations never share a tag.
+func variantETag(etag string, params ...string) string {
+	if etag == "" || len(params) == 0 {
+		return etag
+	}
+	return etagOf([]byte(etag + "\n" + strings.Join(params, "\n")))
+}
+
+// formattedETag mixes an X-Timestamp-Format header value into etag. The
+// TimestampFormat middleware rewrites the body after it has been tagged,
+// so the tag has to tell the formats apart itself.
+func formattedETag(etag, format string) string {
+	if format == "" {
+		return etag
+	}
+	return variantETag(etag, "timestamps="+strings.ToLower(format))
+}
+
+// etagListed reports whether an If-None-Match or If-Match header value
+// names etag, or is "*". Weak tags match their strong counterpart, which
+// is the comparison If-None-Match calls for.
+func etagListed(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag and cache headers for a GET response and, when
+// the client's If-None-Match already names etag, answers 304 Not Modified
+// and returns true
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = formattedETag(etag, r.Header.Get(middleware.TimestampFormatHeader))
+	w.Header().Set("ETag", etag)
+	// The same URL may be served as JSON:API, depending on Accept, and with
+	// other timestamps
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", middleware.TimestampFormatHeader)
+	// Clients may keep the response but must revalidate before reusing it
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagListed(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// ifMatchGuard holds the If-Match settings of the task API
+type ifMatchGuard struct {
+	required bool
+}
+
+// SetRequireIfMatch makes If-Match mandatory on PUT, PATCH and DELETE of a
+// single task. Requests without it are refused with 428. When not required,
+// the header is still honored whenever a client sends it.
+func (h *TaskHandler) SetRequireIfMatch(required bool) {
+	h.ifMatch.required = required
+}
+
+// ifMatchCondition is a request's If-Match header. The repository checks
+// it against the stored task under the same lock or transaction as the
+// write, so no other write can land between the check and the write.
+type ifMatchCondition struct {
+	header string
+	// format is the request's X-Timestamp-Format, which GET mixes into
+	// the tags it sends
+	format string
+	// current is the ETag of the task the check last saw, for a 412
+	current string
+}
+
+// ifMatchFor reads the If-Match header of a write to a single task. It
+// answers 428 itself and returns ok false when the header is required but
+// missing; a nil condition means the write is unconditional.
+func (h *TaskHandler) ifMatchFor(w http.ResponseWriter, r *http.Request) (cond *ifMatchCondition, ok bool) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		if h.ifMatch.required {
+			h.sendErrorResponse(w, http.StatusPreconditionRequired, "Precondition required", "Send If-Match with the task's ETag from GET /api/tasks/{id}")
+			return nil, false
+		}
+		return nil, true
+	}
+	return &ifMatchCondition{header: ifMatch, format: r.Header.Get(middleware.TimestampFormatHeader)}, true
+}
+
+// check returns the version check to pass to the repository
+func (c *ifMatchCondition) check() models.VersionCheck {
+	if c == nil {
+		return nil
+	}
+	return func(task *models.Task) bool {
+		// If-Match calls for strong comparison, so weak tags never match
+		c.current = taskETag(task)
+		formatted := formattedETag(c.current, c.format)
+		for _, candidate := range strings.Split(c.header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || candidate == c.current || candidate == formatted {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sendPreconditionFailed answers a write whose If-Match no longer matched
+// the task with 412 and the task's current ETag
+func (h *TaskHandler) sendPreconditionFailed(w http.ResponseWriter, cond *ifMatchCondition) {
+	if cond != nil && cond.current != "" {
+		w.Header().Set("ETag", cond.current)
+	}
+	h.sendErrorResponse(w, http.StatusPreconditionFailed, "Precondition failed", "The task was modified since it was read; fetch it again and retry")
+}
+
+// unchangedSince returns a check that passes only while the stored task is
+// still the version read, for writes computed from that version
+func unchangedSince(task *models.Task) models.VersionCheck {
+	etag := taskETag(task)
+	return func(current *models.Task) bool {
+		return taskETag(current) == etag
+	}
+}
+
+// sendCacheableJSON writes response like sendJSONResponse, tagged with a
+// hash of the body so unchanged responses can be answered with 304
+func (h *TaskHandler) sendCacheableJSON(w http.ResponseWriter, r *http.Request, statusCode int, response interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to encode response", "")
+		return
+	}
+	if notModified(w, r, etagOf(body.Bytes())) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"to-do-api/models"
+)
+
+// exportMaxTasks caps how many tasks a Markdown export lists
+const exportMaxTasks = 5000
+
+// exportGroups are the status sections of a Markdown export, in order
+var exportGroups = []struct {
+	status  string
+	heading string
+}{
+	{"in_progress", "In progress"},
+	{"pending", "Pending"},
+	{"completed", "Completed"},
+}
+
+// markdownEscaper escapes the characters that would turn a title into
+// Markdown formatting
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// ExportMarkdown handles GET /api/tasks/export.md
+// It writes the tasks as a Markdown checklist grouped by status, for pasting
+// into notes or READMEs. The list filters and sorting apply (by position
+// unless sort_by says otherwise), and tz picks the zone due dates are shown in.
+func (h *TaskHandler) ExportMarkdown(w http.ResponseWriter, r *http.Request) {
+	params, perr := parseListParams(r.URL.Query(), h.clock.Now(), "position", "asc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tz", "tz must be an IANA time zone name such as Europe/Berlin")
+		return
+	}
+
+	tasks, err := h.repo.GetAllPaginated(params.filter, exportMaxTasks, 0, params.sortBy, params.sortOrder)
+	if err != nil {
+		log.Printf("Error fetching tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to export tasks", "")
+		return
+	}
+
+	groups := make(map[string][]models.Task, len(exportGroups))
+	for _, task := range tasks {
+		groups[task.Status] = append(groups[task.Status], task)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Tasks\n")
+	if len(tasks) == 0 {
+		b.WriteString("\n_No tasks._\n")
+	}
+	for _, group := range exportGroups {
+		list := groups[group.status]
+		if len(list) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", group.heading, len(list))
+		for _, task := range list {
+			writeMarkdownTask(&b, task, loc)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// writeMarkdownTask writes one checklist item. The description, already
+// Markdown, follows indented so it stays part of the item.
+func writeMarkdownTask(b *strings.Builder, task models.Task, loc *time.Location) {
+	box := "[ ]"
+	if task.Status == "completed" {
+		box = "[x]"
+	}
+	title, description := task.Title, task.Description
+	if task.Encryption != nil {
+		// Ciphertext is no use in a document
+		title, description = "Encrypted task", ""
+	}
+	fmt.Fprintf(b, "- %s %s", box, markdownEscaper.Replace(title))
+	if task.DueDate != nil {
+		due := task.DueDate.In(loc)
+		if due.Equal(startOfDay(due)) {
+			fmt.Fprintf(b, " (due %s)", due.Format("2006-01-02"))
+		} else {
+			fmt.Fprintf(b, " (due %s)", due.Format("2006-01-02 15:04"))
+		}
+	}
+	b.WriteString("\n")
+	if description = strings.TrimSpace(description); description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			if line == "" {
+				b.WriteString("\n")
+				continue
+			}
+			b.WriteString("  " + line + "\n")
+		}
+	}
+}
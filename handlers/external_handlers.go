@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"to-do-api/models"
+
+	"github.com/gorilla/mux"
+)
+
+// UpsertExternalTask handles PUT /api/tasks/external/{source}/{externalID}
+// It creates the task imported from source under externalID (201), or
+// updates it if it already exists (200), so importers can safely re-run.
+// The body is a full task as for POST /api/tasks.
+func (h *TaskHandler) UpsertExternalTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	source, externalID := vars["source"], vars["externalID"]
+	if err := models.ValidateExternalRef(source, externalID); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	var taskReq models.TaskRequest
+	if err := decodeTaskBody(r, &taskReq); err != nil {
+		h.sendDecodeError(w, err)
+		return
+	}
+
+	taskReq.Normalize()
+	if err := taskReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.encryption.Check(taskReq.Encryption); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	// The quota can't tell an update from a create here, so upserts are
+	// never refused; they still carry the warning
+	quota, err := h.checkTaskQuota(0)
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to save task", "")
+		return
+	}
+
+	task, created, err := h.repo.UpsertExternal(source, externalID, &taskReq)
+	if err != nil {
+		if verr, ok := err.(*models.ValidationError); ok {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+			return
+		}
+		log.Printf("Error upserting external task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to save task", "")
+		return
+	}
+
+	if created {
+		h.sendJSONResponse(w, http.StatusCreated, SuccessResponse{Message: "Task created successfully", Data: withTaskLinks(task), Warnings: quota.warnings})
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Task updated successfully", Data: withTaskLinks(task), Warnings: quota.warnings})
+}
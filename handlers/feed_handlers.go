@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"to-do-api/models"
+)
+
+// feedMaxEntries caps how many entries the activity feed lists
+const feedMaxEntries = 50
+
+// atomFeed is an Atom feed document (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string        `xml:"id"`
+	Title    string        `xml:"title"`
+	Updated  string        `xml:"updated"`
+	Link     atomLink      `xml:"link"`
+	Category *atomCategory `xml:"category,omitempty"`
+	Summary  string        `xml:"summary,omitempty"`
+	at       time.Time
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// GetActivityFeed handles GET /api/feed.atom
+// It lists the most recently created and completed tasks as an Atom feed,
+// newest first, so a shared list can be followed from any feed reader.
+// Entries are built from the stored tasks, so the feed survives restarts;
+// a task that was created and completed shows up twice.
+func (h *TaskHandler) GetActivityFeed(w http.ResponseWriter, r *http.Request) {
+	created, err := h.repo.GetAllPaginated(models.TaskFilter{}, feedMaxEntries, 0, "created_at", "desc")
+	if err != nil {
+		log.Printf("Error fetching tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to build feed", "")
+		return
+	}
+	// completed_at can't be sorted on, but it is never later than
+	// updated_at, so the latest completions are among the latest updates
+	completedFilter := models.TaskFilter{Statuses: []string{"completed"}}
+	completed, err := h.repo.GetAllPaginated(completedFilter, feedMaxEntries, 0, "updated_at", "desc")
+	if err != nil {
+		log.Printf("Error fetching tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to build feed", "")
+		return
+	}
+
+	base := requestBaseURL(r)
+	entry := func(task models.Task, kind string, at time.Time) atomEntry {
+		title := task.Title
+		summary := models.TruncateGraphemes(task.Description, models.SummaryLength)
+		if task.Encryption != nil {
+			// Ciphertext means nothing to a feed reader
+			title, summary = "Encrypted task", ""
+		}
+		id := strconv.Itoa(task.ID)
+		verb := "Created"
+		if kind == "completed" {
+			verb = "Completed"
+		}
+		return atomEntry{
+			ID:       "urn:to-do-api:task:" + id + ":" + kind,
+			Title:    verb + ": " + title,
+			Updated:  at.UTC().Format(time.RFC3339),
+			Link:     atomLink{Rel: "alternate", Href: base + "/api/tasks/" + id},
+			Category: &atomCategory{Term: "task." + kind},
+			Summary:  summary,
+			at:       at,
+		}
+	}
+
+	var entries []atomEntry
+	for _, task := range created {
+		entries = append(entries, entry(task, "created", task.CreatedAt))
+	}
+	for _, task := range completed {
+		if task.CompletedAt != nil {
+			entries = append(entries, entry(task, "completed", *task.CompletedAt))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].at.After(entries[j].at)
+	})
+	if len(entries) > feedMaxEntries {
+		entries = entries[:feedMaxEntries]
+	}
+
+	// An empty feed is dated now; otherwise by its newest entry
+	updated := h.clock.Now()
+	if len(entries) > 0 {
+		updated = entries[0].at
+	}
+	feed := atomFeed{
+		ID:      "urn:to-do-api:feed",
+		Title:   "Task activity",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: base + r.URL.RequestURI()},
+			{Rel: "alternate", Href: base + "/"},
+		},
+		Author:  atomAuthor{Name: "to-do-api"},
+		Entries: entries,
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Printf("Error rendering feed: %v", err)
+	}
+}
+
+// requestBaseURL is the scheme and host the client reached the API on, for
+// absolute links
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
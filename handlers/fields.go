@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"to-do-api/models"
+)
+
+// parseFieldsParam parses a comma-separated list of task fields, such as
+// "id,title,status". An empty value selects every field.
+func parseFieldsParam(v string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if !models.IsTaskField(field) {
+			return nil, fmt.Errorf("unknown field %q; fields are %s", field, strings.Join(models.TaskFieldNames(), ", "))
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// projectTask keeps only the given fields of a task's JSON. Fields that are
+// omitted when empty stay omitted.
+func projectTask(task *models.Task, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
+
+// taskExpansions are the related resources ?expand= can embed, the same
+// relationships JSON:API's include offers
+var taskExpansions = jsonAPIIncludes
+
+// parseExpandParam parses a comma-separated list of related resources to
+// embed in each task, such as "links,notes"
+func parseExpandParam(v string) (map[string]bool, error) {
+	if v == "" {
+		return nil, nil
+	}
+	expand := make(map[string]bool)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !taskExpansions[name] {
+			return nil, fmt.Errorf("expand must list links or notes, got %q", name)
+		}
+		expand[name] = true
+	}
+	return expand, nil
+}
+
+// taskShape is how a client asked for tasks to be shaped: limited to some
+// fields (?fields=) and with related resources embedded (?expand=)
+type taskShape struct {
+	fields []string
+	expand map[string]bool
+}
+
+// plain reports whether tasks are sent as they are
+func (s taskShape) plain() bool {
+	return len(s.fields) == 0 && len(s.expand) == 0
+}
+
+// parseTaskShape reads the fields and expand parameters
+func parseTaskShape(q url.Values) (taskShape, *paramError) {
+	var shape taskShape
+	var err error
+	if shape.fields, err = parseFieldsParam(q.Get("fields")); err != nil {
+		return shape, &paramError{"Invalid fields", err.Error()}
+	}
+	if shape.expand, err = parseExpandParam(q.Get("expand")); err != nil {
+		return shape, &paramError{"Invalid expand", err.Error()}
+	}
+	return shape, nil
+}
+
+// shapeTask renders a task as shape asks, fetching the related resources
+// to embed. Embedded lists are never null.
+func (h *TaskHandler) shapeTask(task *models.Task, shape taskShape) (map[string]json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	var err error
+	if len(shape.fields) > 0 {
+		doc, err = projectTask(task, shape.fields)
+	} else {
+		doc, err = projectTask(task, models.TaskFieldNames())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if shape.expand["links"] {
+		links, err := h.repo.ListLinks(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if links == nil {
+			links = []models.Link{}
+		}
+		if doc["links"], err = json.Marshal(links); err != nil {
+			return nil, err
+		}
+	}
+	if shape.expand["notes"] {
+		notes, err := h.repo.ListNotes(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if notes == nil {
+			notes = []models.Note{}
+		}
+		if doc["notes"], err = json.Marshal(notes); err != nil {
+			return nil, err
+		}
+	}
+	if err := setTaskLinks(doc, task.ID); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
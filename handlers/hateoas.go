@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"to-do-api/models"
+)
+
+// HALLink is an entry of a _links section. Method is set for links that
+// are not followed with GET.
+type HALLink struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// linkedTask is a task with its _links, as task responses render it
+type linkedTask struct {
+	models.Task
+	Links map[string]HALLink `json:"_links"`
+}
+
+// taskLinks returns the links of a task: itself, how to change and delete
+// it, and its notes and links
+func taskLinks(id int) map[string]HALLink {
+	self := "/api/tasks/" + strconv.Itoa(id)
+	return map[string]HALLink{
+		"self":   {Href: self},
+		"update": {Href: self, Method: http.MethodPatch},
+		"delete": {Href: self, Method: http.MethodDelete},
+		"notes":  {Href: self + "/notes"},
+		"links":  {Href: self + "/links"},
+	}
+}
+
+// withTaskLinks adds _links to a task or a list of tasks; other data is
+// returned as it is
+func withTaskLinks(data interface{}) interface{} {
+	switch v := data.(type) {
+	case *models.Task:
+		if v == nil {
+			return data
+		}
+		return linkedTask{Task: *v, Links: taskLinks(v.ID)}
+	case []models.Task:
+		linked := make([]linkedTask, len(v))
+		for i := range v {
+			linked[i] = linkedTask{Task: v[i], Links: taskLinks(v[i].ID)}
+		}
+		return linked
+	}
+	return data
+}
+
+// setTaskLinks adds _links to a shaped task document
+func setTaskLinks(doc map[string]json.RawMessage, id int) error {
+	links, err := json.Marshal(taskLinks(id))
+	if err != nil {
+		return err
+	}
+	doc["_links"] = links
+	return nil
+}
+
+// collectionLinks returns the links of a list response: the page itself
+// and, where they exist, the next and previous pages
+func collectionLinks(r *http.Request, pagination *Pagination) map[string]HALLink {
+	links := map[string]HALLink{"self": {Href: r.URL.RequestURI()}}
+	if pagination == nil {
+		return links
+	}
+	if pagination.Next != "" {
+		links["next"] = HALLink{Href: pagination.Next}
+	}
+	if pagination.Prev != "" {
+		links["prev"] = HALLink{Href: pagination.Prev}
+	}
+	return links
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Idempotency-Key limits. Responses are kept in memory only, so a restart
+// forgets them.
+const (
+	// DefaultIdempotencyTTL is how long a response is replayed for its key
+	DefaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+	// maxIdempotencyEntries bounds the store; the oldest entries go first
+	maxIdempotencyEntries = 10000
+	// maxIdempotentBody bounds request bodies sent with a key
+	maxIdempotentBody = 10 << 20
+)
+
+// idempotentResponse is a recorded response, or a request still in flight
+// while done is false
+type idempotentResponse struct {
+	fingerprint string
+	at          time.Time
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+// idempotencyStore maps (caller, key) to the response first given for it.
+// There are no user accounts, so the caller is told apart by its
+// Authorization header, and clients without one share a scope.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotentResponse
+	// order holds the keys oldest first, for expiry and eviction
+	order []string
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{ttl: ttl, entries: make(map[string]*idempotentResponse)}
+}
+
+// errIdempotencyMismatch means a key was reused for a different request
+var errIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// errIdempotencyInFlight means the first request with a key hasn't finished
+var errIdempotencyInFlight = errors.New("request with this idempotency key is in progress")
+
+// begin looks up a key. It returns the recorded response to replay, or nil
+// after reserving the key for the caller to run the request and finish it.
+func (s *idempotencyStore) begin(key, fingerprint string, now time.Time) (*idempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(now)
+
+	if entry, ok := s.entries[key]; ok {
+		switch {
+		case entry.fingerprint != fingerprint:
+			return nil, errIdempotencyMismatch
+		case !entry.done:
+			return nil, errIdempotencyInFlight
+		}
+		return entry, nil
+	}
+	s.entries[key] = &idempotentResponse{fingerprint: fingerprint, at: now}
+	s.order = append(s.order, key)
+	for len(s.entries) > maxIdempotencyEntries {
+		delete(s.entries, s.order[0])
+		s.order = s.order[1:]
+	}
+	return nil, nil
+}
+
+// finish records the response to a reserved key. Server errors are not
+// recorded, so the client can retry with the same key.
+func (s *idempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	if status >= 500 {
+		delete(s.entries, key)
+		// A retry appends the key again, so a stale copy here would
+		// expire or evict the retry's entry early
+		for i := len(s.order) - 1; i >= 0; i-- {
+			if s.order[i] == key {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	entry.done, entry.status, entry.header, entry.body = true, status, header, body
+}
+
+// expire drops entries older than the TTL. Callers hold mu.
+func (s *idempotencyStore) expire(now time.Time) {
+	n := 0
+	for n < len(s.order) {
+		entry, ok := s.entries[s.order[n]]
+		if ok && now.Sub(entry.at) <= s.ttl {
+			break
+		}
+		if ok {
+			delete(s.entries, s.order[n])
+		}
+		n++
+	}
+	s.order = s.order[n:]
+}
+
+// SetIdempotencyTTL sets how long responses are replayed for an
+// Idempotency-Key; zero turns Idempotency-Key support off
+func (h *TaskHandler) SetIdempotencyTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		h.idempotency = nil
+		return
+	}
+	h.idempotency = newIdempotencyStore(ttl)
+}
+
+// Idempotent wraps a task creating handler to honor the Idempotency-Key
+// request header. The first response for a key is recorded and replayed,
+// with Idempotent-Replayed: true, to retries with the same key and body, so
+// a client on a flaky network can resend a POST without creating the task
+// twice. Reusing a key for a different request is refused with 422, and a
+// retry while the first request is still running with 409. Requests without
+// the header are passed through.
+func (h *TaskHandler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || h.idempotency == nil {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid Idempotency-Key", fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLen))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body too large", "")
+				return
+			}
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyHash(r.Header.Get("Authorization"))
+		storeKey := scope + " " + key
+		fingerprint := idempotencyHash(r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), string(body))
+
+		recorded, err := h.idempotency.begin(storeKey, fingerprint, h.clock.Now())
+		switch err {
+		case errIdempotencyMismatch:
+			h.sendErrorResponse(w, http.StatusUnprocessableEntity, "Idempotency-Key reused", "This key was already used for a different request; use a new key for each new request")
+			return
+		case errIdempotencyInFlight:
+			w.Header().Set("Retry-After", "1")
+			h.sendErrorResponse(w, http.StatusConflict, "Request in progress", "A request with this Idempotency-Key is still being processed; retry shortly")
+			return
+		}
+		if recorded != nil {
+			for name, values := range recorded.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(recorded.status)
+			w.Write(recorded.body)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w, before: w.Header().Clone(), status: http.StatusOK}
+		defer func() {
+			// A panicking handler leaves the key reserved with no response,
+			// so release it for the retry
+			if !rec.wroteHeader {
+				rec.status = http.StatusInternalServerError
+			}
+			h.idempotency.finish(storeKey, rec.status, rec.header, rec.body.Bytes())
+		}()
+		next(rec, r)
+	}
+}
+
+// idempotencyHash hashes parts into a compact identifier
+func idempotencyHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// idempotencyRecorder passes a response through while keeping a copy of
+// its status, body and the headers the handler set
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	before      http.Header
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	// Only what the handler added; middleware sets the rest again on replay
+	r.header = http.Header{}
+	for name, values := range r.ResponseWriter.Header() {
+		if strings.Join(r.before[name], "\x00") != strings.Join(values, "\x00") {
+			r.header[name] = append([]string(nil), values...)
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
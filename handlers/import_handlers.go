@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"to-do-api/models"
+)
+
+// Import limits
+const (
+	maxImportRows  = 1000
+	maxImportBytes = 5 << 20
+)
+
+// importColumns are the CSV columns an import understands; other columns
+// are ignored with a warning
+var importColumns = map[string]bool{
+	"title": true, "description": true, "status": true, "progress": true,
+	"color": true, "start_date": true, "due_date": true,
+}
+
+// ImportRow reports the outcome for one row of an import. Row counts from 1
+// for JSON items and is the line number for CSV rows, so the header is row
+// 1 and the first task row 2. Preview holds the task a dry run would create.
+type ImportRow struct {
+	Row     int                 `json:"row"`
+	Preview *models.TaskRequest `json:"preview,omitempty"`
+	Task    *models.Task        `json:"task,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	DryRun   bool        `json:"dry_run"`
+	Valid    int         `json:"valid"`
+	Invalid  int         `json:"invalid"`
+	Imported int         `json:"imported"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+// ImportTasks handles POST /api/tasks/import
+// The file is CSV or a JSON array of tasks as for POST /api/tasks, sent as
+// the body (Content-Type text/csv or application/json) or as the "file"
+// field of a multipart/form-data upload. Every row is validated first and
+// all valid rows are created in one transaction. When any row is invalid
+// nothing is created unless skip_invalid=true; dry_run=true only reports
+// what would happen.
+func (h *TaskHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	dryRun, err := parseBoolParam(q.Get("dry_run"))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid dry_run parameter", "dry_run must be true or false")
+		return
+	}
+	skipInvalid, err := parseBoolParam(q.Get("skip_invalid"))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid skip_invalid parameter", "skip_invalid must be true or false")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	data, format, err := readImportFile(r)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("Imports are limited to %d MB", maxImportBytes>>20))
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid upload", err.Error())
+		return
+	}
+
+	var rows []ImportRow
+	var reqs []*models.TaskRequest
+	var warnings []string
+	switch format {
+	case "csv":
+		rows, reqs, warnings, err = parseCSVImport(data)
+	case "json":
+		rows, reqs, err = parseJSONImport(data)
+	default:
+		err = errors.New("file must be CSV (text/csv, .csv) or JSON (application/json, .json)")
+	}
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid import file", err.Error())
+		return
+	}
+	if len(rows) == 0 || len(rows) > maxImportRows {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("file must contain between 1 and %d tasks", maxImportRows))
+		return
+	}
+
+	// Validate every row up front; reqs[i] is nil for rows that failed to parse
+	result := ImportResult{DryRun: dryRun, Rows: rows}
+	valid := make([]*models.TaskRequest, 0, len(reqs))
+	validIdx := make([]int, 0, len(reqs))
+	uuids := map[string]bool{}
+	for i, req := range reqs {
+		if req == nil {
+			continue
+		}
+		req.Normalize()
+		if err := req.Validate(); err != nil {
+			rows[i].Error = err.Error()
+			continue
+		}
+		if err := h.encryption.Check(req.Encryption); err != nil {
+			rows[i].Error = err.Error()
+			continue
+		}
+		conflict, err := h.uuidConflict(req, uuids)
+		if err != nil {
+			log.Printf("Error checking task UUID: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to import tasks", "")
+			return
+		}
+		if conflict != "" {
+			rows[i].Error = conflict
+			continue
+		}
+		valid = append(valid, req)
+		validIdx = append(validIdx, i)
+	}
+	result.Valid = len(valid)
+	result.Invalid = len(rows) - len(valid)
+
+	if dryRun {
+		for i, idx := range validIdx {
+			rows[idx].Preview = valid[i]
+		}
+		h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Import preview", Data: result, Warnings: warnings})
+		return
+	}
+	if len(valid) == 0 || (result.Invalid > 0 && !skipInvalid) {
+		h.sendJSONResponse(w, http.StatusBadRequest, SuccessResponse{Message: "Nothing was imported; fix the invalid rows or pass skip_invalid=true", Data: result, Warnings: warnings})
+		return
+	}
+
+	quota, err := h.checkTaskQuota(len(valid))
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to import tasks", "")
+		return
+	}
+	if quota.exceeded {
+		h.sendQuotaExceeded(w, quota)
+		return
+	}
+
+	tasks, err := h.repo.CreateBatch(valid)
+	if errors.Is(err, models.ErrUUIDTaken) {
+		h.sendErrorResponse(w, http.StatusConflict, "UUID already in use", err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error importing tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to import tasks", "")
+		return
+	}
+	for i := range tasks {
+		rows[validIdx[i]].Task = &tasks[i]
+	}
+	result.Imported = len(tasks)
+
+	warnings = append(warnings, quota.warnings...)
+	if result.Invalid > 0 {
+		h.sendJSONResponse(w, http.StatusMultiStatus, SuccessResponse{Message: "Some rows were skipped", Data: result, Warnings: warnings})
+		return
+	}
+	h.sendJSONResponse(w, http.StatusCreated, SuccessResponse{Message: "Tasks imported successfully", Data: result, Warnings: warnings})
+}
+
+// parseBoolParam parses an optional boolean query parameter
+func parseBoolParam(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// readImportFile reads the uploaded file and works out whether it is "csv"
+// or "json", from its media type or else its file name
+func readImportFile(r *http.Request) ([]byte, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		return data, importFormat(mediaType, ""), err
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return nil, "", errors.New(`the upload must have a "file" field`)
+		}
+		return nil, "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+	partType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	return data, importFormat(partType, header.Filename), nil
+}
+
+// importFormat maps a media type or file name to an import format
+func importFormat(mediaType, filename string) string {
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return "csv"
+	case "application/json":
+		return "json"
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	}
+	return ""
+}
+
+// parseJSONImport reads a JSON array of tasks. Items that don't decode, or
+// that set server-managed fields, are reported on their row.
+func parseJSONImport(data []byte) ([]ImportRow, []*models.TaskRequest, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, fmt.Errorf("JSON imports must be an array of tasks: %v", err)
+	}
+	rows := make([]ImportRow, len(items))
+	reqs := make([]*models.TaskRequest, len(items))
+	for i, item := range items {
+		rows[i].Row = i + 1
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(item, &fields); err != nil {
+			rows[i].Error = "each item must be a task object"
+			continue
+		}
+		if err := checkImmutableFields(fields); err != nil {
+			rows[i].Error = err.Error()
+			continue
+		}
+		var req models.TaskRequest
+		if err := json.Unmarshal(item, &req); err != nil {
+			rows[i].Error = err.Error()
+			continue
+		}
+		reqs[i] = &req
+	}
+	return rows, reqs, nil
+}
+
+// parseCSVImport reads a CSV file whose header row names the columns.
+// Dates are RFC 3339 timestamps or YYYY-MM-DD days; empty cells are left
+// unset.
+func parseCSVImport(data []byte) ([]ImportRow, []*models.TaskRequest, []string, error) {
+	// Spreadsheet exports often start with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	columns := make([]string, len(header))
+	hasTitle := false
+	var warnings []string
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		columns[i] = name
+		if name == "title" {
+			hasTitle = true
+		}
+		if !importColumns[name] {
+			warnings = append(warnings, fmt.Sprintf("column %q is not a task field and was ignored", header[i]))
+		}
+	}
+	if !hasTitle {
+		return nil, nil, nil, errors.New("the CSV header must include a title column")
+	}
+
+	var rows []ImportRow
+	var reqs []*models.TaskRequest
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// The reader can't resynchronize after a broken quote, so a
+			// malformed record fails the whole file
+			return nil, nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == maxImportRows {
+			// Let the caller report the limit
+			rows = append(rows, ImportRow{Row: line})
+			reqs = append(reqs, nil)
+			break
+		}
+
+		row := ImportRow{Row: line}
+		req, err := csvTaskRequest(columns, record)
+		if err != nil {
+			row.Error = err.Error()
+			req = nil
+		}
+		rows = append(rows, row)
+		reqs = append(reqs, req)
+	}
+	return rows, reqs, warnings, nil
+}
+
+// csvTaskRequest builds a task request from one CSV record
+func csvTaskRequest(columns []string, record []string) (*models.TaskRequest, error) {
+	req := &models.TaskRequest{}
+	for i, value := range record {
+		if i >= len(columns) || value == "" {
+			continue
+		}
+		switch columns[i] {
+		case "title":
+			req.Title = value
+		case "description":
+			req.Description = value
+		case "status":
+			req.Status = strings.ToLower(strings.TrimSpace(value))
+		case "color":
+			req.Color = strings.TrimSpace(value)
+		case "progress":
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, &models.ValidationError{Field: "progress", Message: "progress must be a whole number"}
+			}
+			req.Progress = &n
+		case "start_date", "due_date":
+			t, err := parseImportDate(strings.TrimSpace(value))
+			if err != nil {
+				return nil, &models.ValidationError{Field: columns[i], Message: columns[i] + " must be an RFC 3339 timestamp or a YYYY-MM-DD date"}
+			}
+			if columns[i] == "start_date" {
+				req.StartDate = &t
+			} else {
+				req.DueDate = &t
+			}
+		}
+	}
+	return req, nil
+}
+
+// parseImportDate parses an RFC 3339 timestamp or a YYYY-MM-DD day, which
+// is taken as midnight UTC
+func parseImportDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"to-do-api/models"
+)
+
+// JSONAPIMediaType is the media type of JSON:API documents (jsonapi.org).
+// Clients that list it in Accept get task resources in that format.
+const JSONAPIMediaType = "application/vnd.api+json"
+
+// jsonAPIIncludes are the relationships a client may ask to have included
+var jsonAPIIncludes = map[string]bool{"links": true, "notes": true}
+
+// jsonAPIDocument is a top-level JSON:API document
+type jsonAPIDocument struct {
+	Data     interface{}            `json:"data"`
+	Included []jsonAPIResource      `json:"included,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+	Links    map[string]string      `json:"links,omitempty"`
+}
+
+// jsonAPIIdentifier identifies a resource in a relationship
+type jsonAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// jsonAPIRelationship links a resource to related ones. Data is only set
+// when the related resources were included, and is then an identifier or a
+// (possibly empty) list of them.
+type jsonAPIRelationship struct {
+	Links map[string]string `json:"links,omitempty"`
+	Data  interface{}       `json:"data,omitempty"`
+}
+
+// jsonAPIResource is a resource object
+type jsonAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]json.RawMessage     `json:"attributes"`
+	Relationships map[string]jsonAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+// wantsJSONAPI reports whether the client asked for JSON:API. As the spec
+// requires, the media type only counts when it has no parameters.
+func wantsJSONAPI(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == JSONAPIMediaType && len(params) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isJSONAPIBody reports whether a request body is a JSON:API document
+func isJSONAPIBody(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == JSONAPIMediaType
+}
+
+// unwrapJSONAPIBody returns the attributes of a JSON:API document's primary
+// resource, which must be a task
+func unwrapJSONAPIBody(body []byte) ([]byte, error) {
+	var doc struct {
+		Data *struct {
+			Type       string          `json:"type"`
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Data == nil {
+		return nil, &models.ValidationError{Field: "data", Message: "data is required"}
+	}
+	if doc.Data.Type != "tasks" {
+		return nil, &models.ValidationError{Field: "data.type", Message: "data.type must be tasks"}
+	}
+	if len(doc.Data.Attributes) == 0 {
+		return []byte("{}"), nil
+	}
+	return doc.Data.Attributes, nil
+}
+
+// parseJSONAPIIncludes reads the include parameter
+func parseJSONAPIIncludes(r *http.Request) (map[string]bool, error) {
+	includes := map[string]bool{}
+	raw := r.URL.Query().Get("include")
+	if raw == "" {
+		return includes, nil
+	}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if !jsonAPIIncludes[name] {
+			return nil, fmt.Errorf("include must list links or notes, got %q", name)
+		}
+		includes[name] = true
+	}
+	return includes, nil
+}
+
+// jsonAPIAttributes encodes v and drops the members that the resource
+// object carries elsewhere
+func jsonAPIAttributes(v interface{}, drop ...string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, err
+	}
+	delete(attributes, "id")
+	for _, name := range drop {
+		delete(attributes, name)
+	}
+	return attributes, nil
+}
+
+// jsonAPITask builds the resource object for a task. Related links and
+// notes are fetched and appended to included when includes asks for them.
+func (h *TaskHandler) jsonAPITask(task *models.Task, includes map[string]bool, included *[]jsonAPIResource) (jsonAPIResource, error) {
+	id := strconv.Itoa(task.ID)
+	self := "/api/tasks/" + id
+	attributes, err := jsonAPIAttributes(task)
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+	resource := jsonAPIResource{
+		Type:       "tasks",
+		ID:         id,
+		Attributes: attributes,
+		Relationships: map[string]jsonAPIRelationship{
+			"links": {Links: map[string]string{"related": self + "/links"}},
+			"notes": {Links: map[string]string{"related": self + "/notes"}},
+		},
+		Links: map[string]string{"self": self},
+	}
+
+	if includes["links"] {
+		links, err := h.repo.ListLinks(task.ID)
+		if err != nil {
+			return jsonAPIResource{}, err
+		}
+		linkage := make([]jsonAPIIdentifier, len(links))
+		for i, link := range links {
+			linkage[i] = jsonAPIIdentifier{Type: "links", ID: strconv.Itoa(link.ID)}
+			related, err := jsonAPIRelated("links", link.ID, link, task.ID)
+			if err != nil {
+				return jsonAPIResource{}, err
+			}
+			*included = append(*included, related)
+		}
+		rel := resource.Relationships["links"]
+		rel.Data = linkage
+		resource.Relationships["links"] = rel
+	}
+	if includes["notes"] {
+		notes, err := h.repo.ListNotes(task.ID)
+		if err != nil {
+			return jsonAPIResource{}, err
+		}
+		linkage := make([]jsonAPIIdentifier, len(notes))
+		for i, note := range notes {
+			linkage[i] = jsonAPIIdentifier{Type: "notes", ID: strconv.Itoa(note.ID)}
+			related, err := jsonAPIRelated("notes", note.ID, note, task.ID)
+			if err != nil {
+				return jsonAPIResource{}, err
+			}
+			*included = append(*included, related)
+		}
+		rel := resource.Relationships["notes"]
+		rel.Data = linkage
+		resource.Relationships["notes"] = rel
+	}
+	return resource, nil
+}
+
+// jsonAPIRelated builds the resource object for a link or note of a task
+func jsonAPIRelated(typ string, id int, v interface{}, taskID int) (jsonAPIResource, error) {
+	attributes, err := jsonAPIAttributes(v, "task_id")
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+	return jsonAPIResource{
+		Type:       typ,
+		ID:         strconv.Itoa(id),
+		Attributes: attributes,
+		Relationships: map[string]jsonAPIRelationship{
+			"task": {Data: jsonAPIIdentifier{Type: "tasks", ID: strconv.Itoa(taskID)}},
+		},
+	}, nil
+}
+
+// jsonAPIDocumentFor converts a task response into a JSON:API document.
+// response.Data must be a *models.Task or a []models.Task; the message and
+// warnings move to meta, and pagination to meta and links.
+func (h *TaskHandler) jsonAPIDocumentFor(r *http.Request, response SuccessResponse, includes map[string]bool) (*jsonAPIDocument, error) {
+	var err error
+	doc := &jsonAPIDocument{
+		Meta:  map[string]interface{}{"message": response.Message},
+		Links: map[string]string{"self": r.URL.RequestURI()},
+	}
+	if len(response.Warnings) > 0 {
+		doc.Meta["warnings"] = response.Warnings
+	}
+	var included []jsonAPIResource
+	switch data := response.Data.(type) {
+	case *models.Task:
+		resource, err := h.jsonAPITask(data, includes, &included)
+		if err != nil {
+			return nil, err
+		}
+		doc.Data = resource
+	case []models.Task:
+		resources := make([]jsonAPIResource, len(data))
+		for i := range data {
+			if resources[i], err = h.jsonAPITask(&data[i], includes, &included); err != nil {
+				return nil, err
+			}
+		}
+		doc.Data = resources
+	default:
+		return nil, fmt.Errorf("no JSON:API representation for %T", response.Data)
+	}
+	doc.Included = included
+
+	if p := response.Pagination; p != nil {
+		doc.Meta["total"] = p.Total
+		doc.Meta["limit"] = p.Limit
+		doc.Meta["offset"] = p.Offset
+		if p.NextCursor != "" {
+			doc.Meta["next_cursor"] = p.NextCursor
+		}
+		if p.Next != "" {
+			doc.Links["next"] = p.Next
+		}
+		if p.Prev != "" {
+			doc.Links["prev"] = p.Prev
+		}
+	}
+	return doc, nil
+}
+
+// sendTaskResponse writes a response carrying a task or a list of tasks,
+// as a JSON:API document when the client asked for one. A list response
+// is tagged for If-None-Match.
+func (h *TaskHandler) sendTaskResponse(w http.ResponseWriter, r *http.Request, statusCode int, response SuccessResponse) {
+	_, list := response.Data.([]models.Task)
+	if !wantsJSONAPI(r) {
+		response.Data = withTaskLinks(response.Data)
+		if list {
+			response.Links = collectionLinks(r, response.Pagination)
+			h.sendCacheableJSON(w, r, statusCode, response)
+			return
+		}
+		h.sendJSONResponse(w, statusCode, response)
+		return
+	}
+
+	includes, err := parseJSONAPIIncludes(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid include parameter", err.Error())
+		return
+	}
+	doc, err := h.jsonAPIDocumentFor(r, response, includes)
+	if err != nil {
+		log.Printf("Error building JSON:API document: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to encode response", "")
+		return
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(doc); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to encode response", "")
+		return
+	}
+	if list && notModified(w, r, etagOf(body.Bytes())) {
+		return
+	}
+
+	w.Header().Set("Content-Type", JSONAPIMediaType)
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"to-do-api/jsonpatch"
+	"to-do-api/models"
+)
+
+// jsonPatchRemovals says what removing (or nulling) a writable task member
+// means: the value it is cleared to, or "" when it can't be removed
+var jsonPatchRemovals = map[string]string{
+	"title":       "",
+	"description": `""`,
+	"start_date":  "null",
+	"due_date":    "null",
+	"status":      "",
+	"progress":    "",
+	"archived":    "",
+	"color":       `""`,
+	"encryption":  "",
+	"location":    "null",
+}
+
+// isJSONPatchBody reports whether a request body is a JSON Patch document
+func isJSONPatchBody(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == jsonpatch.MediaType
+}
+
+// patchTaskWithJSONPatch handles PATCH /api/tasks/{id} with a JSON Patch
+// (RFC 6902) body. The operations apply to the task as GET returns it, and
+// the members they change are then saved as a partial update would save
+// them. The save only lands if the task is still the version the
+// operations saw, so test operations can guard against concurrent changes:
+// a failed test is a 409 and nothing changes. Without If-Match, a task
+// changed in between is patched again from its new version.
+func (h *TaskHandler) patchTaskWithJSONPatch(w http.ResponseWriter, r *http.Request, id int) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	ops, err := jsonpatch.Decode(body)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON Patch", err.Error())
+		return
+	}
+
+	cond, ok := h.ifMatchFor(w, r)
+	if !ok {
+		return
+	}
+
+	for {
+		task, err := h.repo.GetByID(id)
+		if err != nil {
+			log.Printf("Error fetching task: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", "")
+			return
+		}
+		if task == nil {
+			h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+			return
+		}
+		if check := cond.check(); check != nil && !check(task) {
+			h.sendPreconditionFailed(w, cond)
+			return
+		}
+
+		before, err := json.Marshal(task)
+		if err != nil {
+			log.Printf("Error encoding task: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update task", "")
+			return
+		}
+		after, err := ops.Apply(before)
+		if err != nil {
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				w.Header().Set("ETag", taskETag(task))
+				h.sendErrorResponse(w, http.StatusConflict, "Test failed", err.Error())
+				return
+			}
+			h.sendErrorResponse(w, http.StatusUnprocessableEntity, "Patch could not be applied", err.Error())
+			return
+		}
+
+		patch, err := taskPatchFromDocuments(before, after)
+		if err != nil {
+			h.sendDecodeError(w, err)
+			return
+		}
+		patch.Normalize()
+		if err := patch.Validate(); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+		if patch.Encryption != nil {
+			if err := h.encryption.Check(patch.Encryption); err != nil {
+				h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+				return
+			}
+		}
+
+		task, err = h.repo.Patch(id, patch, unchangedSince(task))
+		if errors.Is(err, models.ErrVersionMismatch) {
+			// Changed since it was read: check If-Match and apply the
+			// operations again against the new version
+			continue
+		}
+		if err != nil {
+			if verr, ok := err.(*models.ValidationError); ok {
+				h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+				return
+			}
+			log.Printf("Error patching task: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update task", "")
+			return
+		}
+		if task == nil {
+			h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+			return
+		}
+
+		w.Header().Set("ETag", taskETag(task))
+		h.sendTaskResponse(w, r, http.StatusOK, SuccessResponse{Message: "Task updated successfully", Data: task})
+		return
+	}
+}
+
+// taskPatchFromDocuments turns the difference between a task document and
+// its patched copy into a partial update. Changing a server-managed member
+// or adding an unknown one is a validation error.
+func taskPatchFromDocuments(before, after []byte) (*models.TaskPatch, error) {
+	var old, updated map[string]interface{}
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &updated); err != nil || updated == nil {
+		return nil, &models.ValidationError{Message: "the patched task must be a JSON object"}
+	}
+	var rawUpdated map[string]json.RawMessage
+	json.Unmarshal(after, &rawUpdated)
+
+	names := make([]string, 0, len(old)+len(updated))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range updated {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	// Report problems in a stable order
+	sort.Strings(names)
+
+	changes := make(map[string]json.RawMessage)
+	for _, name := range names {
+		value, present := updated[name]
+		_, had := old[name]
+		if had == present && reflect.DeepEqual(old[name], value) {
+			continue
+		}
+		cleared, writable := jsonPatchRemovals[name]
+		if !writable {
+			if had || isReadOnlyTaskMember(name) {
+				return nil, &models.ValidationError{Field: name, Message: name + " is read-only and cannot be set"}
+			}
+			return nil, &models.ValidationError{Field: name, Message: "unknown field " + name}
+		}
+		if present && value != nil {
+			changes[name] = rawUpdated[name]
+			continue
+		}
+		if cleared == "" {
+			return nil, &models.ValidationError{Field: name, Message: name + " cannot be removed or null"}
+		}
+		changes[name] = json.RawMessage(cleared)
+	}
+
+	data, _ := json.Marshal(changes)
+	var patch models.TaskPatch
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	return &patch, nil
+}
+
+// isReadOnlyTaskMember reports whether name is a task member the server
+// manages, including those omitted when empty
+func isReadOnlyTaskMember(name string) bool {
+	for _, field := range immutableFields {
+		if field == name {
+			return true
+		}
+	}
+	switch name {
+	case "position", "pinned", "summary", "description_html", "source", "external_id", "uuid":
+		return true
+	}
+	return false
+}
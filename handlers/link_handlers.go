@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"to-do-api/models"
+
+	"github.com/gorilla/mux"
+)
+
+// parseLinkIDs extracts the task and link IDs from the route, writing an
+// error response and reporting false when either is malformed
+func (h *TaskHandler) parseLinkIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return 0, 0, false
+	}
+	linkID, err := strconv.Atoi(vars["linkID"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid link ID", "Link ID must be a number")
+		return 0, 0, false
+	}
+	return taskID, linkID, true
+}
+
+// decodeLinkRequest reads, normalizes and validates a link payload
+func (h *TaskHandler) decodeLinkRequest(w http.ResponseWriter, r *http.Request) (*models.LinkRequest, bool) {
+	var linkReq models.LinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&linkReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return nil, false
+	}
+
+	linkReq.Normalize()
+	if err := linkReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return nil, false
+	}
+	return &linkReq, true
+}
+
+// ListTaskLinks handles GET /api/tasks/{id}/links
+func (h *TaskHandler) ListTaskLinks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	links, err := h.repo.ListLinks(taskID)
+	if err != nil {
+		log.Printf("Error fetching links: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch links", "")
+		return
+	}
+
+	if links == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Links retrieved successfully", links)
+}
+
+// CreateTaskLink handles POST /api/tasks/{id}/links
+func (h *TaskHandler) CreateTaskLink(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	linkReq, ok := h.decodeLinkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := h.repo.CreateLink(taskID, linkReq)
+	if err != nil {
+		log.Printf("Error creating link: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create link", "")
+		return
+	}
+
+	if link == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusCreated, "Link created successfully", link)
+}
+
+// UpdateTaskLink handles PUT /api/tasks/{id}/links/{linkID}
+func (h *TaskHandler) UpdateTaskLink(w http.ResponseWriter, r *http.Request) {
+	taskID, linkID, ok := h.parseLinkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	linkReq, ok := h.decodeLinkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := h.repo.UpdateLink(taskID, linkID, linkReq)
+	if err != nil {
+		log.Printf("Error updating link: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update link", "")
+		return
+	}
+
+	if link == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Link not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Link updated successfully", link)
+}
+
+// DeleteTaskLink handles DELETE /api/tasks/{id}/links/{linkID}
+func (h *TaskHandler) DeleteTaskLink(w http.ResponseWriter, r *http.Request) {
+	taskID, linkID, ok := h.parseLinkIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteLink(taskID, linkID); err != nil {
+		if err == sql.ErrNoRows {
+			h.sendErrorResponse(w, http.StatusNotFound, "Link not found", "")
+			return
+		}
+		log.Printf("Error deleting link: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete link", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Link deleted successfully", nil)
+}
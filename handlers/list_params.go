@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"to-do-api/models"
+)
+
+// listParams holds the parsed filtering, sorting and pagination options
+// shared by the task list endpoints
+type listParams struct {
+	filter    models.TaskFilter
+	limit     int
+	offset    int
+	sortBy    string
+	sortOrder string
+	// cursor selects keyset pagination over (created_at, id)
+	cursor bool
+	// format is the response format: json or ndjson
+	format string
+	// shape limits the task fields in the response and embeds related
+	// resources
+	shape taskShape
+}
+
+// paramError describes an invalid query parameter
+type paramError struct {
+	title   string
+	message string
+}
+
+// maxDueWithin bounds the due_within window
+const maxDueWithin = 366 * 24 * time.Hour
+
+// parseListParams reads status, start_after, start_before, startable_on,
+// due_after, due_before, due_within, created_after, created_before, progress_lt,
+// progress_gte, near, radius_km, q, filter, include_archived, limit, offset, cursor, sort_by, sort_order, format, fields and expand from the query string, resolving
+// "today" against now
+func parseListParams(q url.Values, now time.Time, defaultSortBy string, defaultSortOrder string) (listParams, *paramError) {
+	params := listParams{limit: 50, sortBy: defaultSortBy, sortOrder: defaultSortOrder, format: "json"}
+
+	if v := q.Get("format"); v != "" {
+		if v != "json" && v != "ndjson" {
+			return params, &paramError{"Invalid format", "Format must be one of: json, ndjson"}
+		}
+		params.format = v
+	}
+
+	shape, perr := parseTaskShape(q)
+	if perr != nil {
+		return params, perr
+	}
+	params.shape = shape
+
+	params.limit = parseLimit(q.Get("limit"), params.limit)
+	if v := q.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			params.offset = n
+		}
+	}
+	if v := q.Get("sort_by"); v != "" {
+		params.sortBy = v
+	}
+	if v := q.Get("sort_order"); v != "" {
+		params.sortOrder = v
+	}
+
+	statuses, perr := parseStatuses(q)
+	if perr != nil {
+		return params, perr
+	}
+	params.filter.Statuses = statuses
+
+	var err error
+	if params.filter.StartAfter, err = parseTimeParam(now, q.Get("start_after"), false); err != nil {
+		return params, &paramError{"Invalid start_after", err.Error()}
+	}
+	if params.filter.StartBefore, err = parseTimeParam(now, q.Get("start_before"), true); err != nil {
+		return params, &paramError{"Invalid start_before", err.Error()}
+	}
+	if params.filter.StartableBy, err = parseTimeParam(now, q.Get("startable_on"), true); err != nil {
+		return params, &paramError{"Invalid startable_on", err.Error()}
+	}
+	if params.filter.DueAfter, err = parseTimeParam(now, q.Get("due_after"), false); err != nil {
+		return params, &paramError{"Invalid due_after", err.Error()}
+	}
+	if params.filter.DueBefore, err = parseTimeParam(now, q.Get("due_before"), true); err != nil {
+		return params, &paramError{"Invalid due_before", err.Error()}
+	}
+	// due_within=48h selects open tasks due between now and now+48h
+	if v := q.Get("due_within"); v != "" {
+		if params.filter.DueAfter != nil || params.filter.DueBefore != nil {
+			return params, &paramError{"Invalid due_within", "due_within cannot be combined with due_after or due_before"}
+		}
+		d, ok := models.ParseDayDuration(v)
+		if !ok || d <= 0 || d > maxDueWithin {
+			return params, &paramError{"Invalid due_within", "due_within must be a positive duration such as 48h or 7d, at most 366d"}
+		}
+		from, until := now, now.Add(d)
+		params.filter.DueAfter, params.filter.DueBefore = &from, &until
+		params.filter.Open = true
+	}
+	if params.filter.CreatedAfter, err = parseTimeParam(now, q.Get("created_after"), false); err != nil {
+		return params, &paramError{"Invalid created_after", err.Error()}
+	}
+	if params.filter.CreatedBefore, err = parseTimeParam(now, q.Get("created_before"), true); err != nil {
+		return params, &paramError{"Invalid created_before", err.Error()}
+	}
+
+	if params.filter.ProgressBelow, err = parseProgressParam(q.Get("progress_lt")); err != nil {
+		return params, &paramError{"Invalid progress_lt", err.Error()}
+	}
+	if params.filter.ProgressAtLeast, err = parseProgressParam(q.Get("progress_gte")); err != nil {
+		return params, &paramError{"Invalid progress_gte", err.Error()}
+	}
+
+	params.filter.Text = strings.TrimSpace(q.Get("q"))
+	if v := strings.TrimSpace(q.Get("filter")); v != "" {
+		query, err := models.ParseQuery(v, now)
+		if err != nil {
+			return params, &paramError{"Invalid filter", err.Error()}
+		}
+		params.filter.Query = query
+	}
+
+	// near=lat,lng with radius_km (default 5) selects tasks located nearby
+	if v := q.Get("near"); v != "" {
+		near, err := parseNearParam(v, q.Get("radius_km"))
+		if err != nil {
+			return params, &paramError{"Invalid near", err.Error()}
+		}
+		params.filter.Near = near
+	}
+
+	// Archived tasks are hidden unless asked for, by include_archived or a
+	// filter on archived
+	includeArchived, _ := strconv.ParseBool(q.Get("include_archived"))
+	if !includeArchived && (params.filter.Query == nil || !params.filter.Query.Refers("archived")) {
+		unarchived := false
+		params.filter.Archived = &unarchived
+	}
+
+	// An empty cursor requests the first page in cursor mode
+	if q.Has("cursor") {
+		if v := q.Get("sort_by"); v != "" && v != "created_at" {
+			return params, &paramError{"Invalid cursor", "Cursor pagination only supports sort_by=created_at"}
+		}
+		params.cursor = true
+		params.sortBy = "created_at"
+		params.offset = 0
+		if v := q.Get("cursor"); v != "" {
+			after, err := decodeCursor(v, !strings.EqualFold(params.sortOrder, "asc"))
+			if err != nil {
+				return params, &paramError{"Invalid cursor", err.Error()}
+			}
+			params.filter.After = after
+		}
+	}
+
+	return params, nil
+}
+
+// defaultRadiusKm is the proximity search radius when radius_km is omitted
+const defaultRadiusKm = 5
+
+// parseNearParam parses "lat,lng" and an optional radius in kilometres
+func parseNearParam(near, radius string) (*models.GeoCircle, error) {
+	lat, lng, ok := strings.Cut(near, ",")
+	if !ok {
+		return nil, fmt.Errorf("near must be latitude,longitude such as 52.52,13.405")
+	}
+	circle := &models.GeoCircle{RadiusKm: defaultRadiusKm}
+	var err error
+	if circle.Latitude, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil {
+		return nil, fmt.Errorf("near must be latitude,longitude such as 52.52,13.405")
+	}
+	if circle.Longitude, err = strconv.ParseFloat(strings.TrimSpace(lng), 64); err != nil {
+		return nil, fmt.Errorf("near must be latitude,longitude such as 52.52,13.405")
+	}
+	if radius != "" {
+		if circle.RadiusKm, err = strconv.ParseFloat(radius, 64); err != nil {
+			return nil, fmt.Errorf("radius_km must be a number")
+		}
+	}
+	if err := circle.Validate(); err != nil {
+		return nil, err
+	}
+	return circle, nil
+}
+
+// parseLimit parses a page size, clamped to 1-100. Missing or malformed
+// values yield fallback.
+func parseLimit(v string, fallback int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	if n < 1 {
+		return 1
+	}
+	if n > 100 {
+		return 100
+	}
+	return n
+}
+
+// parseProgressParam parses an optional percentage between 0 and 100
+func parseProgressParam(v string) (*int, error) {
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > 100 {
+		return nil, fmt.Errorf("%q must be a number between 0 and 100", v)
+	}
+	return &n, nil
+}
+
+// parseTimeParam parses an RFC 3339 timestamp, a YYYY-MM-DD date or "today".
+// Bare dates resolve to the start of the day, or its last instant when endOfDay is set.
+func parseTimeParam(now time.Time, v string, endOfDay bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	var day time.Time
+	if v == "today" {
+		day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	} else if t, err := time.Parse("2006-01-02", v); err == nil {
+		day = t
+	} else {
+		return nil, fmt.Errorf("%q must be an RFC 3339 timestamp, a YYYY-MM-DD date or \"today\"", v)
+	}
+
+	if endOfDay {
+		day = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return &day, nil
+}
+
+// parseStatuses reads the status filter, which may be repeated and/or
+// comma-separated
+func parseStatuses(q url.Values) ([]string, *paramError) {
+	var statuses []string
+	for _, v := range q["status"] {
+		for _, status := range strings.Split(v, ",") {
+			status = strings.TrimSpace(status)
+			if status == "" {
+				continue
+			}
+			if !isValidStatus(status) {
+				return nil, &paramError{"Invalid status", "Status must be one of: pending, in_progress, completed"}
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
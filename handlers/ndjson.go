@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strconv"
+	"time"
+	"to-do-api/models"
+)
+
+// streamWriteTimeout bounds each write of a streamed response. The deadline
+// is pushed forward after every record, so a long stream to a reader that
+// keeps up is never cut off, while a client that stops reading is dropped
+// instead of holding its connection and goroutine until the server's write
+// timeout.
+const streamWriteTimeout = 10 * time.Second
+
+// streamingStats exposes streamed response counters under /debug/vars
+var streamingStats = expvar.NewMap("streaming")
+
+// sendNDJSON writes tasks as newline-delimited JSON, one task per line,
+// each rendered by shape unless it is nil. Pagination moves to headers
+// since there is no envelope to carry it.
+func sendNDJSON(w http.ResponseWriter, tasks []models.Task, shape func(*models.Task) (interface{}, error), pagination *Pagination) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Total-Count", strconv.Itoa(pagination.Total))
+	if pagination.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", pagination.NextCursor)
+	}
+	if pagination.Next != "" {
+		w.Header().Add("Link", "<"+pagination.Next+`>; rel="next"`)
+	}
+	if pagination.Prev != "" {
+		w.Header().Add("Link", "<"+pagination.Prev+`>; rel="prev"`)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	streamingStats.Add("ndjson_streams", 1)
+	start := time.Now()
+	defer func() {
+		streamingStats.Add("ndjson_duration_ms", time.Since(start).Milliseconds())
+	}()
+
+	// Writers that cannot reach the connection fall back to the server's
+	// overall write timeout
+	rc := http.NewResponseController(w)
+
+	// Encode appends the newline that terminates each record
+	enc := json.NewEncoder(w)
+	for i := range tasks {
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		var record interface{} = withTaskLinks(&tasks[i])
+		if shape != nil {
+			var err error
+			if record, err = shape(&tasks[i]); err != nil {
+				streamingStats.Add("ndjson_aborted", 1)
+				return
+			}
+		}
+		if err := enc.Encode(record); err != nil {
+			streamingStats.Add("ndjson_aborted", 1)
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"to-do-api/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ListTaskNotes handles GET /api/tasks/{id}/notes
+func (h *TaskHandler) ListTaskNotes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	notes, err := h.repo.ListNotes(taskID)
+	if err != nil {
+		log.Printf("Error fetching notes: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch notes", "")
+		return
+	}
+
+	if notes == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Notes retrieved successfully", notes)
+}
+
+// AddTaskNote handles POST /api/tasks/{id}/notes
+// Notes are append-only; they cannot be edited or deleted individually.
+func (h *TaskHandler) AddTaskNote(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	var noteReq models.NoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&noteReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	noteReq.Normalize()
+	if err := noteReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	note, err := h.repo.AddNote(taskID, &noteReq)
+	if err != nil {
+		log.Printf("Error adding note: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to add note", "")
+		return
+	}
+
+	if note == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusCreated, "Note added successfully", note)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+	"to-do-api/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// templates holds the server-rendered HTML views
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// plannerMaxTasks caps how many tasks a printed day sheet lists
+const plannerMaxTasks = 200
+
+// plannerItem is a task as shown on the day sheet
+type plannerItem struct {
+	Title   string
+	Summary string
+	Due     string
+}
+
+// plannerPage is the data behind templates/planner.html
+type plannerPage struct {
+	Date      string
+	Overdue   []plannerItem
+	Timed     []plannerItem
+	AllDay    []plannerItem
+	NoteLines []struct{}
+}
+
+// GetPlannerToday handles GET /api/planner/today
+// It renders a printable sheet of today's open tasks: overdue ones, tasks due
+// at a set time and all-day tasks (due at midnight), plus space for notes.
+func (h *TaskHandler) GetPlannerToday(w http.ResponseWriter, r *http.Request) {
+	loc, err := requestLocation(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tz", "tz must be an IANA time zone name such as Europe/Berlin")
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "html" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid format", "Format must be: html")
+		return
+	}
+
+	now := h.clock.Now().In(loc)
+	today := startOfDay(now)
+	endOfToday := today.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	unarchived := false
+	filter := models.TaskFilter{DueBefore: &endOfToday, Open: true, Archived: &unarchived}
+
+	tasks, err := h.repo.GetAllPaginated(filter, plannerMaxTasks, 0, "due_date", "asc")
+	if err != nil {
+		log.Printf("Error fetching tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to build planner", "")
+		return
+	}
+
+	page := plannerPage{Date: today.Format("Monday, 2 January 2006"), NoteLines: make([]struct{}, 8)}
+	for _, task := range tasks {
+		due := task.DueDate.In(loc)
+		item := plannerItem{Title: task.Title, Summary: models.TruncateGraphemes(task.Description, models.SummaryLength)}
+		if task.Encryption != nil {
+			// Ciphertext is meaningless on paper
+			item = plannerItem{Title: "Encrypted task"}
+		}
+		switch {
+		case due.Before(today):
+			item.Due = due.Format("Jan 2")
+			page.Overdue = append(page.Overdue, item)
+		case due.Equal(startOfDay(due)):
+			page.AllDay = append(page.AllDay, item)
+		default:
+			item.Due = due.Format("15:04")
+			page.Timed = append(page.Timed, item)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := templates.ExecuteTemplate(w, "planner.html", page); err != nil {
+		log.Printf("Error rendering planner: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"to-do-api/events"
+	"to-do-api/models"
+)
+
+// quotaWarningRatio is the share of a quota at which responses start to warn
+const quotaWarningRatio = 0.9
+
+// SetTaskQuota caps the number of tasks in the workspace; 0 means no limit.
+// Creates that would go over it are refused, and from 90% on responses carry
+// a warning so clients see the limit coming.
+func (h *TaskHandler) SetTaskQuota(quota int) {
+	h.taskQuota = quota
+}
+
+// SetEventBus sets the bus notifications such as quota warnings are
+// published on
+func (h *TaskHandler) SetEventBus(bus *events.Bus) {
+	h.events = bus
+}
+
+// quotaCheck is the outcome of checking the task quota before a write
+type quotaCheck struct {
+	quota    int
+	used     int
+	exceeded bool
+	warnings []string
+}
+
+// checkTaskQuota reports whether adding more tasks fits the task quota and
+// the warnings to attach to the response. Crossing the warning threshold
+// also publishes a quota.warning event.
+func (h *TaskHandler) checkTaskQuota(adding int) (quotaCheck, error) {
+	check := quotaCheck{quota: h.taskQuota}
+	if h.taskQuota <= 0 {
+		return check, nil
+	}
+
+	used, err := h.repo.Count(models.TaskFilter{})
+	if err != nil {
+		return check, err
+	}
+	check.used = used
+	after := used + adding
+	if after > h.taskQuota {
+		check.exceeded = true
+		return check, nil
+	}
+
+	threshold := quotaWarningRatio * float64(h.taskQuota)
+	if float64(after) >= threshold {
+		message := fmt.Sprintf("%d of %d tasks used (%d%% of the task quota)", after, h.taskQuota, after*100/h.taskQuota)
+		check.warnings = append(check.warnings, message)
+		if float64(used) < threshold && h.events != nil {
+			h.events.Publish(events.Event{Type: events.QuotaWarning, Message: message})
+		}
+	}
+	return check, nil
+}
+
+// sendQuotaExceeded refuses a write that would go over the task quota
+func (h *TaskHandler) sendQuotaExceeded(w http.ResponseWriter, check quotaCheck) {
+	h.sendErrorResponse(w, http.StatusForbidden, "Task quota exceeded", fmt.Sprintf("This workspace is limited to %d tasks and has %d; delete tasks to make room", check.quota, check.used))
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"to-do-api/models"
+	"to-do-api/recurring"
+
+	"github.com/gorilla/mux"
+)
+
+// SetRecurringGenerator sets the generator woken when a schedule is created,
+// so its first occurrences appear without waiting for the next pass. Its
+// passes wait for a running batch like any client.
+func (h *TaskHandler) SetRecurringGenerator(gen *recurring.Generator) {
+	gen.SetLock(h.batch.mu.RLocker())
+	h.recurring = gen
+}
+
+// ListSchedules handles GET /api/schedules
+func (h *TaskHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.repo.ListSchedules()
+	if err != nil {
+		log.Printf("Error fetching schedules: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch schedules", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Schedules retrieved successfully", schedules)
+}
+
+// CreateSchedule handles POST /api/schedules
+func (h *TaskHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var scheduleReq models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&scheduleReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	scheduleReq.Normalize()
+	if err := scheduleReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	schedule, err := h.repo.CreateSchedule(&scheduleReq)
+	if err != nil {
+		log.Printf("Error creating schedule: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create schedule", "")
+		return
+	}
+	if h.recurring != nil {
+		h.recurring.Wake()
+	}
+
+	h.sendSuccessResponse(w, http.StatusCreated, "Schedule created successfully", schedule)
+}
+
+// GetSchedule handles GET /api/schedules/{id}
+func (h *TaskHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid schedule ID", "Schedule ID must be a number")
+		return
+	}
+
+	schedule, err := h.repo.GetSchedule(id)
+	if err != nil {
+		log.Printf("Error fetching schedule: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch schedule", "")
+		return
+	}
+	if schedule == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Schedule not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Schedule retrieved successfully", schedule)
+}
+
+// DeleteSchedule handles DELETE /api/schedules/{id}
+// Tasks already generated from the schedule are kept.
+func (h *TaskHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid schedule ID", "Schedule ID must be a number")
+		return
+	}
+
+	if err := h.repo.DeleteSchedule(id); err != nil {
+		if err == sql.ErrNoRows {
+			h.sendErrorResponse(w, http.StatusNotFound, "Schedule not found", "")
+			return
+		}
+		log.Printf("Error deleting schedule: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete schedule", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Schedule deleted successfully", nil)
+}
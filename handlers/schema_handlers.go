@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"to-do-api/schemas"
+
+	"github.com/gorilla/mux"
+)
+
+// ListSchemas handles GET /api/schemas
+func (h *TaskHandler) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	index := make(map[string]string)
+	for _, name := range schemas.Names() {
+		index[name] = "/api/schemas/" + name
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Schemas retrieved successfully", index)
+}
+
+// GetSchema handles GET /api/schemas/{name}
+func (h *TaskHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	data, ok := schemas.Get(mux.Vars(r)["name"])
+	if !ok {
+		h.sendErrorResponse(w, http.StatusNotFound, "Schema not found", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
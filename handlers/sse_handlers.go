@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"to-do-api/events"
+)
+
+// Event stream settings
+const (
+	// sseBuffer is the number of events queued for a client before the
+	// oldest are dropped
+	sseBuffer = 256
+	// sseHeartbeat keeps idle streams open through proxies that close
+	// silent connections
+	sseHeartbeat = 15 * time.Second
+	// sseRetry is the reconnection delay suggested to clients, in ms
+	sseRetry = 3000
+)
+
+// SetMaxEventStreams caps concurrent GET /api/events clients; 0 means no
+// limit
+func (h *TaskHandler) SetMaxEventStreams(max int) {
+	h.streams = newStreamClients(max)
+}
+
+// TaskEventsStream handles GET /api/events
+// It streams task events as Server-Sent Events, each with the bus sequence
+// number as its id and the event type as its name, optionally limited to
+// tasks with the given status. A client reconnecting with Last-Event-ID
+// (or ?last_event_id=) first gets the events it missed; when they are no
+// longer available it gets a reset event and should reload instead.
+func (h *TaskHandler) TaskEventsStream(w http.ResponseWriter, r *http.Request) {
+	filter, perr := parseEventFilter(r.URL.Query())
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+	if h.events == nil {
+		h.sendErrorResponse(w, http.StatusServiceUnavailable, "Event stream unavailable", "This server does not publish task events")
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var resumeFrom uint64
+	if lastID != "" {
+		seq, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid Last-Event-ID", "Last-Event-ID must be the id of a previous event")
+			return
+		}
+		resumeFrom = seq
+	}
+
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+	client, ok := h.streams.add(cancel, time.Now())
+	if !ok {
+		streamingStats.Add("sse_rejected", 1)
+		w.Header().Set("Retry-After", strconv.Itoa(int(streamIdleEviction.Seconds())))
+		h.sendErrorResponse(w, http.StatusServiceUnavailable, "Too many event streams", "The maximum number of event stream clients is connected; retry later")
+		return
+	}
+	defer h.streams.remove(client)
+
+	opts := events.Options{Name: "sse", Buffer: sseBuffer, Policy: events.DropOldest}
+	var sub *events.Subscription
+	var missed []events.Event
+	complete := true
+	if lastID != "" {
+		sub, missed, complete = h.events.SubscribeFrom(opts, resumeFrom)
+	} else {
+		sub = h.events.Subscribe(opts)
+	}
+	defer sub.Close()
+
+	// The stream outlives the server's read timeout; writes get their own
+	// deadline below
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	streamingStats.Add("sse_streams", 1)
+	streamingStats.Add("sse_clients", 1)
+	defer streamingStats.Add("sse_clients", -1)
+
+	// write sends one frame and flushes it; false means the client is gone
+	// or stopped reading
+	write := func(frame string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := io.WriteString(w, frame); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	send := func(e events.Event) bool {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return true
+		}
+		client.lastEvent.Store(time.Now().UnixNano())
+		return write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data))
+	}
+
+	if !write(fmt.Sprintf("retry: %d\n\n", sseRetry)) {
+		streamingStats.Add("sse_aborted", 1)
+		return
+	}
+	if !complete && !write("event: reset\ndata: {}\n\n") {
+		streamingStats.Add("sse_aborted", 1)
+		return
+	}
+	for _, e := range missed {
+		if filter.matches(e) && !send(e) {
+			streamingStats.Add("sse_aborted", 1)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if context.Cause(ctx) == errStreamEvicted {
+				write("event: evicted\ndata: {}\n\n")
+			}
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if !filter.matches(e) {
+				continue
+			}
+			// Fan-out latency is the time from publishing to writing
+			streamingStats.Add("sse_fanout_events", 1)
+			streamingStats.Add("sse_fanout_ms", time.Since(e.OccurredAt).Milliseconds())
+			if !send(e) {
+				streamingStats.Add("sse_aborted", 1)
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				streamingStats.Add("sse_aborted", 1)
+				return
+			}
+		}
+	}
+}
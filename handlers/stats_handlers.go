@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"to-do-api/models"
+)
+
+// GetStats handles GET /api/stats
+// It reports counts by status, overdue and archived tasks, and how many
+// tasks were created and completed in the last 7 and 30 days.
+func (h *TaskHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	// Truncate so concurrent requests share one coalesced query
+	now := h.clock.Now().Truncate(time.Second)
+
+	stats, err := h.repo.Stats(now)
+	if err != nil {
+		log.Printf("Error computing stats: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to compute stats", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Stats retrieved successfully", stats)
+}
+
+// maxCompletionPeriods bounds the length of a completion series
+const maxCompletionPeriods = 366
+
+// GetCompletionStats handles GET /api/stats/completions
+// It returns the number of tasks completed per day or week (granularity,
+// default day) for the last periods days or weeks, ending with the current
+// one. Periods default to 30 days or 12 weeks and start at local midnight
+// (weeks on Monday) in the tz time zone.
+func (h *TaskHandler) GetCompletionStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	granularity := query.Get("granularity")
+	if granularity == "" {
+		granularity = "day"
+	}
+	if granularity != "day" && granularity != "week" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid granularity", "granularity must be day or week")
+		return
+	}
+
+	periods := 30
+	if granularity == "week" {
+		periods = 12
+	}
+	if raw := query.Get("periods"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxCompletionPeriods {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid periods", "periods must be a number between 1 and 366")
+			return
+		}
+		periods = n
+	}
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tz", "tz must be an IANA time zone name such as Europe/Berlin")
+		return
+	}
+
+	// Step back from the start of the current period to the first one
+	today := startOfDay(h.clock.Now().In(loc))
+	days := 1
+	current := today
+	if granularity == "week" {
+		// time.Weekday counts from Sunday; weeks start on Monday
+		days = 7
+		current = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	}
+	start := current.AddDate(0, 0, -days*(periods-1))
+	end := current.AddDate(0, 0, days)
+
+	times, err := h.repo.CompletionTimes(start, end)
+	if err != nil {
+		log.Printf("Error computing completion stats: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to compute stats", "")
+		return
+	}
+
+	series := models.BuildCompletionSeries(times, start, periods, granularity)
+	h.sendSuccessResponse(w, http.StatusOK, "Completion stats retrieved successfully", series)
+}
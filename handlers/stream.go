@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+	"to-do-api/events"
+	"to-do-api/models"
+)
+
+// eventFilter selects the events a streaming client receives
+type eventFilter struct {
+	statuses []string
+}
+
+// parseEventFilter reads the status filter of a streaming endpoint
+func parseEventFilter(q url.Values) (eventFilter, *paramError) {
+	statuses, perr := parseStatuses(q)
+	if perr != nil {
+		return eventFilter{}, perr
+	}
+	return eventFilter{statuses: statuses}, nil
+}
+
+// matches reports whether e passes the filter. Events that carry no task,
+// such as deletes and batch changes, always pass: the client cannot tell
+// whether they concern a task it follows.
+func (f eventFilter) matches(e events.Event) bool {
+	if e.Task == nil {
+		return true
+	}
+	return models.TaskFilter{Statuses: f.statuses}.Matches(*e.Task)
+}
+
+// Event stream client limits
+const (
+	// DefaultMaxEventStreams caps concurrent GET /api/events clients
+	DefaultMaxEventStreams = 1000
+	// streamIdleEviction is how long a client must have gone without an
+	// event before a new client may take its place when the cap is reached
+	streamIdleEviction = 5 * time.Minute
+)
+
+// errStreamEvicted is the cancel cause of a client evicted to make room
+var errStreamEvicted = errors.New("evicted for a new client")
+
+// streamClients tracks connected event stream clients against a cap
+type streamClients struct {
+	mu      sync.Mutex
+	max     int
+	clients map[*streamClient]struct{}
+}
+
+// streamClient is one connected event stream
+type streamClient struct {
+	cancel context.CancelCauseFunc
+	// lastEvent is when the client was last sent an event, in Unix
+	// nanoseconds; heartbeats do not count
+	lastEvent atomic.Int64
+}
+
+func newStreamClients(max int) *streamClients {
+	return &streamClients{max: max, clients: make(map[*streamClient]struct{})}
+}
+
+// add registers a client that is stopped by cancel. At the cap it evicts
+// the client idle longest, if that one has been idle for at least
+// streamIdleEviction, and otherwise refuses the new client.
+func (c *streamClients) add(cancel context.CancelCauseFunc, now time.Time) (*streamClient, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.max > 0 && len(c.clients) >= c.max {
+		var idlest *streamClient
+		for cl := range c.clients {
+			if idlest == nil || cl.lastEvent.Load() < idlest.lastEvent.Load() {
+				idlest = cl
+			}
+		}
+		if now.Sub(time.Unix(0, idlest.lastEvent.Load())) < streamIdleEviction {
+			return nil, false
+		}
+		delete(c.clients, idlest)
+		idlest.cancel(errStreamEvicted)
+		streamingStats.Add("sse_evicted", 1)
+	}
+
+	cl := &streamClient{cancel: cancel}
+	cl.lastEvent.Store(now.UnixNano())
+	c.clients[cl] = struct{}{}
+	return cl, true
+}
+
+// remove unregisters a client; removing an evicted client is a no-op
+func (c *streamClients) remove(cl *streamClient) {
+	c.mu.Lock()
+	delete(c.clients, cl)
+	c.mu.Unlock()
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"to-do-api/models"
+)
+
+// Sync limits
+const (
+	maxSyncChanges = 500
+	maxSyncBody    = 10 << 20
+)
+
+// Outcomes of a pushed change
+const (
+	syncApplied  = "applied"
+	syncConflict = "conflict"
+	syncInvalid  = "invalid"
+)
+
+// SyncChange is a change a client made while offline. Task is the new
+// task for created, the fields to change (as for PATCH) for updated, and
+// absent for deleted. Version is the version of the task the change was
+// made to, the seq of its latest change the client pulled.
+type SyncChange struct {
+	Type     string          `json:"type"`
+	ClientID string          `json:"client_id,omitempty"`
+	TaskID   int             `json:"task_id,omitempty"`
+	Version  int64           `json:"version,omitempty"`
+	Task     json.RawMessage `json:"task,omitempty"`
+}
+
+// SyncRequest is the body of POST /api/sync: the changes to push, and the
+// cursor to pull from
+type SyncRequest struct {
+	Cursor  string       `json:"cursor"`
+	Changes []SyncChange `json:"changes"`
+}
+
+// SyncChangeResult reports a pushed change. Version is the task's version
+// after the change, or for a conflict its current version, which a client
+// pushes again with to overwrite the server's copy. Task is the server's
+// copy in a conflict, absent when the task was deleted there.
+type SyncChangeResult struct {
+	Index    int         `json:"index"`
+	Status   string      `json:"status"`
+	ClientID string      `json:"client_id,omitempty"`
+	TaskID   int         `json:"task_id,omitempty"`
+	Version  int64       `json:"version,omitempty"`
+	Task     interface{} `json:"task,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// SyncResult is the response to a sync: the outcome of every pushed
+// change, then the changes since the cursor
+type SyncResult struct {
+	Pushed []SyncChangeResult `json:"pushed"`
+	ChangesPage
+}
+
+// Sync handles POST /api/sync
+// Offline clients push the changes they made and pull everyone else's in
+// one round trip. A change to an existing task carries the version it was
+// made to; if the task has changed since, it is not applied and the
+// server's copy is reported as a conflict for the client to resolve.
+// Changes apply in order, each on its own; conflicts and invalid changes
+// don't stop the rest. The pull that follows includes the changes just
+// pushed, under their new versions.
+func (h *TaskHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	var req SyncRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSyncBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body too large", fmt.Sprintf("Syncs are limited to %d MB", maxSyncBody>>20))
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON payload", "The body must be an object with an optional cursor and changes")
+		return
+	}
+	if len(req.Changes) > maxSyncChanges {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("request must contain at most %d changes", maxSyncChanges))
+		return
+	}
+	since, ok := parseCursor(req.Cursor)
+	if !ok {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid cursor", "cursor must be a cursor returned as next_cursor")
+		return
+	}
+	for i, change := range req.Changes {
+		if err := change.check(); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("changes[%d]: %v", i, err))
+			return
+		}
+	}
+
+	creates := 0
+	for _, change := range req.Changes {
+		if change.Type == models.ChangeCreated {
+			creates++
+		}
+	}
+	quota, err := h.checkTaskQuota(creates)
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to sync", "")
+		return
+	}
+	if quota.exceeded {
+		h.sendQuotaExceeded(w, quota)
+		return
+	}
+
+	pushed := make([]SyncChangeResult, len(req.Changes))
+	for i, change := range req.Changes {
+		if pushed[i], err = h.applySyncChange(change); err != nil {
+			break
+		}
+		pushed[i].Index = i
+	}
+	if err != nil {
+		log.Printf("Error applying sync change: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to sync", "")
+		return
+	}
+
+	page, err := h.changesPage(since, parseLimit(r.URL.Query().Get("limit"), 100))
+	if err != nil {
+		log.Printf("Error listing changes: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to sync", "")
+		return
+	}
+
+	message := "Sync completed successfully"
+	for _, res := range pushed {
+		if res.Status != syncApplied {
+			message = "Sync completed with conflicts or invalid changes"
+			break
+		}
+	}
+	h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: message, Data: SyncResult{Pushed: pushed, ChangesPage: page}, Warnings: quota.warnings})
+}
+
+// check validates the shape of a change, before anything is applied
+func (c *SyncChange) check() error {
+	switch c.Type {
+	case models.ChangeCreated:
+		if c.TaskID != 0 || c.Version != 0 {
+			return errors.New("a created task has no task_id or version yet")
+		}
+		if len(c.Task) == 0 {
+			return errors.New("task is required")
+		}
+	case models.ChangeUpdated, models.ChangeDeleted:
+		if c.TaskID <= 0 {
+			return errors.New("task_id is required")
+		}
+		if c.Version <= 0 {
+			return errors.New("version is required")
+		}
+		if c.Type == models.ChangeUpdated && len(c.Task) == 0 {
+			return errors.New("task is required")
+		}
+		if c.Type == models.ChangeDeleted && len(c.Task) > 0 {
+			return errors.New("a deleted task has no task")
+		}
+	default:
+		return errors.New("type must be created, updated or deleted")
+	}
+	return nil
+}
+
+// applySyncChange applies one pushed change. Invalid changes and conflicts
+// are reported in the result; only storage failures return an error.
+func (h *TaskHandler) applySyncChange(change SyncChange) (SyncChangeResult, error) {
+	res := SyncChangeResult{ClientID: change.ClientID, TaskID: change.TaskID}
+	invalid := func(err error) (SyncChangeResult, error) {
+		res.Status, res.Error = syncInvalid, err.Error()
+		return res, nil
+	}
+
+	if change.Type == models.ChangeCreated {
+		var taskReq models.TaskRequest
+		if err := decodeSyncTask(change.Task, &taskReq); err != nil {
+			return invalid(err)
+		}
+		taskReq.Normalize()
+		if err := taskReq.Validate(); err != nil {
+			return invalid(err)
+		}
+		if err := h.encryption.Check(taskReq.Encryption); err != nil {
+			return invalid(err)
+		}
+		// A create pushed again after a lost response finds its task by UUID
+		if taskReq.UUID != "" {
+			existing, err := h.taskByUUID(taskReq.UUID)
+			if err != nil {
+				return res, err
+			}
+			if existing != nil {
+				res.TaskID = existing.ID
+				return h.syncApplied(res)
+			}
+		}
+		task, err := h.repo.Create(&taskReq)
+		if err != nil {
+			return res, err
+		}
+		res.TaskID = task.ID
+		return h.syncApplied(res)
+	}
+
+	var patch models.TaskPatch
+	if change.Type == models.ChangeUpdated {
+		if err := decodeSyncTask(change.Task, &patch); err != nil {
+			return invalid(err)
+		}
+		patch.Normalize()
+		if err := patch.Validate(); err != nil {
+			return invalid(err)
+		}
+		if patch.Encryption != nil {
+			if err := h.encryption.Check(patch.Encryption); err != nil {
+				return invalid(err)
+			}
+		}
+	}
+
+	// Read before the version so the task is the version compared; the
+	// write then only lands on that version (models.VersionCheck)
+	task, err := h.repo.GetByID(change.TaskID)
+	if err != nil {
+		return res, err
+	}
+	current, err := h.repo.GetChange(change.TaskID)
+	if err != nil {
+		return res, err
+	}
+	if current == nil {
+		return invalid(errors.New("task not found"))
+	}
+	if current.Type == models.ChangeDeleted || task == nil {
+		// Deleting it again changes nothing
+		if change.Type == models.ChangeDeleted {
+			res.Status, res.Version = syncApplied, current.Seq
+			return res, nil
+		}
+		res.Status, res.Version = syncConflict, current.Seq
+		return res, nil
+	}
+	if current.Seq != change.Version {
+		return h.syncConflict(res)
+	}
+
+	if change.Type == models.ChangeDeleted {
+		recordUndo, err := h.prepareUndo("delete", []int{change.TaskID}, models.TaskFilter{}, true)
+		if err != nil {
+			return res, err
+		}
+		if err := h.repo.Delete(change.TaskID, unchangedSince(task)); err != nil {
+			if errors.Is(err, models.ErrVersionMismatch) || err == sql.ErrNoRows {
+				return h.syncConflict(res)
+			}
+			return res, err
+		}
+		recordUndo()
+		return h.syncApplied(res)
+	}
+
+	task, err = h.repo.Patch(change.TaskID, &patch, unchangedSince(task))
+	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			return h.syncConflict(res)
+		}
+		if verr, ok := err.(*models.ValidationError); ok {
+			return invalid(verr)
+		}
+		return res, err
+	}
+	if task == nil {
+		return h.syncConflict(res)
+	}
+	return h.syncApplied(res)
+}
+
+// syncConflict reports a change as conflicting, with the task's current
+// version and state
+func (h *TaskHandler) syncConflict(res SyncChangeResult) (SyncChangeResult, error) {
+	current, err := h.repo.GetChange(res.TaskID)
+	if err != nil {
+		return res, err
+	}
+	res.Status = syncConflict
+	if current == nil {
+		return res, nil
+	}
+	res.Version = current.Seq
+	if current.Type != models.ChangeDeleted {
+		task, err := h.repo.GetByID(res.TaskID)
+		if err != nil {
+			return res, err
+		}
+		res.Task = withTaskLinks(task)
+	}
+	return res, nil
+}
+
+// syncApplied reports a change as applied, with the task's new version
+func (h *TaskHandler) syncApplied(res SyncChangeResult) (SyncChangeResult, error) {
+	current, err := h.repo.GetChange(res.TaskID)
+	if err != nil {
+		return res, err
+	}
+	res.Status = syncApplied
+	if current != nil {
+		res.Version = current.Seq
+	}
+	return res, nil
+}
+
+// decodeSyncTask decodes the task of a pushed change, rejecting any attempt
+// to set an immutable field
+func decodeSyncTask(data json.RawMessage, v interface{}) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return errors.New("task must be an object")
+	}
+	if err := checkImmutableFields(fields); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -3,22 +3,52 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"to-do-api/events"
+	"to-do-api/markdown"
 	"to-do-api/models"
+	"to-do-api/recurring"
+	"to-do-api/webhooks"
 
 	"github.com/gorilla/mux"
 )
 
 // TaskHandler handles HTTP requests for tasks
 type TaskHandler struct {
-	repo models.TaskRepository
+	repo        models.TaskRepository
+	clock       models.Clock
+	webhookKeys *webhooks.KeySet
+	encryption  models.EncryptionMode
+	taskQuota   int
+	events      *events.Bus
+	undo        *undoLog
+	recurring   *recurring.Generator
+	streams     *streamClients
+	webhooks    *webhooks.Dispatcher
+	ifMatch     ifMatchGuard
+	idempotency *idempotencyStore
+	batch       batchGuard
+	// batchRouter serves the operations of a batch
+	batchRouter http.Handler
+	// sqliteSnapshot copies the live SQLite database for backups
+	sqliteSnapshot func(destPath string) error
 }
 
 // NewTaskHandler creates a new task handler
 func NewTaskHandler(repo models.TaskRepository) *TaskHandler {
-	return &TaskHandler{repo: repo}
+	return &TaskHandler{repo: repo, clock: models.SystemClock, encryption: models.EncryptionOff, undo: &undoLog{}, streams: newStreamClients(DefaultMaxEventStreams), idempotency: newIdempotencyStore(DefaultIdempotencyTTL)}
+}
+
+// SetClock replaces the clock used to resolve relative dates such as
+// "today" and the overdue cutoff
+func (h *TaskHandler) SetClock(clock models.Clock) {
+	h.clock = clock
 }
 
 // ErrorResponse represents an error response
@@ -29,89 +59,432 @@ type ErrorResponse struct {
 
 // SuccessResponse represents a success response
 type SuccessResponse struct {
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	// Links navigate from a list response to the neighbouring pages
+	Links map[string]HALLink `json:"_links,omitempty"`
+	// Warnings flag conditions the client should act on before they cause
+	// failures, such as a nearly full quota
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Pagination describes where a list page sits in the full result set.
+// Next and Prev are relative URLs and are omitted at either end. NextCursor
+// is set when the list is ordered by created_at and more tasks follow.
+type Pagination struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // CreateTask handles POST /api/tasks
 func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	var taskReq models.TaskRequest
 	
-	if err := json.NewDecoder(r.Body).Decode(&taskReq); err != nil {
-		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+	if err := decodeTaskBody(r, &taskReq); err != nil {
+		h.sendDecodeError(w, err)
 		return
 	}
 	
+	taskReq.Normalize()
 	if err := taskReq.Validate(); err != nil {
 		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
 		return
 	}
 	
+	if err := h.encryption.Check(taskReq.Encryption); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+	
+	quota, err := h.checkTaskQuota(1)
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create task", "")
+		return
+	}
+	if quota.exceeded {
+		h.sendQuotaExceeded(w, quota)
+		return
+	}
+	
 	task, err := h.repo.Create(&taskReq)
+	if errors.Is(err, models.ErrUUIDTaken) {
+		h.sendUUIDTaken(w, taskReq.UUID)
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating task: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create task", "")
 		return
 	}
 	
-	h.sendSuccessResponse(w, http.StatusCreated, "Task created successfully", task)
+	h.sendTaskResponse(w, r, http.StatusCreated, SuccessResponse{Message: "Task created successfully", Data: task, Warnings: quota.warnings})
 }
 
-// GetTasks handles GET /api/tasks
-func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
-	// Query params: status, limit, offset, sort_by, sort_order
-	q := r.URL.Query()
-	status := q.Get("status")
-	limit := 50
-	offset := 0
-	if v := q.Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			if n < 1 {
-				limit = 1
-			} else if n > 100 {
-				limit = 100
-			} else {
-				limit = n
-			}
+// maxBulkItems caps the number of tasks accepted by a single bulk request
+const maxBulkItems = 100
+
+// BulkCreateResult reports the outcome for one item of a bulk create request
+type BulkCreateResult struct {
+	Index int          `json:"index"`
+	Task  *models.Task `json:"task,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// BulkCreateTasks handles POST /api/tasks/bulk
+func (h *TaskHandler) BulkCreateTasks(w http.ResponseWriter, r *http.Request) {
+	var taskReqs []models.TaskRequest
+	if err := decodeTaskBody(r, &taskReqs); err != nil {
+		h.sendDecodeError(w, err)
+		return
+	}
+
+	if len(taskReqs) == 0 || len(taskReqs) > maxBulkItems {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("request must contain between 1 and %d tasks", maxBulkItems))
+		return
+	}
+
+	// Validate every item up front; only valid items are inserted
+	results := make([]BulkCreateResult, len(taskReqs))
+	valid := make([]*models.TaskRequest, 0, len(taskReqs))
+	validIdx := make([]int, 0, len(taskReqs))
+	uuids := map[string]bool{}
+	for i := range taskReqs {
+		results[i].Index = i
+		taskReqs[i].Normalize()
+		if err := taskReqs[i].Validate(); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err := h.encryption.Check(taskReqs[i].Encryption); err != nil {
+			results[i].Error = err.Error()
+			continue
 		}
+		conflict, err := h.uuidConflict(&taskReqs[i], uuids)
+		if err != nil {
+			log.Printf("Error checking task UUID: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create tasks", "")
+			return
+		}
+		if conflict != "" {
+			results[i].Error = conflict
+			continue
+		}
+		valid = append(valid, &taskReqs[i])
+		validIdx = append(validIdx, i)
+	}
+
+	if len(valid) == 0 {
+		h.sendJSONResponse(w, http.StatusBadRequest, SuccessResponse{Message: "No tasks were valid", Data: results})
+		return
+	}
+
+	quota, err := h.checkTaskQuota(len(valid))
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create tasks", "")
+		return
+	}
+	if quota.exceeded {
+		h.sendQuotaExceeded(w, quota)
+		return
+	}
+
+	tasks, err := h.repo.CreateBatch(valid)
+	if errors.Is(err, models.ErrUUIDTaken) {
+		h.sendErrorResponse(w, http.StatusConflict, "UUID already in use", err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error bulk creating tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create tasks", "")
+		return
 	}
-	if v := q.Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
+	for i := range tasks {
+		results[validIdx[i]].Task = &tasks[i]
+	}
+
+	if len(valid) < len(taskReqs) {
+		h.sendJSONResponse(w, http.StatusMultiStatus, SuccessResponse{Message: "Some tasks failed validation", Data: results, Warnings: quota.warnings})
+		return
+	}
+	h.sendJSONResponse(w, http.StatusCreated, SuccessResponse{Message: "Tasks created successfully", Data: results, Warnings: quota.warnings})
+}
+
+// BulkUpdateTasks handles PATCH /api/tasks/bulk
+func (h *TaskHandler) BulkUpdateTasks(w http.ResponseWriter, r *http.Request) {
+	var bulkReq models.BulkUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&bulkReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := bulkReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	recordUndo, err := h.prepareUndo("bulk_update", bulkReq.IDs, bulkReq.Filter.Selection(), false)
+	if err != nil {
+		log.Printf("Error bulk updating tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update tasks", "")
+		return
+	}
+
+	updated, err := h.repo.UpdateBatch(bulkReq.IDs, bulkReq.Filter.Selection(), bulkReq.Changes)
+	if err != nil {
+		if verr, ok := err.(*models.ValidationError); ok {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+			return
 		}
+		log.Printf("Error bulk updating tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update tasks", "")
+		return
+	}
+
+	recordUndo()
+	h.sendSuccessResponse(w, http.StatusOK, "Tasks updated successfully", map[string]int{"updated": updated})
+}
+
+// ArchiveCompletedTasks handles POST /api/tasks/archive-completed
+func (h *TaskHandler) ArchiveCompletedTasks(w http.ResponseWriter, r *http.Request) {
+	unarchived, archived := false, true
+	filter := models.TaskFilter{Statuses: []string{"completed"}, Archived: &unarchived}
+
+	recordUndo, err := h.prepareUndo("archive_completed", nil, filter, false)
+	if err != nil {
+		log.Printf("Error archiving tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to archive tasks", "")
+		return
+	}
+
+	count, err := h.repo.UpdateBatch(nil, filter, models.TaskChanges{Archived: &archived})
+	if err != nil {
+		log.Printf("Error archiving tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to archive tasks", "")
+		return
+	}
+	recordUndo()
+
+	h.sendSuccessResponse(w, http.StatusOK, "Completed tasks archived successfully", map[string]int{"archived": count})
+}
+
+// CompleteAllTasks handles POST /api/tasks/complete-all
+// It completes every open task matching the list filters in the query
+// string (status, due_within, due_before, ...) in one transaction, so a
+// client can complete exactly the tasks it is showing.
+func (h *TaskHandler) CompleteAllTasks(w http.ResponseWriter, r *http.Request) {
+	params, perr := parseListParams(r.URL.Query(), h.clock.Now(), "created_at", "desc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
 	}
-	sortBy := q.Get("sort_by")
-	if sortBy == "" {
-		sortBy = "created_at"
+	filter := params.filter
+	filter.Open = true
+	filter.After = nil
+	completed := "completed"
+
+	recordUndo, err := h.prepareUndo("complete_all", nil, filter, false)
+	if err != nil {
+		log.Printf("Error completing tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to complete tasks", "")
+		return
 	}
-	sortOrder := q.Get("sort_order")
-	if sortOrder == "" {
-		sortOrder = "desc"
+
+	count, err := h.repo.UpdateBatch(nil, filter, models.TaskChanges{Status: &completed})
+	if err != nil {
+		log.Printf("Error completing tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to complete tasks", "")
+		return
 	}
+	recordUndo()
+
+	h.sendSuccessResponse(w, http.StatusOK, "Tasks completed successfully", map[string]int{"completed": count})
+}
 
-	var filterStatusPtr *string
-	if status != "" {
-		// Validate status
-		if !isValidStatus(status) {
-			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid status", "Status must be one of: pending, in_progress, completed")
+// BulkDeleteTasks handles DELETE /api/tasks
+func (h *TaskHandler) BulkDeleteTasks(w http.ResponseWriter, r *http.Request) {
+	var bulkReq models.BulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&bulkReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := bulkReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	recordUndo := func() {}
+	if !bulkReq.DryRun {
+		var err error
+		if recordUndo, err = h.prepareUndo("bulk_delete", bulkReq.IDs, bulkReq.Filter.Selection(), true); err != nil {
+			log.Printf("Error bulk deleting tasks: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete tasks", "")
 			return
 		}
-		filterStatusPtr = &status
 	}
 
-	tasks, err := h.repo.GetAllPaginated(filterStatusPtr, limit, offset, sortBy, sortOrder)
+	deleted, err := h.repo.DeleteBatch(bulkReq.IDs, bulkReq.Filter.Selection(), bulkReq.DryRun)
+	if err != nil {
+		log.Printf("Error bulk deleting tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete tasks", "")
+		return
+	}
+
+	if bulkReq.DryRun {
+		h.sendSuccessResponse(w, http.StatusOK, "Dry run: no tasks were deleted", map[string]int{"would_delete": deleted})
+		return
+	}
+	recordUndo()
+	h.sendSuccessResponse(w, http.StatusOK, "Tasks deleted successfully", map[string]int{"deleted": deleted})
+}
+
+// GetTasks handles GET /api/tasks
+func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
+	params, perr := parseListParams(r.URL.Query(), h.clock.Now(), "created_at", "desc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+
+	h.sendTaskList(w, r, params)
+}
+
+// GetOverdueTasks handles GET /api/tasks/overdue
+func (h *TaskHandler) GetOverdueTasks(w http.ResponseWriter, r *http.Request) {
+	params, perr := parseListParams(r.URL.Query(), h.clock.Now(), "due_date", "asc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+
+	now := h.clock.Now()
+	params.filter.OverdueAt = &now
+	h.sendTaskList(w, r, params)
+}
+
+// sendTaskList fetches a page of tasks and writes the list response
+func (h *TaskHandler) sendTaskList(w http.ResponseWriter, r *http.Request, params listParams) {
+	// Cursor mode reads one extra task to learn whether another page follows
+	limit := params.limit
+	if params.cursor {
+		limit++
+	}
+	// Only the requested fields are read, where storage allows
+	listFilter := params.filter
+	listFilter.Fields = params.shape.fields
+	tasks, err := h.repo.GetAllPaginated(listFilter, limit, params.offset, params.sortBy, params.sortOrder)
 	if err != nil {
 		log.Printf("Error fetching tasks: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tasks", "")
 		return
 	}
+	hasMore := false
+	if params.cursor && len(tasks) > params.limit {
+		tasks = tasks[:params.limit]
+		hasMore = true
+	}
+	
+	// Copy before adding computed fields; the slice may be shared with
+	// concurrent requests by the coalescing repository. This also returns
+	// an empty array instead of null if no tasks.
+	page := make([]models.Task, len(tasks))
+	copy(page, tasks)
+	for i := range page {
+		page[i].Summarize()
+	}
+	
+	// The total ignores the cursor so it stays the same on every page
+	countFilter := params.filter
+	countFilter.After = nil
+	total, err := h.repo.Count(countFilter)
+	if err != nil {
+		log.Printf("Error counting tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tasks", "")
+		return
+	}
+	
+	pagination := newPagination(r, params, page, total, hasMore)
+	var shape func(*models.Task) (interface{}, error)
+	if !params.shape.plain() {
+		shape = func(task *models.Task) (interface{}, error) {
+			return h.shapeTask(task, params.shape)
+		}
+	}
+	if params.format == "ndjson" {
+		sendNDJSON(w, page, shape, pagination)
+		return
+	}
+	
+	if shape != nil && !wantsJSONAPI(r) {
+		shaped := make([]interface{}, len(page))
+		for i := range page {
+			if shaped[i], err = shape(&page[i]); err != nil {
+				log.Printf("Error fetching tasks: %v", err)
+				h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch tasks", "")
+				return
+			}
+		}
+		h.sendCacheableJSON(w, r, http.StatusOK, SuccessResponse{
+			Message:    "Tasks retrieved successfully",
+			Data:       shaped,
+			Pagination: pagination,
+			Links:      collectionLinks(r, pagination),
+		})
+		return
+	}
+	
+	h.sendTaskResponse(w, r, http.StatusOK, SuccessResponse{
+		Message:    "Tasks retrieved successfully",
+		Data:       page,
+		Pagination: pagination,
+	})
+}
+
+// newPagination builds the pagination block for a page of tasks. hasMore
+// reports whether a cursor-mode page is followed by another.
+func newPagination(r *http.Request, params listParams, page []models.Task, total int, hasMore bool) *Pagination {
+	p := &Pagination{Total: total, Limit: params.limit, Offset: params.offset}
+	
+	link := func(offset int) string {
+		q := r.URL.Query()
+		q.Set("limit", strconv.Itoa(params.limit))
+		q.Set("offset", strconv.Itoa(offset))
+		return r.URL.Path + "?" + q.Encode()
+	}
 	
-	// Return empty array instead of null if no tasks
-	if tasks == nil {
-		tasks = []models.Task{}
+	if params.cursor {
+		if hasMore {
+			p.NextCursor = encodeCursor(page[len(page)-1])
+			q := r.URL.Query()
+			q.Del("offset")
+			q.Set("limit", strconv.Itoa(params.limit))
+			q.Set("cursor", p.NextCursor)
+			p.Next = r.URL.Path + "?" + q.Encode()
+		}
+		return p
 	}
 	
-	h.sendSuccessResponse(w, http.StatusOK, "Tasks retrieved successfully", tasks)
+	if params.offset+len(page) < total {
+		p.Next = link(params.offset + len(page))
+		if params.sortBy == "created_at" && len(page) > 0 {
+			p.NextCursor = encodeCursor(page[len(page)-1])
+		}
+	}
+	if params.offset > 0 {
+		prev := params.offset - params.limit
+		if prev < 0 {
+			prev = 0
+		}
+		p.Prev = link(prev)
+	}
+	return p
 }
 
 // GetTask handles GET /api/tasks/{id}
@@ -123,6 +496,19 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	render := r.URL.Query().Get("render")
+	if render != "" && render != "html" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid render parameter", "render must be html")
+		return
+	}
+	shape, perr := parseTaskShape(r.URL.Query())
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+	shaped := !shape.plain() && !wantsJSONAPI(r)
+	
+
 	task, err := h.repo.GetByID(id)
 	if err != nil {
 		log.Printf("Error fetching task: %v", err)
@@ -134,8 +520,49 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
 		return
 	}
+	// Embedded resources change without the task, so expanded responses
+	// are tagged by their body instead. Other representations get a tag
+	// of their own; the plain JSON keeps the task's tag, for If-Match.
+	expanded := shaped && len(shape.expand) > 0
+	var variant []string
+	if render != "" {
+		variant = append(variant, "render="+render)
+	}
+	if shaped {
+		variant = append(variant, "fields="+strings.Join(shape.fields, ","))
+	}
+	if wantsJSONAPI(r) {
+		variant = append(variant, "jsonapi")
+	}
+	if !expanded && notModified(w, r, variantETag(taskETag(task), variant...)) {
+		return
+	}
+	
+	// Encrypted descriptions are ciphertext, so there is nothing to render.
+	// Render into a copy; repositories may hand out their stored task.
+	if render == "html" && task.Encryption == nil {
+		rendered := *task
+		rendered.DescriptionHTML = markdown.Render(task.Description)
+		task = &rendered
+	}
+	
+	if shaped {
+		doc, err := h.shapeTask(task, shape)
+		if err != nil {
+			log.Printf("Error fetching task: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", "")
+			return
+		}
+		response := SuccessResponse{Message: "Task retrieved successfully", Data: doc}
+		if expanded {
+			h.sendCacheableJSON(w, r, http.StatusOK, response)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, response)
+		return
+	}
 	
-	h.sendSuccessResponse(w, http.StatusOK, "Task retrieved successfully", task)
+	h.sendTaskResponse(w, r, http.StatusOK, SuccessResponse{Message: "Task retrieved successfully", Data: task})
 }
 
 // UpdateTask handles PUT /api/tasks/{id}
@@ -148,19 +575,43 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var taskReq models.TaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&taskReq); err != nil {
-		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+	if err := decodeTaskBody(r, &taskReq); err != nil {
+		h.sendDecodeError(w, err)
 		return
 	}
 	
+	taskReq.Normalize()
+	
 	// For updates, we allow partial updates, so we don't require title
 	if taskReq.Status != "" && !isValidStatus(taskReq.Status) {
 		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid status", "Status must be one of: pending, in_progress, completed")
 		return
 	}
 	
-	task, err := h.repo.Update(id, &taskReq)
+	if err := taskReq.ValidateUpdate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+	
+	if err := h.encryption.Check(taskReq.Encryption); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+	
+	cond, ok := h.ifMatchFor(w, r)
+	if !ok {
+		return
+	}
+	task, err := h.repo.Update(id, &taskReq, cond.check())
 	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			h.sendPreconditionFailed(w, cond)
+			return
+		}
+		if verr, ok := err.(*models.ValidationError); ok {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+			return
+		}
 		log.Printf("Error updating task: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update task", "")
 		return
@@ -171,7 +622,70 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	h.sendSuccessResponse(w, http.StatusOK, "Task updated successfully", task)
+	w.Header().Set("ETag", taskETag(task))
+	h.sendTaskResponse(w, r, http.StatusOK, SuccessResponse{Message: "Task updated successfully", Data: task})
+}
+
+// PatchTask handles PATCH /api/tasks/{id}
+func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	if isJSONPatchBody(r) {
+		h.patchTaskWithJSONPatch(w, r, id)
+		return
+	}
+
+	var patch models.TaskPatch
+	if err := decodeTaskBody(r, &patch); err != nil {
+		h.sendDecodeError(w, err)
+		return
+	}
+
+	patch.Normalize()
+	if err := patch.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	// Patches cannot turn encryption off, so only new key metadata is checked
+	if patch.Encryption != nil {
+		if err := h.encryption.Check(patch.Encryption); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+			return
+		}
+	}
+
+	cond, ok := h.ifMatchFor(w, r)
+	if !ok {
+		return
+	}
+	task, err := h.repo.Patch(id, &patch, cond.check())
+	if err != nil {
+		if errors.Is(err, models.ErrVersionMismatch) {
+			h.sendPreconditionFailed(w, cond)
+			return
+		}
+		if verr, ok := err.(*models.ValidationError); ok {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+			return
+		}
+		log.Printf("Error patching task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update task", "")
+		return
+	}
+
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(task))
+	h.sendTaskResponse(w, r, http.StatusOK, SuccessResponse{Message: "Task updated successfully", Data: task})
 }
 
 // DeleteTask handles DELETE /api/tasks/{id}
@@ -183,20 +697,236 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	err = h.repo.Delete(id)
+	cond, ok := h.ifMatchFor(w, r)
+	if !ok {
+		return
+	}
+	recordUndo, err := h.prepareUndo("delete", []int{id}, models.TaskFilter{}, true)
+	if err != nil {
+		log.Printf("Error deleting task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", "")
+		return
+	}
+	
+	err = h.repo.Delete(id, cond.check())
 	if err != nil {
 		if err == sql.ErrNoRows {
 			h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
 			return
 		}
+		if errors.Is(err, models.ErrVersionMismatch) {
+			h.sendPreconditionFailed(w, cond)
+			return
+		}
 		log.Printf("Error deleting task: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", "")
 		return
 	}
+	recordUndo()
 	
 	h.sendSuccessResponse(w, http.StatusOK, "Task deleted successfully", nil)
 }
 
+// MoveTask handles POST /api/tasks/{id}/move
+func (h *TaskHandler) MoveTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	var moveReq models.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&moveReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := moveReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	task, err := h.repo.Move(id, moveReq.Position)
+	if err != nil {
+		log.Printf("Error moving task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to move task", "")
+		return
+	}
+
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Task moved successfully", withTaskLinks(task))
+}
+
+// SnoozeTask handles POST /api/tasks/{id}/snooze
+func (h *TaskHandler) SnoozeTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	var snoozeReq models.SnoozeRequest
+	if err := json.NewDecoder(r.Body).Decode(&snoozeReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := snoozeReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	task, err := h.repo.GetByID(id)
+	if err != nil {
+		log.Printf("Error fetching task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to snooze task", "")
+		return
+	}
+
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	patch, err := snoozeReq.Patch(task, h.clock.Now())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	task, err = h.repo.Patch(id, patch, nil)
+	if err != nil {
+		if verr, ok := err.(*models.ValidationError); ok {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", verr.Error())
+			return
+		}
+		log.Printf("Error snoozing task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to snooze task", "")
+		return
+	}
+
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Task snoozed successfully", withTaskLinks(task))
+}
+
+// TogglePinTask handles POST /api/tasks/{id}/pin
+func (h *TaskHandler) TogglePinTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	task, err := h.repo.TogglePin(id)
+	if err != nil {
+		log.Printf("Error toggling pin: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update task", "")
+		return
+	}
+
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	message := "Task unpinned successfully"
+	if task.Pinned {
+		message = "Task pinned successfully"
+	}
+	h.sendSuccessResponse(w, http.StatusOK, message, withTaskLinks(task))
+}
+
+// DuplicateTask handles POST /api/tasks/{id}/duplicate
+func (h *TaskHandler) DuplicateTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid task ID", "Task ID must be a number")
+		return
+	}
+
+	// The body is optional; an empty one duplicates without shifting dates
+	var dupReq models.DuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&dupReq); err != nil && err != io.EOF {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := dupReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	source, err := h.repo.GetByID(id)
+	if err != nil {
+		log.Printf("Error fetching task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to duplicate task", "")
+		return
+	}
+
+	if source == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+
+	quota, err := h.checkTaskQuota(1)
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to duplicate task", "")
+		return
+	}
+	if quota.exceeded {
+		h.sendQuotaExceeded(w, quota)
+		return
+	}
+
+	task, err := h.repo.Create(dupReq.Copy(source))
+	if err != nil {
+		log.Printf("Error duplicating task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to duplicate task", "")
+		return
+	}
+
+	h.sendJSONResponse(w, http.StatusCreated, SuccessResponse{Message: "Task duplicated successfully", Data: withTaskLinks(task), Warnings: quota.warnings})
+}
+
+// ReorderTasks handles PUT /api/tasks/reorder
+func (h *TaskHandler) ReorderTasks(w http.ResponseWriter, r *http.Request) {
+	var reorderReq models.ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&reorderReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := reorderReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.repo.Reorder(reorderReq.IDs); err != nil {
+		if err == sql.ErrNoRows {
+			h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "One or more task IDs do not exist")
+			return
+		}
+		log.Printf("Error reordering tasks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to reorder tasks", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Tasks reordered successfully", nil)
+}
+
 // HealthCheck handles GET /health
 func (h *TaskHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{
@@ -224,13 +954,16 @@ func (h *TaskHandler) sendErrorResponse(w http.ResponseWriter, statusCode int, e
 
 // sendSuccessResponse sends a standardized success response
 func (h *TaskHandler) sendSuccessResponse(w http.ResponseWriter, statusCode int, message string, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	response := SuccessResponse{
+	h.sendJSONResponse(w, statusCode, SuccessResponse{
 		Message: message,
 		Data:    data,
-	}
+	})
+}
+
+// sendJSONResponse writes an arbitrary JSON response body
+func (h *TaskHandler) sendJSONResponse(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	
 	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"to-do-api/database"
+	"to-do-api/handlers"
+	"to-do-api/models"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestRepository opens a fresh SQLite repository in a temporary
+// directory, holding the tasks titled in titles
+func newTestRepository(t *testing.T, titles ...string) *models.SQLiteTaskRepository {
+	t.Helper()
+	t.Setenv("LIBSQL_URL", "")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "tasks.db"))
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := models.NewSQLiteTaskRepository(db)
+	for _, title := range titles {
+		if _, err := repo.Create(&models.TaskRequest{Title: title, Status: "pending"}); err != nil {
+			t.Fatalf("Create(%q): %v", title, err)
+		}
+	}
+	return repo
+}
+
+// serve sends a JSON request to handler and returns the response
+func serve(handler http.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestBulkDeleteRejectsEmptyFilter(t *testing.T) {
+	repo := newTestRepository(t, "first", "second")
+	h := handlers.NewTaskHandler(repo)
+
+	rec := serve(h.BulkDeleteTasks, http.MethodDelete, "/api/tasks", `{"filter":{}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	if count, err := repo.Count(models.TaskFilter{}); err != nil || count != 2 {
+		t.Fatalf("Count = %d, %v; want 2 tasks left", count, err)
+	}
+
+	rec = serve(h.BulkDeleteTasks, http.MethodDelete, "/api/tasks", `{"filter":{"all":true}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("all: status = %d, want %d; body %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if count, err := repo.Count(models.TaskFilter{}); err != nil || count != 0 {
+		t.Fatalf("all: Count = %d, %v; want 0", count, err)
+	}
+}
+
+func TestBulkUpdateRejectsEmptyFilter(t *testing.T) {
+	repo := newTestRepository(t, "first", "second")
+	h := handlers.NewTaskHandler(repo)
+
+	rec := serve(h.BulkUpdateTasks, http.MethodPatch, "/api/tasks/bulk", `{"filter":{},"changes":{"status":"completed"}}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	if count, err := repo.Count(models.TaskFilter{Statuses: []string{"completed"}}); err != nil || count != 0 {
+		t.Fatalf("Count(completed) = %d, %v; want 0", count, err)
+	}
+}
+
+func TestGetTaskTagsEachRepresentation(t *testing.T) {
+	repo := newTestRepository(t, "first")
+	h := handlers.NewTaskHandler(repo)
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, path, nil), map[string]string{"id": "1"})
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h.GetTask(rec, req)
+		return rec
+	}
+
+	seen := map[string]string{}
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"plain":      get("/api/tasks/1"),
+		"fields":     get("/api/tasks/1?fields=id,title"),
+		"render":     get("/api/tasks/1?render=html"),
+		"timestamps": get("/api/tasks/1", "X-Timestamp-Format", "epoch_ms"),
+	} {
+		etag := rec.Header().Get("ETag")
+		if etag == "" {
+			t.Fatalf("%s: no ETag", name)
+		}
+		if other, ok := seen[etag]; ok {
+			t.Errorf("%s and %s share the ETag %s", name, other, etag)
+		}
+		seen[etag] = name
+	}
+
+	rec := get("/api/tasks/1", "X-Timestamp-Format", "epoch_ms")
+	vary := strings.Join(rec.Header().Values("Vary"), ",")
+	if !strings.Contains(vary, "X-Timestamp-Format") {
+		t.Errorf("Vary = %q, want X-Timestamp-Format", vary)
+	}
+	rec = get("/api/tasks/1", "X-Timestamp-Format", "epoch_ms", "If-None-Match", rec.Header().Get("ETag"))
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation: status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"to-do-api/models"
+	"to-do-api/todoist"
+)
+
+// TodoistImportRow reports one imported Todoist task
+type TodoistImportRow struct {
+	TodoistID string              `json:"todoist_id"`
+	Preview   *models.TaskRequest `json:"preview,omitempty"`
+	Task      *models.Task        `json:"task,omitempty"`
+	Created   bool                `json:"created,omitempty"`
+}
+
+// TodoistImportResult summarizes a Todoist import
+type TodoistImportResult struct {
+	DryRun  bool               `json:"dry_run"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Tasks   []TodoistImportRow `json:"tasks"`
+	Skipped []todoist.Skipped  `json:"skipped"`
+}
+
+// ImportTodoist handles POST /api/tasks/import/todoist
+// The body is a Todoist export (projects plus items or tasks, or a bare
+// array of tasks), or {"token": "..."} to fetch open tasks with a Todoist
+// API token. Tasks are upserted by their Todoist ID, so importing again
+// updates them instead of creating duplicates; dry_run=true only reports
+// what would be imported.
+func (h *TaskHandler) ImportTodoist(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := parseBoolParam(r.URL.Query().Get("dry_run"))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid dry_run parameter", "dry_run must be true or false")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, "File too large", fmt.Sprintf("Imports are limited to %d MB", maxImportBytes>>20))
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	var export *todoist.Export
+	var tokenReq struct {
+		Token string `json:"token"`
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) && json.Unmarshal(body, &tokenReq) == nil && tokenReq.Token != "" {
+		export, err = todoist.Fetch(r.Context(), tokenReq.Token)
+		if err == todoist.ErrUnauthorized {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Todoist refused the token", "Check the API token under Todoist settings, Integrations")
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching from Todoist: %v", err)
+			h.sendErrorResponse(w, http.StatusBadGateway, "Failed to fetch from Todoist", "")
+			return
+		}
+	} else if export, err = todoist.ParseExport(body); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid Todoist export", err.Error())
+		return
+	}
+
+	tasks, skipped, warnings := todoist.Convert(export)
+	if len(tasks) > maxImportRows {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", fmt.Sprintf("imports are limited to %d tasks", maxImportRows))
+		return
+	}
+
+	result := TodoistImportResult{DryRun: dryRun, Tasks: []TodoistImportRow{}, Skipped: skipped}
+	valid := tasks[:0]
+	for _, task := range tasks {
+		task.Request.Normalize()
+		if err := task.Request.Validate(); err != nil {
+			result.Skipped = append(result.Skipped, todoist.Skipped{ID: task.ExternalID, Content: task.Request.Title, Reason: err.Error()})
+			continue
+		}
+		valid = append(valid, task)
+	}
+	if result.Skipped == nil {
+		result.Skipped = []todoist.Skipped{}
+	}
+
+	if dryRun {
+		for i := range valid {
+			result.Tasks = append(result.Tasks, TodoistImportRow{TodoistID: valid[i].ExternalID, Preview: &valid[i].Request})
+		}
+		h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Todoist import preview", Data: result, Warnings: warnings})
+		return
+	}
+
+	// As with external upserts, the quota can't tell updates from creates,
+	// so imports are never refused; they still carry the warning
+	quota, err := h.checkTaskQuota(0)
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to import tasks", "")
+		return
+	}
+
+	// Each task is upserted on its own, so a failure part way leaves the
+	// earlier ones in place; running the import again picks up the rest
+	for _, item := range valid {
+		task, created, err := h.repo.UpsertExternal(todoist.Source, item.ExternalID, &item.Request)
+		if err != nil {
+			log.Printf("Error importing Todoist task %s: %v", item.ExternalID, err)
+			h.sendJSONResponse(w, http.StatusInternalServerError, SuccessResponse{Message: "Import stopped by an error; run it again to continue", Data: result, Warnings: warnings})
+			return
+		}
+		if created {
+			result.Created++
+			// Notes are append-only, so they are only added on the first import
+			if item.Note != "" {
+				if _, err := h.repo.AddNote(task.ID, &models.NoteRequest{Body: item.Note}); err != nil {
+					log.Printf("Error adding note to imported task %d: %v", task.ID, err)
+				}
+			}
+		} else {
+			result.Updated++
+		}
+		result.Tasks = append(result.Tasks, TodoistImportRow{TodoistID: item.ExternalID, Task: task, Created: created})
+	}
+
+	warnings = append(warnings, quota.warnings...)
+	h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Todoist tasks imported", Data: result, Warnings: warnings})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"to-do-api/models"
+)
+
+// Per-task errors reported by TransitionTasks
+var (
+	errTaskNotFound     = errors.New("task not found")
+	errTransitionFailed = errors.New("failed to update task")
+)
+
+// TransitionResult reports the outcome for one task of a transition request
+type TransitionResult struct {
+	ID    int          `json:"id"`
+	From  string       `json:"from,omitempty"`
+	Task  *models.Task `json:"task,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// TransitionTasks handles POST /api/tasks/transition
+// Each task is checked against the workflow rules and moved on its own, so
+// one disallowed or missing task does not hold back the rest.
+func (h *TaskHandler) TransitionTasks(w http.ResponseWriter, r *http.Request) {
+	var transitionReq models.TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&transitionReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := transitionReq.Validate(maxBulkItems); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	results := make([]TransitionResult, len(transitionReq.IDs))
+	failed := 0
+	for i, id := range transitionReq.IDs {
+		results[i].ID = id
+		task, err := h.transitionTask(id, transitionReq.Status, &results[i])
+		if err != nil {
+			results[i].Error = err.Error()
+			failed++
+			continue
+		}
+		results[i].Task = task
+	}
+
+	switch {
+	case failed == len(results):
+		h.sendJSONResponse(w, http.StatusBadRequest, SuccessResponse{Message: "No tasks were transitioned", Data: results})
+	case failed > 0:
+		h.sendJSONResponse(w, http.StatusMultiStatus, SuccessResponse{Message: "Some tasks could not be transitioned", Data: results})
+	default:
+		h.sendSuccessResponse(w, http.StatusOK, "Tasks transitioned successfully", results)
+	}
+}
+
+// transitionTask moves one task to status, recording its previous status in
+// result. Errors are safe to show to the client.
+func (h *TaskHandler) transitionTask(id int, status string, result *TransitionResult) (*models.Task, error) {
+	task, err := h.repo.GetByID(id)
+	if err != nil {
+		log.Printf("Error retrieving task %d: %v", id, err)
+		return nil, errTransitionFailed
+	}
+	if task == nil {
+		return nil, errTaskNotFound
+	}
+	result.From = task.Status
+	if err := models.CheckTransition(task.Status, status); err != nil {
+		return nil, err
+	}
+	if task.Status == status {
+		return task, nil
+	}
+
+	task, err = h.repo.Patch(id, &models.TaskPatch{Status: &status}, nil)
+	if err != nil {
+		log.Printf("Error transitioning task %d: %v", id, err)
+		return nil, errTransitionFailed
+	}
+	if task == nil {
+		return nil, errTaskNotFound
+	}
+	return task, nil
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+	"to-do-api/models"
+)
+
+// Undo log limits. Entries are kept in memory only, so a restart clears them.
+const (
+	undoTTL   = 10 * time.Minute
+	undoDepth = 20
+	// maxUndoTasks caps the tasks one entry may hold; larger bulk operations
+	// can't be undone
+	maxUndoTasks = 1000
+)
+
+// undoEntry records the state a destructive action replaced
+type undoEntry struct {
+	action    string
+	at        time.Time
+	snapshots []models.TaskSnapshot
+}
+
+// undoLog is a short-lived stack of undoable actions. There are no user
+// accounts, so it is shared by every client of the server.
+type undoLog struct {
+	mu      sync.Mutex
+	entries []undoEntry
+}
+
+// push records an action, dropping the oldest beyond undoDepth
+func (l *undoLog) push(entry undoEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > undoDepth {
+		l.entries = l.entries[len(l.entries)-undoDepth:]
+	}
+}
+
+// clear drops every entry, once they no longer describe the stored data
+func (l *undoLog) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// save returns the entries held, for reset to put back
+func (l *undoLog) save() []undoEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]undoEntry(nil), l.entries...)
+}
+
+// reset replaces the entries with ones saved earlier
+func (l *undoLog) reset(entries []undoEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = entries
+}
+
+// pop removes and returns the most recent entry that has not expired
+func (l *undoLog) pop(now time.Time) (undoEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for len(l.entries) > 0 {
+		entry := l.entries[len(l.entries)-1]
+		l.entries = l.entries[:len(l.entries)-1]
+		if now.Sub(entry.at) <= undoTTL {
+			return entry, true
+		}
+	}
+	return undoEntry{}, false
+}
+
+// UndoResult reports what POST /api/undo restored
+type UndoResult struct {
+	Action   string        `json:"action"`
+	At       time.Time     `json:"performed_at"`
+	Restored []models.Task `json:"restored"`
+}
+
+// errUndoTooLarge means a selection has too many tasks to record for undo
+var errUndoTooLarge = errors.New("too many tasks to undo")
+
+// snapshotTasks captures the tasks an action is about to change or delete,
+// selected as the bulk endpoints select them: by ids when given, otherwise
+// by filter. withChildren also captures links and notes, for deletes.
+func (h *TaskHandler) snapshotTasks(ids []int, filter models.TaskFilter, withChildren bool) ([]models.TaskSnapshot, error) {
+	var tasks []models.Task
+	if len(ids) > 0 {
+		for _, id := range ids {
+			task, err := h.repo.GetByID(id)
+			if err != nil {
+				return nil, err
+			}
+			if task != nil {
+				tasks = append(tasks, *task)
+			}
+		}
+	} else {
+		count, err := h.repo.Count(filter)
+		if err != nil {
+			return nil, err
+		}
+		if count > maxUndoTasks {
+			return nil, errUndoTooLarge
+		}
+		if count > 0 {
+			if tasks, err = h.repo.GetAllPaginated(filter, count, 0, "id", "asc"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	snapshots := make([]models.TaskSnapshot, len(tasks))
+	for i, task := range tasks {
+		snapshots[i].Task = task
+		if !withChildren {
+			continue
+		}
+		links, err := h.repo.ListLinks(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		notes, err := h.repo.ListNotes(task.ID)
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i].Links, snapshots[i].Notes = links, notes
+	}
+	return snapshots, nil
+}
+
+// prepareUndo snapshots a selection before a destructive action. It returns
+// a function that records the action once it has succeeded; a selection too
+// large to record is allowed to go ahead without undo.
+func (h *TaskHandler) prepareUndo(action string, ids []int, filter models.TaskFilter, withChildren bool) (func(), error) {
+	snapshots, err := h.snapshotTasks(ids, filter, withChildren)
+	if err == errUndoTooLarge {
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if len(snapshots) > 0 {
+			h.undo.push(undoEntry{action: action, at: h.clock.Now(), snapshots: snapshots})
+		}
+	}, nil
+}
+
+// Undo handles POST /api/undo
+// It reverses the most recent delete, bulk delete, bulk update, archive or
+// complete-all from the last 10 minutes and returns the restored tasks. Each call undoes
+// one more action.
+func (h *TaskHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.undo.pop(h.clock.Now())
+	if !ok {
+		h.sendErrorResponse(w, http.StatusNotFound, "Nothing to undo", "No destructive action in the last 10 minutes")
+		return
+	}
+
+	if err := h.repo.RestoreTasks(entry.snapshots); err != nil {
+		if errors.Is(err, models.ErrRestoreConflict) {
+			h.sendErrorResponse(w, http.StatusConflict, "Cannot undo", err.Error())
+			return
+		}
+		log.Printf("Error undoing %s: %v", entry.action, err)
+		h.undo.push(entry)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to undo", "")
+		return
+	}
+
+	result := UndoResult{Action: entry.action, At: entry.at, Restored: make([]models.Task, len(entry.snapshots))}
+	for i, snapshot := range entry.snapshots {
+		result.Restored[i] = snapshot.Task
+	}
+	h.sendSuccessResponse(w, http.StatusOK, "Action undone successfully", result)
+}
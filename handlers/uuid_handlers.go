@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"to-do-api/models"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTaskByUUID handles GET /api/tasks/uuid/{uuid}
+// It answers as GET /api/tasks/{id} does for the task created under a
+// client-generated UUID, so offline clients can find their tasks without
+// having learned the IDs the server assigned.
+func (h *TaskHandler) GetTaskByUUID(w http.ResponseWriter, r *http.Request) {
+	uuid := strings.ToLower(mux.Vars(r)["uuid"])
+	if !models.IsUUID(uuid) {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid UUID", "uuid must be a UUID such as 123e4567-e89b-12d3-a456-426614174000")
+		return
+	}
+	task, err := h.taskByUUID(uuid)
+	if err != nil {
+		log.Printf("Error fetching task: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch task", "")
+		return
+	}
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found", "")
+		return
+	}
+	h.GetTask(w, mux.SetURLVars(r, map[string]string{"id": strconv.Itoa(task.ID)}))
+}
+
+// taskByUUID returns the task created under uuid, or nil if there is none
+func (h *TaskHandler) taskByUUID(uuid string) (*models.Task, error) {
+	tasks, err := h.repo.GetAllPaginated(models.TaskFilter{UUID: uuid}, 1, 0, "created_at", "asc")
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// uuidConflict explains why req can't be created under its UUID: an
+// earlier item of the same request took it (seen holds their UUIDs), or a
+// stored task has it. It returns "" when the UUID is free or absent.
+func (h *TaskHandler) uuidConflict(req *models.TaskRequest, seen map[string]bool) (string, error) {
+	if req.UUID == "" {
+		return "", nil
+	}
+	if seen[req.UUID] {
+		return "uuid is used by an earlier task in this request", nil
+	}
+	seen[req.UUID] = true
+	task, err := h.taskByUUID(req.UUID)
+	if err != nil || task == nil {
+		return "", err
+	}
+	return fmt.Sprintf("uuid belongs to task %d", task.ID), nil
+}
+
+// sendUUIDTaken answers 409 for a create whose UUID is taken, pointing at
+// the task that has it so a client retrying an offline create can pick it up
+func (h *TaskHandler) sendUUIDTaken(w http.ResponseWriter, uuid string) {
+	task, err := h.taskByUUID(uuid)
+	if err != nil {
+		log.Printf("Error fetching task: %v", err)
+	}
+	if task == nil {
+		h.sendErrorResponse(w, http.StatusConflict, "UUID already in use", models.ErrUUIDTaken.Error())
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/tasks/%d", task.ID))
+	h.sendErrorResponse(w, http.StatusConflict, "UUID already in use", fmt.Sprintf("uuid belongs to task %d", task.ID))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxUpcomingDays caps the window accepted by GET /api/tasks/upcoming
+const maxUpcomingDays = 90
+
+// requestLocation returns the time zone named by the tz query parameter
+// (an IANA name such as "Europe/Berlin"), defaulting to the server's zone
+func requestLocation(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// startOfDay returns midnight of the day containing t in t's location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// GetTodayTasks handles GET /api/tasks/today
+// It returns open tasks due by the end of today, including overdue ones.
+func (h *TaskHandler) GetTodayTasks(w http.ResponseWriter, r *http.Request) {
+	loc, err := requestLocation(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tz", "tz must be an IANA time zone name such as Europe/Berlin")
+		return
+	}
+
+	params, perr := parseListParams(r.URL.Query(), h.clock.Now(), "due_date", "asc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+
+	endOfToday := startOfDay(h.clock.Now().In(loc)).AddDate(0, 0, 1).Add(-time.Nanosecond)
+	params.filter.DueBefore = &endOfToday
+	params.filter.Open = true
+	h.sendTaskList(w, r, params)
+}
+
+// GetUpcomingTasks handles GET /api/tasks/upcoming?days=7
+// It returns open tasks due from tomorrow through the end of the given number of days.
+func (h *TaskHandler) GetUpcomingTasks(w http.ResponseWriter, r *http.Request) {
+	loc, err := requestLocation(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid tz", "tz must be an IANA time zone name such as Europe/Berlin")
+		return
+	}
+
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUpcomingDays {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid days", "days must be a number between 1 and 90")
+			return
+		}
+		days = n
+	}
+
+	params, perr := parseListParams(r.URL.Query(), h.clock.Now(), "due_date", "asc")
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+
+	tomorrow := startOfDay(h.clock.Now().In(loc)).AddDate(0, 0, 1)
+	end := tomorrow.AddDate(0, 0, days).Add(-time.Nanosecond)
+	params.filter.DueAfter = &tomorrow
+	params.filter.DueBefore = &end
+	params.filter.Open = true
+	h.sendTaskList(w, r, params)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"to-do-api/models"
+	"to-do-api/webhooks"
+
+	"github.com/gorilla/mux"
+)
+
+// SetWebhookKeys sets the keys published at /api/webhooks/keys
+func (h *TaskHandler) SetWebhookKeys(keys *webhooks.KeySet) {
+	h.webhookKeys = keys
+}
+
+// GetWebhookKeys handles GET /api/webhooks/keys
+// It lists the public keys receivers use to verify webhook signatures.
+func (h *TaskHandler) GetWebhookKeys(w http.ResponseWriter, r *http.Request) {
+	h.sendSuccessResponse(w, http.StatusOK, "Webhook keys retrieved successfully", h.webhookKeys.PublicKeys())
+}
+
+// SetWebhookDispatcher sets the dispatcher whose delivery log is served at
+// /api/webhooks/{id}/deliveries. Its reads of webhooks wait for a running
+// batch.
+func (h *TaskHandler) SetWebhookDispatcher(d *webhooks.Dispatcher) {
+	d.SetLock(h.batch.mu.RLocker())
+	h.webhooks = d
+}
+
+// ListWebhooks handles GET /api/webhooks
+func (h *TaskHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.repo.ListWebhooks()
+	if err != nil {
+		log.Printf("Error fetching webhooks: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch webhooks", "")
+		return
+	}
+	// Secrets are only shown once, when the webhook is created
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Webhooks retrieved successfully", hooks)
+}
+
+// CreateWebhook handles POST /api/webhooks
+// The response includes the secret that keys the delivery signatures; it
+// is not shown again.
+func (h *TaskHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhookReq models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookReq); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format", err.Error())
+		return
+	}
+
+	webhookReq.Normalize()
+	if err := webhookReq.Validate(); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	hook, err := h.repo.CreateWebhook(&webhookReq)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create webhook", "")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusCreated, "Webhook created successfully", hook)
+}
+
+// GetWebhook handles GET /api/webhooks/{id}
+func (h *TaskHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.findWebhook(w, r)
+	if !ok {
+		return
+	}
+	hook.Secret = ""
+
+	h.sendSuccessResponse(w, http.StatusOK, "Webhook retrieved successfully", hook)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/{id}
+// Deliveries still pending for the webhook are dropped.
+func (h *TaskHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid webhook ID", "Webhook ID must be a number")
+		return
+	}
+
+	if err := h.repo.DeleteWebhook(id); err != nil {
+		if err == sql.ErrNoRows {
+			h.sendErrorResponse(w, http.StatusNotFound, "Webhook not found", "")
+			return
+		}
+		log.Printf("Error deleting webhook: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete webhook", "")
+		return
+	}
+	if h.webhooks != nil {
+		h.webhooks.Forget(id)
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, "Webhook deleted successfully", nil)
+}
+
+// ListWebhookDeliveries handles GET /api/webhooks/{id}/deliveries
+// It lists the webhook's most recent delivery attempts, newest first.
+func (h *TaskHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.findWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	attempts := []webhooks.Attempt{}
+	if h.webhooks != nil {
+		attempts = h.webhooks.Attempts(hook.ID)
+	}
+	h.sendSuccessResponse(w, http.StatusOK, "Webhook deliveries retrieved successfully", attempts)
+}
+
+// findWebhook loads the webhook named in the path, writing the error
+// response and returning false when it cannot
+func (h *TaskHandler) findWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid webhook ID", "Webhook ID must be a number")
+		return nil, false
+	}
+
+	hook, err := h.repo.GetWebhook(id)
+	if err != nil {
+		log.Printf("Error fetching webhook: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to fetch webhook", "")
+		return nil, false
+	}
+	if hook == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, "Webhook not found", "")
+		return nil, false
+	}
+	return hook, true
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"to-do-api/models"
+)
+
+// WorkspaceSettings describes server-wide settings clients adapt to
+type WorkspaceSettings struct {
+	// Encryption is the policy for client-side encrypted task content
+	Encryption models.EncryptionMode `json:"encryption"`
+	// TaskQuota is the maximum number of tasks, if limited, and TasksUsed
+	// how many exist
+	TaskQuota int  `json:"task_quota,omitempty"`
+	TasksUsed *int `json:"tasks_used,omitempty"`
+}
+
+// SetEncryptionMode sets whether tasks may, must or must not carry
+// client-encrypted title and description
+func (h *TaskHandler) SetEncryptionMode(mode models.EncryptionMode) {
+	h.encryption = mode
+}
+
+// GetWorkspace handles GET /api/workspace
+func (h *TaskHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	settings := WorkspaceSettings{Encryption: h.encryption, TaskQuota: h.taskQuota}
+
+	quota, err := h.checkTaskQuota(0)
+	if err != nil {
+		log.Printf("Error checking task quota: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve workspace", "")
+		return
+	}
+	if h.taskQuota > 0 {
+		settings.TasksUsed = &quota.used
+	}
+
+	h.sendJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Workspace retrieved successfully", Data: settings, Warnings: quota.warnings})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"to-do-api/events"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// WebSocket stream settings
+const (
+	// wsBuffer is the number of events queued for a client before the
+	// oldest are dropped
+	wsBuffer = 256
+	// wsWriteTimeout bounds a single message or ping; a client that cannot
+	// keep up is disconnected
+	wsWriteTimeout = 10 * time.Second
+	// wsPingInterval keeps idle connections open through proxies and
+	// detects dead clients
+	wsPingInterval = 30 * time.Second
+)
+
+// TaskEventsWebSocket handles GET /api/ws
+// It upgrades to a WebSocket and sends every task event on the bus as a
+// JSON text message, optionally limited to tasks with the given status.
+// Messages from the client are ignored. Events are dropped, oldest first,
+// for a client that falls behind; a gap in seq tells it to reload.
+func (h *TaskHandler) TaskEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	filter, perr := parseEventFilter(r.URL.Query())
+	if perr != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, perr.title, perr.message)
+		return
+	}
+	if h.events == nil {
+		h.sendErrorResponse(w, http.StatusServiceUnavailable, "Event stream unavailable", "This server does not publish task events")
+		return
+	}
+
+	// The connection outlives the server's read and write timeouts
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	// Any origin may connect, as CORS allows any origin for the rest of
+	// the API
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		// Accept has already written the error response
+		return
+	}
+	defer conn.CloseNow()
+
+	sub := h.events.Subscribe(events.Options{Name: "websocket", Buffer: wsBuffer, Policy: events.DropOldest})
+	defer sub.Close()
+
+	ctx := conn.CloseRead(r.Context())
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				conn.Close(websocket.StatusGoingAway, "event stream closed")
+				return
+			}
+			if !filter.matches(e) {
+				continue
+			}
+			wctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := wsjson.Write(wctx, conn, e)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			pctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := conn.Ping(pctx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package i18n translates the API's user-facing messages.
+//
+// Messages are written in English in the code, and English text is also the
+// catalog key: a locale is a JSON object mapping English messages to their
+// translations, in locales/<language>.json. Keys may contain {name}
+// placeholders standing for the variable parts of a message, such as
+// "{id} not found", which the translation repeats wherever the language
+// puts them. Messages without a translation are left in English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceLanguage is the language messages are written in
+const SourceLanguage = "en"
+
+//go:embed locales/*.json
+var files embed.FS
+
+// placeholder matches a {name} in a catalog entry
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// pattern is a catalog entry with placeholders
+type pattern struct {
+	re          *regexp.Regexp
+	names       []string
+	translation string
+}
+
+// locale is the catalog of one language
+type locale struct {
+	exact    map[string]string
+	patterns []pattern
+}
+
+// Catalog holds the translations of every supported language
+type Catalog struct {
+	locales map[string]*locale
+}
+
+// New returns a catalog of the locales built into the API
+func New() (*Catalog, error) {
+	c := &Catalog{locales: map[string]*locale{}}
+	if err := c.load(files, "locales"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadDir adds the locales in dir, one <language>.json file each, to the
+// catalog. A file for a built-in language replaces its entries one by one.
+func (c *Catalog) LoadDir(dir string) error {
+	return c.load(os.DirFS(dir), ".")
+}
+
+// load reads every .json file in dir of fsys as a locale
+func (c *Catalog) load(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, name)))
+		if err != nil {
+			return err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := c.add(strings.ToLower(strings.TrimSuffix(name, ".json")), messages); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// add merges translations into a language's catalog
+func (c *Catalog) add(lang string, messages map[string]string) error {
+	l, ok := c.locales[lang]
+	if !ok {
+		l = &locale{exact: map[string]string{}}
+		c.locales[lang] = l
+	}
+	// Sorted, so the first of two matching patterns is always the same one
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		translation := messages[key]
+		names := placeholder.FindAllString(key, -1)
+		if len(names) == 0 {
+			l.exact[key] = translation
+			continue
+		}
+		for _, name := range placeholder.FindAllString(translation, -1) {
+			if !strings.Contains(key, name) {
+				return fmt.Errorf("translation of %q uses %s, which the message does not have", key, name)
+			}
+		}
+		// Each placeholder matches anything; the literal text is anchored
+		parts := placeholder.Split(key, -1)
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		re := regexp.MustCompile("^" + strings.Join(parts, "(.*?)") + "$")
+		l.patterns = append(l.patterns, pattern{re: re, names: names, translation: translation})
+	}
+	return nil
+}
+
+// Languages lists the supported languages, the source language first
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.locales)+1)
+	for lang := range c.locales {
+		if lang != SourceLanguage {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return append([]string{SourceLanguage}, langs...)
+}
+
+// Match picks the supported language an Accept-Language header prefers
+// most, falling back to the source language. A regional tag such as de-AT
+// also matches its language.
+func (c *Catalog) Match(acceptLanguage string) string {
+	type choice struct {
+		tag string
+		q   float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{tag, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+
+	for _, ch := range choices {
+		for tag := ch.tag; tag != ""; {
+			if tag == SourceLanguage || tag == "*" {
+				return SourceLanguage
+			}
+			if _, ok := c.locales[tag]; ok {
+				return tag
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return SourceLanguage
+}
+
+// Translate returns message in lang, or message itself when the catalog
+// has no translation. A message of the form "<path>: <message>", as
+// validation errors about nested items are, has its second part translated.
+func (c *Catalog) Translate(lang, message string) string {
+	l, ok := c.locales[lang]
+	if !ok || message == "" {
+		return message
+	}
+	if translation, ok := l.exact[message]; ok {
+		return translation
+	}
+	for _, p := range l.patterns {
+		values := p.re.FindStringSubmatch(message)
+		if values == nil {
+			continue
+		}
+		out := p.translation
+		for i, name := range p.names {
+			out = strings.ReplaceAll(out, name, values[i+1])
+		}
+		return out
+	}
+	if path, rest, ok := strings.Cut(message, ": "); ok && !strings.Contains(path, " ") {
+		if translated := c.Translate(lang, rest); translated != rest {
+			return path + ": " + translated
+		}
+	}
+	return message
+}
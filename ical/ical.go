@@ -0,0 +1,413 @@
+// Package ical converts tasks to and from iCalendar VTODO components
+// (RFC 5545), for CalDAV clients.
+//
+// Due and start dates at midnight UTC are all-day dates here, so they are
+// written as DATE values, and DATE values are read back as midnight UTC.
+// Date-times are written in UTC; floating ones are read as UTC. Colors and
+// geographic positions use the RFC 7986 COLOR and RFC 5545 GEO properties.
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"to-do-api/models"
+)
+
+// ContentType is the media type of a VTODO resource
+const ContentType = "text/calendar; charset=utf-8; component=VTODO"
+
+// ProdID identifies this server in the calendar objects it writes
+const ProdID = "-//to-do-api//CalDAV//EN"
+
+// Date and date-time layouts
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+)
+
+// statuses maps task statuses to VTODO statuses
+var statuses = map[string]string{
+	"pending":     "NEEDS-ACTION",
+	"in_progress": "IN-PROCESS",
+	"completed":   "COMPLETED",
+}
+
+// Encode writes task as a calendar object holding one VTODO with the given
+// UID. DTSTAMP is the task's updated_at, so the output only changes when
+// the task does.
+func Encode(task *models.Task, uid string) []byte {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		writeFolded(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", ProdID)
+	line("BEGIN", "VTODO")
+	line("UID", escapeText(uid))
+	line("DTSTAMP", task.UpdatedAt.UTC().Format(utcLayout))
+	line("CREATED", task.CreatedAt.UTC().Format(utcLayout))
+	line("LAST-MODIFIED", task.UpdatedAt.UTC().Format(utcLayout))
+	line("SUMMARY", escapeText(task.Title))
+	if task.Description != "" {
+		line("DESCRIPTION", escapeText(task.Description))
+	}
+	if task.StartDate != nil {
+		writeFolded(&b, formatDate("DTSTART", *task.StartDate))
+	}
+	if task.DueDate != nil {
+		writeFolded(&b, formatDate("DUE", *task.DueDate))
+	}
+	line("STATUS", statuses[task.Status])
+	if task.Progress > 0 {
+		line("PERCENT-COMPLETE", strconv.Itoa(task.Progress))
+	}
+	if task.CompletedAt != nil {
+		line("COMPLETED", task.CompletedAt.UTC().Format(utcLayout))
+	}
+	if task.Color != "" {
+		line("COLOR", task.Color)
+	}
+	if task.Location != nil {
+		line("GEO", strconv.FormatFloat(task.Location.Latitude, 'f', -1, 64)+";"+strconv.FormatFloat(task.Location.Longitude, 'f', -1, 64))
+		if task.Location.Place != "" {
+			line("LOCATION", escapeText(task.Location.Place))
+		}
+	}
+	line("END", "VTODO")
+	line("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+// formatDate writes a date property, as a DATE value for midnight UTC
+func formatDate(name string, t time.Time) string {
+	t = t.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return name + ";VALUE=DATE:" + t.Format(dateLayout)
+	}
+	return name + ":" + t.Format(utcLayout)
+}
+
+// writeFolded writes a content line, folded so no line exceeds 75 octets
+// and no UTF-8 sequence is split
+func writeFolded(b *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// unescapeText reverses escapeText
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// Todo is a parsed VTODO. Properties the component lacks are left zero,
+// which a PUT takes as clearing them.
+type Todo struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       *time.Time
+	Due         *time.Time
+	// Status is the task status the VTODO status maps to, or "" when unset
+	Status   string
+	Progress *int
+	Color    string
+	// Geo is the latitude and longitude, and Place the LOCATION text
+	Geo   *[2]float64
+	Place string
+}
+
+// ErrNoTodo reports a calendar object without a VTODO, such as an event
+var ErrNoTodo = errors.New("the calendar object holds no VTODO")
+
+// property is one content line
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the first VTODO of a calendar object. Nested components
+// such as alarms are skipped.
+func Parse(data []byte) (*Todo, error) {
+	lines := unfold(string(data))
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, errors.New("the body is not an iCalendar object")
+	}
+
+	var todo *Todo
+	depth := 0
+	for _, line := range lines {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch prop.name {
+		case "BEGIN":
+			if todo == nil && depth == 1 && strings.EqualFold(prop.value, "VTODO") {
+				todo = &Todo{}
+			}
+			depth++
+			continue
+		case "END":
+			depth--
+			if todo != nil && depth == 1 {
+				return todo, nil
+			}
+			continue
+		}
+		// Only the VTODO's own properties, not those of its alarms
+		if todo == nil || depth != 2 {
+			continue
+		}
+		if err := todo.set(prop); err != nil {
+			return nil, fmt.Errorf("%s: %v", prop.name, err)
+		}
+	}
+	if todo != nil {
+		return nil, errors.New("the VTODO is not closed")
+	}
+	return nil, ErrNoTodo
+}
+
+// set applies one VTODO property
+func (t *Todo) set(prop property) error {
+	switch prop.name {
+	case "UID":
+		t.UID = unescapeText(prop.value)
+	case "SUMMARY":
+		t.Summary = unescapeText(prop.value)
+	case "DESCRIPTION":
+		t.Description = unescapeText(prop.value)
+	case "DTSTART", "DUE":
+		v, err := parseDate(prop)
+		if err != nil {
+			return err
+		}
+		if prop.name == "DUE" {
+			t.Due = &v
+		} else {
+			t.Start = &v
+		}
+	case "STATUS":
+		switch strings.ToUpper(prop.value) {
+		case "NEEDS-ACTION":
+			t.Status = "pending"
+		case "IN-PROCESS":
+			t.Status = "in_progress"
+		case "COMPLETED", "CANCELLED":
+			// Tasks can't be cancelled; cancelled ones are done with
+			t.Status = "completed"
+		}
+	case "PERCENT-COMPLETE":
+		n, err := strconv.Atoi(strings.TrimSpace(prop.value))
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		t.Progress = &n
+	case "COLOR":
+		t.Color = strings.TrimSpace(prop.value)
+	case "GEO":
+		parts := strings.Split(prop.value, ";")
+		if len(parts) != 2 {
+			return errors.New("must be latitude;longitude")
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lon, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err1 != nil || err2 != nil {
+			return errors.New("must be latitude;longitude")
+		}
+		t.Geo = &[2]float64{lat, lon}
+	case "LOCATION":
+		t.Place = unescapeText(prop.value)
+	}
+	return nil
+}
+
+// TaskLocation returns the location to store. Tasks only have a location
+// with coordinates, so a place name without GEO is dropped.
+func (t *Todo) TaskLocation() *models.Location {
+	if t.Geo == nil {
+		return nil
+	}
+	return &models.Location{Latitude: t.Geo[0], Longitude: t.Geo[1], Place: t.Place}
+}
+
+// Request returns the request that creates a task from the VTODO
+func (t *Todo) Request() models.TaskRequest {
+	return models.TaskRequest{
+		Title:       t.Summary,
+		Description: t.Description,
+		StartDate:   t.Start,
+		DueDate:     t.Due,
+		Status:      t.status(),
+		Progress:    t.Progress,
+		Color:       t.color(),
+		Location:    t.TaskLocation(),
+	}
+}
+
+// Patch returns the patch that makes a task match the VTODO. Properties the
+// VTODO lacks are cleared, except the color, which is kept when the VTODO
+// has none this API accepts.
+func (t *Todo) Patch() models.TaskPatch {
+	title, description, status := t.Summary, t.Description, t.status()
+	progress := 0
+	if t.Progress != nil {
+		progress = *t.Progress
+	}
+	patch := models.TaskPatch{
+		Title:       &title,
+		Description: &description,
+		StartDate:   models.OptionalTime{Set: true, Value: t.Start},
+		DueDate:     models.OptionalTime{Set: true, Value: t.Due},
+		Status:      &status,
+		Progress:    &progress,
+		Location:    models.OptionalLocation{Set: true, Value: t.TaskLocation()},
+	}
+	if color := t.color(); color != "" {
+		patch.Color = &color
+	}
+	return patch
+}
+
+// status defaults a VTODO without STATUS to pending
+func (t *Todo) status() string {
+	if t.Status == "" {
+		return "pending"
+	}
+	return t.Status
+}
+
+// hexColor matches the hex colors tasks accept
+var hexColor = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// color returns the VTODO's color when tasks accept it, else "". Clients
+// may send any CSS color name, and those outside the palette are dropped.
+func (t *Todo) color() string {
+	color := strings.ToLower(t.Color)
+	for _, name := range models.ColorPalette {
+		if color == name {
+			return color
+		}
+	}
+	if hexColor.MatchString(color) {
+		return color
+	}
+	return ""
+}
+
+// parseDate reads a DATE or DATE-TIME value, honouring TZID
+func parseDate(prop property) (time.Time, error) {
+	v := strings.TrimSpace(prop.value)
+	if strings.EqualFold(prop.params["VALUE"], "DATE") || len(v) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return time.Time{}, errors.New("must be a date such as 20240105")
+		}
+		return t, nil
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse(utcLayout, v)
+		if err != nil {
+			return time.Time{}, errors.New("must be a date-time such as 20240105T100000Z")
+		}
+		return t, nil
+	}
+	loc := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, v, loc)
+	if err != nil {
+		return time.Time{}, errors.New("must be a date-time such as 20240105T100000Z")
+	}
+	return t.UTC(), nil
+}
+
+// unfold splits a calendar object into content lines, joining folded ones
+func unfold(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var lines []string
+	for _, raw := range strings.Split(s, "\n") {
+		if raw == "" {
+			continue
+		}
+		if (raw[0] == ' ' || raw[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += raw[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(raw, "\r"))
+	}
+	return lines
+}
+
+// parseLine splits a content line into name, parameters and value
+func parseLine(line string) (property, error) {
+	// The value starts at the first colon outside a quoted parameter
+	colon := -1
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		if line[i] == '"' {
+			quoted = !quoted
+		} else if line[i] == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("invalid content line %q", line)
+	}
+	parts := strings.Split(line[:colon], ";")
+	prop := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if eq := strings.IndexByte(param, '='); eq > 0 {
+			prop.params[strings.ToUpper(param[:eq])] = param[eq+1:]
+		}
+	}
+	return prop, nil
+}
@@ -0,0 +1,351 @@
+// Package jsonpatch applies JSON Patch documents (RFC 6902) to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MediaType is the media type of JSON Patch documents
+const MediaType = "application/json-patch+json"
+
+// Operation is one step of a patch. Value is only used by add, replace
+// and test, and From by move and copy.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is a JSON Patch document, applied in order
+type Patch []Operation
+
+// ErrTestFailed means a test operation found a different value
+var ErrTestFailed = errors.New("test operation failed")
+
+// Error reports the operation a patch failed at
+type Error struct {
+	Index int
+	Op    Operation
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %v", e.Index, e.Op.Op, e.Op.Path, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Decode reads a patch document and checks that every operation is well
+// formed, before anything is applied
+func Decode(data []byte) (Patch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.New("a JSON Patch must be an array of operations")
+	}
+	patch := make(Patch, len(raw))
+	for i, fields := range raw {
+		op := &patch[i]
+		if err := decodeString(fields, "op", &op.Op); err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+		if err := decodeString(fields, "path", &op.Path); err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			value, ok := fields["value"]
+			if !ok {
+				return nil, fmt.Errorf("operation %d: %s needs a value", i, op.Op)
+			}
+			op.Value = value
+		case "remove":
+		case "move", "copy":
+			if err := decodeString(fields, "from", &op.From); err != nil {
+				return nil, fmt.Errorf("operation %d: %v", i, err)
+			}
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("operation %d: %v", i, err)
+			}
+			if op.Op == "move" && strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, fmt.Errorf("operation %d: a value can't be moved into itself", i)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", i, op.Op)
+		}
+	}
+	return patch, nil
+}
+
+// decodeString reads a required string member of an operation
+func decodeString(fields map[string]json.RawMessage, name string, dest *string) error {
+	value, ok := fields[name]
+	if !ok {
+		return fmt.Errorf("%s is required", name)
+	}
+	if err := json.Unmarshal(value, dest); err != nil {
+		return fmt.Errorf("%s must be a string", name)
+	}
+	return nil
+}
+
+// Apply applies the patch to a JSON document and returns the result. It
+// stops at the first failing operation and returns an *Error, wrapping
+// ErrTestFailed when a test did not match.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	value, err := decodeValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if value, err = apply(value, op); err != nil {
+			return nil, &Error{Index: i, Op: op, Err: err}
+		}
+	}
+	return json.Marshal(value)
+}
+
+// decodeValue decodes JSON keeping numbers exact
+func decodeValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// apply runs one operation and returns the new document
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if _, err := get(doc, path); err != nil {
+				return nil, err
+			}
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	case "remove":
+		return remove(doc, path)
+	}
+
+	from, _ := parsePointer(op.From)
+	value, err := get(doc, from)
+	if err != nil {
+		return nil, err
+	}
+	if op.Op == "move" {
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+	} else {
+		// A copy must not share maps or slices with its source
+		data, _ := json.Marshal(value)
+		value, _ = decodeValue(data)
+	}
+	return add(doc, path, value)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must be empty or start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// get returns the value at path
+func get(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			current = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("%q does not exist", token)
+		}
+	}
+	return current, nil
+}
+
+// add sets the value at path, inserting into arrays, and returns the new
+// document
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		i := len(node)
+		if last != "-" {
+			if i, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return setParent(doc, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("can't add %q to a value that is not an object or array", last)
+}
+
+// remove deletes the value at path and returns the new document
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("the whole document can't be removed")
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("member %q does not exist", last)
+		}
+		delete(node, last)
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:i:i], node[i+1:]...)
+		return setParent(doc, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("%q does not exist", last)
+}
+
+// setParent stores a grown or shrunk array back at path
+func setParent(doc interface{}, path []string, array []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+	parent, _ := get(doc, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = array
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		node[i] = array
+	}
+	return doc, nil
+}
+
+// arrayIndex parses an array index token no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("array index %d is out of range", i)
+	}
+	return i, nil
+}
+
+// equal compares JSON values as test requires: numbers by value, objects
+// regardless of member order
+func equal(a, b interface{}) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		if aerr == nil && berr == nil {
+			return af == bf
+		}
+		return an == bn
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			other, ok := bv[k]
+			if !ok || !equal(v, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -2,31 +2,160 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 	"to-do-api/database"
+	"to-do-api/diagnostics"
+	"to-do-api/events"
 	"to-do-api/handlers"
+	"to-do-api/i18n"
+	"to-do-api/mcp"
 	"to-do-api/middleware"
 	"to-do-api/models"
+	"to-do-api/openapi"
+	"to-do-api/recurring"
+	"to-do-api/schemas"
+	"to-do-api/webhooks"
 
 	"github.com/gorilla/mux"
+	bolt "go.etcd.io/bbolt"
 )
 
 func main() {
-	// Initialize database
-	db, err := database.InitDB()
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Admin subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "anonymize-db" {
+		anonymizeDB(os.Args[2:])
+		return
+	}
+	// "mcp" serves the Model Context Protocol on stdin and stdout instead of
+	// HTTP, for assistants that start the server as a subprocess
+	mcpStdio := len(os.Args) > 1 && os.Args[1] == "mcp"
+
+	// Live checks served at /api/admin/diagnostics
+	diag := diagnostics.NewRunner()
+
+	// Initialize the storage backend selected by STORAGE_BACKEND (sqlite or bolt)
+	var storage models.TaskRepository
+	// Copies the local SQLite database for GET /api/admin/backup?format=sqlite
+	var sqliteSnapshot func(destPath string) error
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "sqlite":
+		db, err := database.InitDB()
+		if err != nil {
+			log.Fatalf("Failed to initialize database: %v", err)
+		}
+		defer database.CloseDB(db)
+		sqliteRepo := models.NewSQLiteTaskRepository(db)
+		storage = sqliteRepo
+		diag.Add(diagnostics.Database(db.Ping))
+		diag.Add(diagnostics.Schema(sqliteRepo.CheckSchema))
+		if os.Getenv("LIBSQL_URL") == "" {
+			diag.Add(diagnostics.WALSize(database.Path()))
+			sqliteSnapshot = func(destPath string) error { return database.Snapshot(db, destPath) }
+		}
+	case "bolt":
+		db, err := database.InitBolt()
+		if err != nil {
+			log.Fatalf("Failed to initialize bolt database: %v", err)
+		}
+		defer database.CloseBolt(db)
+		storage = models.NewBoltTaskRepository(db)
+		diag.Add(diagnostics.Database(func() error {
+			return db.View(func(*bolt.Tx) error { return nil })
+		}))
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q", backend)
 	}
-	defer database.CloseDB(db)
 
 	// Initialize repository and handlers
-	taskRepo := models.NewSQLiteTaskRepository(db)
+	// Identical concurrent list reads share a single query, and every write
+	// is published on the event bus
+	eventBus := events.NewBus()
+	diag.Add(diagnostics.EventBus(eventBus))
+	taskRepo := events.NewPublishingTaskRepository(models.NewCoalescingTaskRepository(storage), eventBus)
 	taskHandler := handlers.NewTaskHandler(taskRepo)
+	if sqliteSnapshot != nil {
+		taskHandler.SetSQLiteSnapshot(sqliteSnapshot)
+	}
+
+	// Publish the webhook signing keys so receivers can verify deliveries
+	webhookKeys, err := webhooks.ParseKeySet(os.Getenv("WEBHOOK_SIGNING_KEYS"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_SIGNING_KEYS: %v", err)
+	}
+	taskHandler.SetWebhookKeys(webhookKeys)
+
+	// Workspace policy for client-side encrypted task content
+	encryptionMode, err := models.ParseEncryptionMode(os.Getenv("ENCRYPTION_MODE"))
+	if err != nil {
+		log.Fatalf("Invalid ENCRYPTION_MODE: %v", err)
+	}
+	taskHandler.SetEncryptionMode(encryptionMode)
+
+	// Optional cap on the number of tasks, with warnings from 90%
+	taskHandler.SetEventBus(eventBus)
+	if v := os.Getenv("TASK_QUOTA"); v != "" {
+		quota, err := strconv.Atoi(v)
+		if err != nil || quota < 0 {
+			log.Fatalf("Invalid TASK_QUOTA %q: must be a non-negative number", v)
+		}
+		taskHandler.SetTaskQuota(quota)
+	}
+
+	// Refuse task writes that don't say which version they overwrite
+	if require, _ := strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH")); require {
+		taskHandler.SetRequireIfMatch(true)
+	}
+
+	// Replay responses to retried creates that carry an Idempotency-Key
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		ttl, ok := models.ParseDayDuration(v)
+		if !ok || ttl < 0 {
+			log.Fatalf("Invalid IDEMPOTENCY_TTL %q: must be a duration such as 24h or 1d, or 0 to turn it off", v)
+		}
+		taskHandler.SetIdempotencyTTL(ttl)
+	}
+
+	// Cap concurrent Server-Sent Events clients
+	if v := os.Getenv("SSE_MAX_CLIENTS"); v != "" {
+		max, err := strconv.Atoi(v)
+		if err != nil || max < 0 {
+			log.Fatalf("Invalid SSE_MAX_CLIENTS %q: must be a non-negative number", v)
+		}
+		taskHandler.SetMaxEventStreams(max)
+	}
+
+	// Materialize upcoming occurrences of recurring schedules in the background
+	horizon := recurring.DefaultHorizon
+	if v := os.Getenv("RECURRING_HORIZON"); v != "" {
+		d, ok := models.ParseDayDuration(v)
+		if !ok || d <= 0 {
+			log.Fatalf("Invalid RECURRING_HORIZON %q: must be a positive duration such as 14d or 72h", v)
+		}
+		horizon = d
+	}
+	generator := recurring.NewGenerator(taskRepo, horizon, 0)
+	taskHandler.SetRecurringGenerator(generator)
+	generatorCtx, stopGenerator := context.WithCancel(context.Background())
+	go generator.Run(generatorCtx)
+
+	// Deliver task events to registered webhooks in the background
+	dispatcher := webhooks.NewDispatcher(taskRepo, eventBus, webhookKeys)
+	taskHandler.SetWebhookDispatcher(dispatcher)
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	go dispatcher.Run(dispatcherCtx)
+
+	// Optionally prime the database page cache in the background
+	if warm, _ := strconv.ParseBool(os.Getenv("CACHE_WARMING")); warm {
+		go warmPageCache(taskRepo)
+	}
 
 	// Create router
 	router := mux.NewRouter()
@@ -34,31 +163,180 @@ func main() {
 	// Apply middleware
 	router.Use(middleware.CORS)
 	router.Use(middleware.Logging)
-	router.Use(middleware.Gzip)
+	router.Use(middleware.Compress)
+	router.Use(middleware.ContentNegotiation)
+	router.Use(middleware.UTF8)
+
+	// Serialize timestamps in the configured format (TIMESTAMP_FORMAT)
+	timestampFormat, err := middleware.TimestampFormat(os.Getenv("TIMESTAMP_FORMAT"))
+	if err != nil {
+		log.Fatalf("Invalid TIMESTAMP_FORMAT: %v", err)
+	}
+	router.Use(timestampFormat)
+
+	// Error bodies as JSON:API error documents for JSON:API clients
+	router.Use(middleware.JSONAPIErrors)
+
+	// Success bodies with or without the {message, data} wrapper
+	// (RESPONSE_ENVELOPE, overridden per request by ?envelope=)
+	envelope := true
+	if v := os.Getenv("RESPONSE_ENVELOPE"); v != "" {
+		if envelope, err = strconv.ParseBool(v); err != nil {
+			log.Fatalf("Invalid RESPONSE_ENVELOPE: %v", err)
+		}
+	}
+	router.Use(middleware.Envelope(envelope))
+
+	// Messages in the client's Accept-Language, from the built-in locales
+	// and any in LOCALES_DIR
+	catalog, err := i18n.New()
+	if err != nil {
+		log.Fatalf("Failed to load locales: %v", err)
+	}
+	if dir := os.Getenv("LOCALES_DIR"); dir != "" {
+		if err := catalog.LoadDir(dir); err != nil {
+			log.Fatalf("Failed to load LOCALES_DIR: %v", err)
+		}
+	}
+	router.Use(middleware.Localize(catalog))
+
+	// Deprecation, Sunset and Link headers on routes slated for removal
+	router.Use(middleware.Deprecations(openapi.Deprecations))
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
-	
+
+	// Optionally validate request bodies against the published JSON Schemas
+	if validate, _ := strconv.ParseBool(os.Getenv("SCHEMA_VALIDATION")); validate {
+		schemaValidation, err := middleware.SchemaValidation(schemas.RequestBodies)
+		if err != nil {
+			log.Fatalf("Failed to compile schemas: %v", err)
+		}
+		api.Use(schemaValidation)
+	}
+
 	// Task routes
-	api.HandleFunc("/tasks", taskHandler.CreateTask).Methods("POST")
+	api.HandleFunc("/tasks", taskHandler.Idempotent(taskHandler.CreateTask)).Methods("POST")
 	api.HandleFunc("/tasks", taskHandler.GetTasks).Methods("GET")
+	api.HandleFunc("/tasks/overdue", taskHandler.GetOverdueTasks).Methods("GET")
+	api.HandleFunc("/tasks/today", taskHandler.GetTodayTasks).Methods("GET")
+	api.HandleFunc("/tasks/upcoming", taskHandler.GetUpcomingTasks).Methods("GET")
+	api.HandleFunc("/tasks/export.md", taskHandler.ExportMarkdown).Methods("GET")
+	api.HandleFunc("/planner/today", taskHandler.GetPlannerToday).Methods("GET")
+	api.HandleFunc("/feed.atom", taskHandler.GetActivityFeed).Methods("GET")
+	api.HandleFunc("/board", taskHandler.GetBoard).Methods("GET")
+	api.HandleFunc("/changes", taskHandler.GetChanges).Methods("GET")
+	api.HandleFunc("/stats", taskHandler.GetStats).Methods("GET")
+	api.HandleFunc("/stats/completions", taskHandler.GetCompletionStats).Methods("GET")
+	api.HandleFunc("/tasks", taskHandler.BulkDeleteTasks).Methods("DELETE")
+	api.HandleFunc("/tasks/bulk", taskHandler.Idempotent(taskHandler.BulkCreateTasks)).Methods("POST")
+	api.HandleFunc("/tasks/import", taskHandler.ImportTasks).Methods("POST")
+	api.HandleFunc("/tasks/import/todoist", taskHandler.ImportTodoist).Methods("POST")
+	api.HandleFunc("/tasks/bulk", taskHandler.BulkUpdateTasks).Methods("PATCH")
+	api.HandleFunc("/tasks/archive-completed", taskHandler.ArchiveCompletedTasks).Methods("POST")
+	api.HandleFunc("/tasks/transition", taskHandler.TransitionTasks).Methods("POST")
+	api.HandleFunc("/tasks/complete-all", taskHandler.CompleteAllTasks).Methods("POST")
+	api.HandleFunc("/undo", taskHandler.Undo).Methods("POST")
+	api.HandleFunc("/batch", taskHandler.Idempotent(taskHandler.Batch)).Methods("POST")
+	api.HandleFunc("/sync", taskHandler.Sync).Methods("POST")
+	api.HandleFunc("/tasks/external/{source}/{externalID}", taskHandler.UpsertExternalTask).Methods("PUT")
+	api.HandleFunc("/tasks/uuid/{uuid}", taskHandler.GetTaskByUUID).Methods("GET")
 	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.GetTask).Methods("GET")
 	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.UpdateTask).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.PatchTask).Methods("PATCH")
 	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.DeleteTask).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/move", taskHandler.MoveTask).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/duplicate", taskHandler.Idempotent(taskHandler.DuplicateTask)).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/pin", taskHandler.TogglePinTask).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/snooze", taskHandler.SnoozeTask).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links", taskHandler.ListTaskLinks).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links", taskHandler.CreateTaskLink).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}", taskHandler.UpdateTaskLink).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}", taskHandler.DeleteTaskLink).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/notes", taskHandler.ListTaskNotes).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/notes", taskHandler.AddTaskNote).Methods("POST")
+	api.HandleFunc("/tasks/reorder", taskHandler.ReorderTasks).Methods("PUT")
+
+	// Recurring task schedules
+	api.HandleFunc("/schedules", taskHandler.ListSchedules).Methods("GET")
+	api.HandleFunc("/schedules", taskHandler.CreateSchedule).Methods("POST")
+	api.HandleFunc("/schedules/{id:[0-9]+}", taskHandler.GetSchedule).Methods("GET")
+	api.HandleFunc("/schedules/{id:[0-9]+}", taskHandler.DeleteSchedule).Methods("DELETE")
+
+	// Schema routes
+	api.HandleFunc("/schemas", taskHandler.ListSchemas).Methods("GET")
+	api.HandleFunc("/schemas/{name}", taskHandler.GetSchema).Methods("GET")
+
+	// Webhook routes
+	api.HandleFunc("/webhooks/keys", taskHandler.GetWebhookKeys).Methods("GET")
+	api.HandleFunc("/webhooks", taskHandler.ListWebhooks).Methods("GET")
+	api.HandleFunc("/webhooks", taskHandler.CreateWebhook).Methods("POST")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", taskHandler.GetWebhook).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", taskHandler.DeleteWebhook).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", taskHandler.ListWebhookDeliveries).Methods("GET")
+
+	// Live task events
+	api.HandleFunc("/ws", taskHandler.TaskEventsWebSocket).Methods("GET")
+	api.HandleFunc("/events", taskHandler.TaskEventsStream).Methods("GET")
+
+	// Workspace settings
+	api.HandleFunc("/workspace", taskHandler.GetWorkspace).Methods("GET")
+
+	// Operational runbook checks for monitoring
+	api.Handle("/admin/diagnostics", diag.Handler()).Methods("GET")
+	api.HandleFunc("/admin/backup", taskHandler.GetBackup).Methods("GET")
+	api.HandleFunc("/admin/restore", taskHandler.RestoreBackup).Methods("POST")
+
+	// OpenAPI document generated from the routes, with Swagger UI
+	api.Handle("/openapi.json", openapi.Handler(router, schemas.RequestBodies)).Methods("GET")
+	router.Handle("/docs", openapi.DocsHandler("/api/openapi.json")).Methods("GET")
 
 	// Health check route
 	router.HandleFunc("/health", taskHandler.HealthCheck).Methods("GET")
 
+	// Runtime metrics (including request coalescing hits/misses)
+	router.Handle("/debug/vars", expvar.Handler()).Methods("GET")
+
+	// Event bus subscribers with their lag and dropped events
+	router.Handle("/debug/events", eventBus.Handler()).Methods("GET")
+
 	// Static file serving
 	staticFS := http.FileServer(http.Dir("./static"))
-	router.PathPrefix("/static/").Handler(middleware.WithCacheControl(http.StripPrefix("/static/", staticFS), "public, max-age=604800, immutable"))
-	
+	router.PathPrefix("/static/").Handler(middleware.WithCacheControl(http.StripPrefix("/static/", staticFS), "public, max-age=604800, immutable")).Methods("GET")
+
 	// Root route serves the frontend
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		http.ServeFile(w, r, "./static/index.html")
 	}).Methods("GET")
 
+	// Batch operations go through the router like any other request. Every
+	// other way in waits while a batch writes, as storage then runs every
+	// call in the batch's transaction.
+	taskHandler.SetBatchRouter(router)
+
+	// CalDAV sits beside the API router: it has its own methods, OPTIONS
+	// answers and XML bodies, which the API middleware would get in the way of
+	root := http.NewServeMux()
+	root.Handle("/caldav/", middleware.Logging(taskHandler.BatchLock(http.HandlerFunc(taskHandler.CalDAV))))
+	root.Handle("/.well-known/caldav", http.RedirectHandler("/caldav/", http.StatusMovedPermanently))
+	root.Handle("/", taskHandler.BatchLock(middleware.Methods(router)))
+
+	// AI assistants manage tasks as Model Context Protocol tools, over SSE
+	// at /mcp/sse or, with the mcp subcommand, over stdio
+	mcpServer := mcp.NewServer(taskHandler.BatchLock(router))
+	root.Handle("/mcp/", middleware.Logging(mcpServer.SSEHandler("/mcp")))
+	if mcpStdio {
+		// Logs go to stderr, leaving stdout to the protocol
+		log.Println("Serving MCP on stdio")
+		if err := mcpServer.ServeStdio(context.Background(), os.Stdin, os.Stdout); err != nil {
+			log.Printf("MCP stdio failed: %v", err)
+		}
+		stopGenerator()
+		stopDispatcher()
+		return
+	}
+
 	// Get port from environment variable or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -68,7 +346,7 @@ func main() {
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + port,
-		Handler:      router,
+		Handler:      root,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -79,7 +357,7 @@ func main() {
 		log.Printf("Server starting on port %s", port)
 		log.Printf("Health check: http://localhost:%s/health", port)
 		log.Printf("UI: http://localhost:%s/", port)
-		
+
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
@@ -90,6 +368,8 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopGenerator()
+	stopDispatcher()
 
 	// Create a deadline to wait for
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -102,3 +382,61 @@ func main() {
 
 	log.Println("Server exited")
 }
+
+// warmPageCache runs the queries behind the default UI views once after
+// boot, with the handlers' defaults, so their first real requests find the
+// pages they read in SQLite's page cache and the OS file cache: the first
+// page of the task list, each board column, the today and upcoming agenda
+// in the server's time zone, and the stats, each with its count. The
+// results are discarded; nothing caches responses, so every request still
+// runs its queries and only the disk reads are saved.
+func warmPageCache(repo models.TaskRepository) {
+	start := time.Now()
+	unarchived := false
+	today := time.Now().Truncate(time.Second)
+	tomorrow := time.Date(today.Year(), today.Month(), today.Day()+1, 0, 0, 0, 0, today.Location())
+	endOfToday := tomorrow.Add(-time.Nanosecond)
+	endOfWeek := tomorrow.AddDate(0, 0, 7).Add(-time.Nanosecond)
+	views := []struct {
+		filter models.TaskFilter
+		sortBy string
+		order  string
+	}{
+		{models.TaskFilter{Archived: &unarchived}, "created_at", "desc"},
+		{models.TaskFilter{Archived: &unarchived, Statuses: []string{"pending"}}, "position", "asc"},
+		{models.TaskFilter{Archived: &unarchived, Statuses: []string{"in_progress"}}, "position", "asc"},
+		{models.TaskFilter{Archived: &unarchived, Statuses: []string{"completed"}}, "position", "asc"},
+		{models.TaskFilter{Archived: &unarchived, Open: true, DueBefore: &endOfToday}, "due_date", "asc"},
+		{models.TaskFilter{Archived: &unarchived, Open: true, DueAfter: &tomorrow, DueBefore: &endOfWeek}, "due_date", "asc"},
+	}
+	for _, view := range views {
+		if _, err := repo.GetAllPaginated(view.filter, 50, 0, view.sortBy, view.order); err != nil {
+			log.Printf("Page cache warm-up failed: %v", err)
+			return
+		}
+		if _, err := repo.Count(view.filter); err != nil {
+			log.Printf("Page cache warm-up failed: %v", err)
+			return
+		}
+	}
+	if _, err := repo.Stats(today); err != nil {
+		log.Printf("Page cache warm-up failed: %v", err)
+		return
+	}
+	log.Printf("Page cache warm-up completed in %s", time.Since(start))
+}
+
+// anonymizeDB implements "anonymize-db -out <path>": it copies the SQLite
+// database at DB_PATH to path with titles, descriptions, links and notes
+// scrambled, for attaching to performance bug reports
+func anonymizeDB(args []string) {
+	flags := flag.NewFlagSet("anonymize-db", flag.ExitOnError)
+	out := flags.String("out", "tasks-anonymized.db", "path of the anonymized copy; must not exist")
+	flags.Parse(args)
+
+	dbPath := database.Path()
+	if err := database.Anonymize(dbPath, *out); err != nil {
+		log.Fatalf("Failed to anonymize database: %v", err)
+	}
+	log.Printf("Anonymized copy of %s written to %s", dbPath, *out)
+}
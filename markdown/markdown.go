@@ -0,0 +1,211 @@
+// Package markdown renders the small Markdown subset used in task
+// descriptions to HTML that is safe to embed in a page.
+//
+// Supported syntax: ATX headings, paragraphs, block quotes, bullet and
+// numbered lists, fenced code blocks, horizontal rules, **strong**,
+// *emphasis*, `code` and [links](https://example.com). All source text is
+// HTML-escaped and the renderer only ever emits its own tags, so output is
+// sanitized by construction. Links are kept only for http, https and mailto
+// URLs; other schemes (javascript:, data:, ...) render as plain text.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletPattern  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^\s*\d{1,9}[.)]\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+)
+
+// Render converts Markdown source to sanitized HTML
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return b.String()
+}
+
+// renderBlocks renders a sequence of lines as block-level elements
+func renderBlocks(b *strings.Builder, lines []string) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+		case rulePattern.MatchString(trimmed):
+			flush()
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case bulletPattern.MatchString(line):
+			flush()
+			i = renderList(b, lines, i, bulletPattern, "ul")
+
+		case orderedPattern.MatchString(line):
+			flush()
+			i = renderList(b, lines, i, orderedPattern, "ol")
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+}
+
+// renderList renders consecutive items matching pattern starting at lines[i]
+// and returns the index of the last line consumed
+func renderList(b *strings.Builder, lines []string, i int, pattern *regexp.Regexp, tag string) int {
+	b.WriteString("<" + tag + ">\n")
+	for ; i < len(lines); i++ {
+		m := pattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		b.WriteString("<li>" + renderInline(m[1]) + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i - 1
+}
+
+// renderInline renders emphasis, code spans and links within a block
+func renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__"):
+			if n, inner := delimited(s, i, s[i:i+2]); n > 0 {
+				b.WriteString("<strong>" + renderInline(inner) + "</strong>")
+				i += n
+				continue
+			}
+
+		case s[i] == '*' || s[i] == '_':
+			if n, inner := delimited(s, i, s[i:i+1]); n > 0 {
+				b.WriteString("<em>" + renderInline(inner) + "</em>")
+				i += n
+				continue
+			}
+
+		case s[i] == '[':
+			if n, text, href := link(s[i:]); n > 0 {
+				if safeURL(href) {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">` + renderInline(text) + "</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+	return b.String()
+}
+
+// delimited finds a non-empty span wrapped in delim starting at s[i]. It
+// returns the number of bytes consumed, or 0 when the span does not close.
+// Underscores inside words (snake_case) never open emphasis.
+func delimited(s string, i int, delim string) (int, string) {
+	if delim[0] == '_' && i > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(s[:i]); unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return 0, ""
+		}
+	}
+	start := i + len(delim)
+	end := strings.Index(s[start:], delim)
+	if end <= 0 || strings.TrimSpace(s[start:start+end]) != s[start:start+end] {
+		return 0, ""
+	}
+	return len(delim)*2 + end, s[start : start+end]
+}
+
+// link parses "[text](href)" at the start of s
+func link(s string) (int, string, string) {
+	closeText := strings.Index(s, "](")
+	if closeText < 1 {
+		return 0, "", ""
+	}
+	// Parentheses inside the URL must balance, as in CommonMark
+	depth := 0
+	for j := closeText + 2; j < len(s); j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return j + 1, s[1:closeText], strings.TrimSpace(s[closeText+2 : j])
+			}
+			depth--
+		}
+	}
+	return 0, "", ""
+}
+
+// safeURL reports whether href may be emitted as a link target
+func safeURL(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+// Package mcp serves the task API to AI assistants over the Model Context
+// Protocol. Task CRUD, search and stats are exposed as tools; a tool call
+// is sent through the API router like any other request, so validation,
+// events, webhooks and undo behave exactly as for HTTP clients.
+//
+// Messages are JSON-RPC 2.0, carried over stdio (ServeStdio) or the
+// HTTP+SSE transport (SSEHandler).
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"to-do-api/openapi"
+)
+
+// ProtocolVersion is the MCP revision implemented
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers MCP messages by calling the API
+type Server struct {
+	api http.Handler
+}
+
+// NewServer returns a server that runs tool calls against api, normally
+// the API's own router
+func NewServer(api http.Handler) *Server {
+	return &Server{api: api}
+}
+
+// request is a JSON-RPC request, or a notification when ID is absent
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handle answers a message, a single request or a batch of them. It
+// returns nil when nothing is to be sent back, as for notifications.
+func (s *Server) Handle(ctx context.Context, msg []byte) []byte {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) > 0 && msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err != nil {
+			return encode(errorResponse(nil, codeParseError, "Parse error"))
+		}
+		if len(batch) == 0 {
+			return encode(errorResponse(nil, codeInvalidRequest, "Empty batch"))
+		}
+		var replies []*response
+		for _, item := range batch {
+			if reply := s.handleOne(ctx, item); reply != nil {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			return nil
+		}
+		return encode(replies)
+	}
+	if reply := s.handleOne(ctx, msg); reply != nil {
+		return encode(reply)
+	}
+	return nil
+}
+
+// handleOne answers one request; notifications get nil
+func (s *Server) handleOne(ctx context.Context, msg []byte) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return errorResponse(nil, codeParseError, "Parse error")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "Invalid request")
+	}
+	notification := len(req.ID) == 0
+
+	var result interface{}
+	var rerr *rpcError
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "to-do-api", "version": openapi.Version},
+		}
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": tools}
+	case "tools/call":
+		result, rerr = s.callTool(ctx, req.Params)
+	default:
+		// Notifications such as notifications/initialized need no answer
+		if notification {
+			return nil
+		}
+		rerr = &rpcError{Code: codeMethodNotFound, Message: "Method not found: " + req.Method}
+	}
+	if notification {
+		return nil
+	}
+	if rerr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+func encode(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,276 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// tool is an MCP tool backed by one API request
+type tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	// route turns the call's arguments into the API request to send
+	route func(args map[string]interface{}) (method, path string, body map[string]interface{}, err error)
+}
+
+// Property schemas shared by the tools
+var (
+	idProperty     = map[string]interface{}{"type": "integer", "minimum": 1, "description": "Task ID"}
+	statusProperty = map[string]interface{}{"type": "string", "enum": []string{"pending", "in_progress", "completed"}}
+	dateProperty   = map[string]interface{}{"type": "string", "description": "RFC 3339 date-time, such as 2025-06-30T17:00:00Z"}
+	limitProperty  = map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 100, "description": "Maximum number of tasks to return (default 50)"}
+)
+
+// taskFields are the writable task fields, as create and update take them
+var taskFields = map[string]interface{}{
+	"title":       map[string]interface{}{"type": "string"},
+	"description": map[string]interface{}{"type": "string"},
+	"status":      statusProperty,
+	"start_date":  dateProperty,
+	"due_date":    dateProperty,
+	"progress":    map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100, "description": "Percent done"},
+	"color":       map[string]interface{}{"type": "string", "description": "Palette color name"},
+}
+
+// tools are the operations offered to assistants
+var tools = []tool{
+	{
+		Name:        "list_tasks",
+		Description: "List tasks, pinned first, newest first by default. Archived tasks are left out unless include_archived is set.",
+		InputSchema: objectSchema(map[string]interface{}{
+			"status":           map[string]interface{}{"type": "string", "description": "Only tasks with this status, or several separated by commas"},
+			"due_within":       map[string]interface{}{"type": "string", "description": "Only open tasks due within this duration from now, such as 48h or 7d"},
+			"filter":           map[string]interface{}{"type": "string", "description": "Filter expression, such as status:pending AND (due<2025-07-01 OR pinned:true)"},
+			"include_archived": map[string]interface{}{"type": "boolean"},
+			"sort_by":          map[string]interface{}{"type": "string", "enum": []string{"created_at", "title", "status", "due_date", "position"}},
+			"sort_order":       map[string]interface{}{"type": "string", "enum": []string{"asc", "desc"}},
+			"limit":            limitProperty,
+			"offset":           map[string]interface{}{"type": "integer", "minimum": 0},
+		}),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			return http.MethodGet, "/api/tasks" + query(args, "status", "due_within", "filter", "include_archived", "sort_by", "sort_order", "limit", "offset"), nil, nil
+		},
+	},
+	{
+		Name:        "search_tasks",
+		Description: "Find tasks whose title or description contains the query, ignoring case.",
+		InputSchema: objectSchema(map[string]interface{}{
+			"query":  map[string]interface{}{"type": "string", "minLength": 1},
+			"status": map[string]interface{}{"type": "string", "description": "Only tasks with this status, or several separated by commas"},
+			"limit":  limitProperty,
+		}, "query"),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			q, _ := args["query"].(string)
+			if q == "" {
+				return "", "", nil, errors.New("query is required")
+			}
+			args = withProperty(args, "q", q)
+			return http.MethodGet, "/api/tasks" + query(args, "q", "status", "limit"), nil, nil
+		},
+	},
+	{
+		Name:        "get_task",
+		Description: "Get a task by ID.",
+		InputSchema: objectSchema(map[string]interface{}{"id": idProperty}, "id"),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			path, err := taskPath(args)
+			return http.MethodGet, path, nil, err
+		},
+	},
+	{
+		Name:        "create_task",
+		Description: "Create a task. Only the title is required; status defaults to pending.",
+		InputSchema: objectSchema(taskFields, "title"),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			return http.MethodPost, "/api/tasks", args, nil
+		},
+	},
+	{
+		Name:        "update_task",
+		Description: "Change some fields of a task; fields left out keep their value. Set status to completed to complete it.",
+		InputSchema: objectSchema(withProperty(taskFields, "id", idProperty), "id"),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			path, err := taskPath(args)
+			if err != nil {
+				return "", "", nil, err
+			}
+			body := make(map[string]interface{}, len(args))
+			for name, value := range args {
+				if name != "id" {
+					body[name] = value
+				}
+			}
+			return http.MethodPatch, path, body, nil
+		},
+	},
+	{
+		Name:        "delete_task",
+		Description: "Delete a task with its links and notes. It can be restored with POST /api/undo for 10 minutes.",
+		InputSchema: objectSchema(map[string]interface{}{"id": idProperty}, "id"),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			path, err := taskPath(args)
+			return http.MethodDelete, path, nil, err
+		},
+	},
+	{
+		Name:        "get_stats",
+		Description: "Count tasks by status, overdue and archived tasks, and tasks created and completed in the last 7 and 30 days.",
+		InputSchema: objectSchema(map[string]interface{}{}),
+		route: func(args map[string]interface{}) (string, string, map[string]interface{}, error) {
+			return http.MethodGet, "/api/stats", nil, nil
+		},
+	},
+}
+
+// toolContent is a text item of a tool result
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// toolResult is the result of tools/call. Failures of the tool itself are
+// results with IsError set, so the assistant sees them.
+type toolResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+func textResult(text string, isError bool) toolResult {
+	return toolResult{Content: []toolContent{{Type: "text", Text: text}}, IsError: isError}
+}
+
+// callTool runs a tools/call request. The tool's result is the API's
+// response body.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "Invalid params"}
+	}
+	var t *tool
+	for i := range tools {
+		if tools[i].Name == call.Name {
+			t = &tools[i]
+			break
+		}
+	}
+	if t == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "Unknown tool: " + call.Name}
+	}
+	if call.Arguments == nil {
+		call.Arguments = map[string]interface{}{}
+	}
+
+	method, path, body, err := t.route(call.Arguments)
+	if err != nil {
+		return textResult(err.Error(), true), nil
+	}
+	var data []byte
+	if body != nil {
+		if data, err = json.Marshal(body); err != nil {
+			return textResult(err.Error(), true), nil
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(data))
+	if err != nil {
+		return textResult(err.Error(), true), nil
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := &recorder{header: http.Header{}}
+	s.api.ServeHTTP(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	text := string(bytes.TrimSpace(rec.body.Bytes()))
+	if text == "" {
+		text = fmt.Sprintf("%d %s", rec.status, http.StatusText(rec.status))
+	}
+	return textResult(text, rec.status >= 400), nil
+}
+
+// objectSchema returns the schema of a tool's arguments
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// withProperty returns a copy of a map of schema properties or arguments
+// with one more
+func withProperty(properties map[string]interface{}, name string, schema interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(properties)+1)
+	for k, v := range properties {
+		out[k] = v
+	}
+	out[name] = schema
+	return out
+}
+
+// taskPath returns the API path of the task named by the id argument
+func taskPath(args map[string]interface{}) (string, error) {
+	id, ok := args["id"].(float64)
+	if !ok || id < 1 || id != float64(int(id)) {
+		return "", errors.New("id must be a positive integer")
+	}
+	return "/api/tasks/" + strconv.Itoa(int(id)), nil
+}
+
+// query encodes the named arguments that are present as a query string
+func query(args map[string]interface{}, names ...string) string {
+	values := url.Values{}
+	for _, name := range names {
+		switch v := args[name].(type) {
+		case string:
+			if v != "" {
+				values.Set(name, v)
+			}
+		case float64:
+			values.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			values.Set(name, strconv.FormatBool(v))
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// recorder captures the API's response to a tool call
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport settings
+const (
+	// maxMessage caps the size of one message
+	maxMessage = 10 << 20
+	// sessionBuffer is the number of replies queued for an SSE client
+	sessionBuffer = 64
+	// heartbeat keeps idle SSE streams open through proxies
+	heartbeat = 15 * time.Second
+	// writeTimeout bounds each write to an SSE stream
+	writeTimeout = 10 * time.Second
+)
+
+// ServeStdio reads newline-delimited messages from in and writes the
+// replies to out, one per line, until in ends or ctx is done
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), maxMessage)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if reply := s.Handle(ctx, line); reply != nil {
+			if _, err := out.Write(append(reply, '\n')); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// session is a connected SSE client
+type session struct {
+	replies chan []byte
+	done    chan struct{}
+}
+
+// sseTransport holds the open SSE sessions
+type sseTransport struct {
+	server   *Server
+	prefix   string
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// SSEHandler serves the HTTP+SSE transport under prefix: a client opens
+// GET <prefix>/sse, is told where to post in an endpoint event, and gets
+// the replies to what it posts to <prefix>/message as message events
+func (s *Server) SSEHandler(prefix string) http.Handler {
+	t := &sseTransport{server: s, prefix: strings.TrimSuffix(prefix, "/"), sessions: map[string]*session{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(t.prefix+"/sse", t.stream)
+	mux.HandleFunc(t.prefix+"/message", t.message)
+	return mux
+}
+
+// stream handles GET <prefix>/sse
+func (t *sseTransport) stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "Open the event stream with GET")
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to open session", "")
+		return
+	}
+	sess := &session{replies: make(chan []byte, sessionBuffer), done: make(chan struct{})}
+	t.mu.Lock()
+	t.sessions[id] = sess
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.sessions, id)
+		t.mu.Unlock()
+		close(sess.done)
+	}()
+
+	// The stream outlives the server's read timeout; writes get their own
+	// deadline below
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(frame string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := io.WriteString(w, frame); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !write(fmt.Sprintf("event: endpoint\ndata: %s/message?session_id=%s\n\n", t.prefix, id)) {
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case reply := <-sess.replies:
+			if !write("event: message\ndata: " + string(reply) + "\n\n") {
+				return
+			}
+		case <-ticker.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// message handles POST <prefix>/message?session_id=. The reply goes out
+// on the session's stream; the POST itself is answered 202.
+func (t *sseTransport) message(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "Send messages with POST")
+		return
+	}
+	t.mu.Lock()
+	sess := t.sessions[r.URL.Query().Get("session_id")]
+	t.mu.Unlock()
+	if sess == nil {
+		writeError(w, http.StatusNotFound, "Session not found", "Open "+t.prefix+"/sse and post to the endpoint it sends")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessage))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "Request body too large", fmt.Sprintf("Messages are limited to %d MB", maxMessage>>20))
+		return
+	}
+	if reply := t.server.Handle(r.Context(), body); reply != nil {
+		select {
+		case sess.replies <- reply:
+		case <-sess.done:
+			writeError(w, http.StatusNotFound, "Session not found", "The event stream was closed")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// newSessionID returns a random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeError writes an error body shaped like the API's
+func writeError(w http.ResponseWriter, status int, error, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": error, "message": message})
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// binaryMediaTypes are request bodies that are not text, such as SQLite
+// backups, and are passed through unchecked
+var binaryMediaTypes = map[string]bool{
+	"application/octet-stream": true,
+	"application/vnd.sqlite3":  true,
+	"application/x-sqlite3":    true,
+}
+
+// UTF8 enforces UTF-8 on the wire: requests declaring another charset get a
+// 415, bodies containing invalid UTF-8 get a 400, and clients whose
+// Accept-Charset rules out UTF-8 get a 406.
+func UTF8(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept-Charset"); accept != "" && !acceptsUTF8(accept) {
+			writeCharsetError(w, http.StatusNotAcceptable, "Not acceptable", "Responses are only available in utf-8")
+			return
+		}
+
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			if mediaType, params, err := mime.ParseMediaType(ct); err == nil {
+				if binaryMediaTypes[mediaType] {
+					next.ServeHTTP(w, r)
+					return
+				}
+				if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "utf8") {
+					writeCharsetError(w, http.StatusUnsupportedMediaType, "Unsupported charset", "Request bodies must be encoded as utf-8")
+					return
+				}
+			}
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeCharsetError(w, http.StatusBadRequest, "Invalid request body", "Failed to read request body")
+			return
+		}
+		if !utf8.Valid(body) {
+			writeCharsetError(w, http.StatusBadRequest, "Invalid UTF-8", "Request body contains invalid UTF-8")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptsUTF8 reports whether an Accept-Charset header allows utf-8
+func acceptsUTF8(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		charset := strings.ToLower(strings.TrimSpace(fields[0]))
+		if charset != "utf-8" && charset != "utf8" && charset != "*" {
+			continue
+		}
+		rejected := false
+		for _, param := range fields[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == "q=0" {
+				rejected = true
+			}
+		}
+		if !rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCharsetError writes an error body in the API's standard shape
+func writeCharsetError(w http.ResponseWriter, statusCode int, error string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   error,
+		"message": message,
+	})
+}
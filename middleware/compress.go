@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressor is a streaming encoder for one content coding
+type compressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// Encoders are reused across responses; a zstd encoder in particular
+// allocates its window up front
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}}
+	zstdWriters = sync.Pool{New: func() interface{} {
+		// A single goroutine and a 1 MB window keep pooled encoders small;
+		// the options are fixed, so NewWriter can't fail
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(1<<20))
+		return enc
+	}}
+)
+
+// codings are the content codings responses are compressed with, in the
+// order preferred when a client likes several equally. zstd comes first:
+// it compresses JSON lists better than gzip, and faster.
+var codings = []string{"zstd", "gzip"}
+
+// newCompressor returns a pooled encoder for coding writing to w
+func newCompressor(coding string, w io.Writer) compressor {
+	if coding == "zstd" {
+		enc := zstdWriters.Get().(*zstd.Encoder)
+		enc.Reset(w)
+		return enc
+	}
+	gz := gzipWriters.Get().(*gzip.Writer)
+	gz.Reset(w)
+	return gz
+}
+
+// releaseCompressor finishes c and returns it to its pool
+func releaseCompressor(c compressor) {
+	c.Close()
+	switch c := c.(type) {
+	case *zstd.Encoder:
+		c.Reset(nil)
+		zstdWriters.Put(c)
+	case *gzip.Writer:
+		gzipWriters.Put(c)
+	}
+}
+
+// negotiateEncoding picks the coding for an Accept-Encoding header: the
+// supported one with the highest quality, the server's preference on a
+// tie. "*" stands for codings the header doesn't name, and q=0 refuses
+// one. It returns "" when the response should not be compressed.
+func negotiateEncoding(header string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(name)) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range codings {
+		q, ok := qualities[coding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressResponseWriter wraps http.ResponseWriter to compress the body
+type compressResponseWriter struct {
+	http.ResponseWriter
+	writer compressor
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	// Content-Length is not reliable once compressed
+	w.Header().Del("Content-Length")
+	return w.writer.Write(b)
+}
+
+// Flush sends what has been compressed so far, so streamed responses such
+// as Server-Sent Events are not held back in the encoder's buffer
+func (w *compressResponseWriter) Flush() {
+	w.writer.Flush()
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Compress is a middleware that compresses HTTP responses with zstd or
+// gzip, whichever the client's Accept-Encoding prefers
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// Upgraded connections such as WebSockets are not HTTP bodies
+		coding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if coding == "" || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c := newCompressor(coding, w)
+		defer releaseCompressor(c)
+
+		w.Header().Set("Content-Encoding", coding)
+		next.ServeHTTP(&compressResponseWriter{ResponseWriter: w, writer: c}, r)
+	})
+}
@@ -4,26 +4,26 @@ import (
 	"net/http"
 )
 
-// CORS middleware to handle Cross-Origin Resource Sharing
+// CORS middleware to handle Cross-Origin Resource Sharing. Preflight
+// OPTIONS requests match no route, so Methods answers them.
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+		setCORSHeaders(w.Header())
 
 		// Call the next handler
 		next.ServeHTTP(w, r)
 	})
 }
 
+// setCORSHeaders sets the CORS response headers
+func setCORSHeaders(h http.Header) {
+	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Timestamp-Format, If-None-Match, If-Match, Idempotency-Key")
+	h.Set("Access-Control-Expose-Headers", "Link, X-Total-Count, X-Next-Cursor, X-Warning, ETag, Idempotent-Replayed, Deprecation, Sunset")
+	h.Set("Access-Control-Max-Age", "86400") // 24 hours
+}
+
 // Logging middleware to log HTTP requests
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"to-do-api/openapi"
+
+	"github.com/gorilla/mux"
+)
+
+// Deprecations answers the routes in routes with machine-readable
+// migration warnings: Deprecation (RFC 9745) with the date the route was
+// deprecated, Sunset (RFC 8594) with the date it may be removed, and Link
+// to its successor and migration notes. Keys of routes are
+// "METHOD /path/template" as registered with the router.
+func Deprecations(routes map[string]openapi.Deprecation) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					method := r.Method
+					if method == http.MethodHead {
+						method = http.MethodGet
+					}
+					if d, ok := routes[method+" "+template]; ok {
+						setDeprecationHeaders(w.Header(), d)
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setDeprecationHeaders sets the headers announcing d. Link is added to,
+// as paged lists send one of their own.
+func setDeprecationHeaders(h http.Header, d openapi.Deprecation) {
+	h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		h.Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+	if d.Info != "" {
+		h.Add("Link", "<"+d.Info+`>; rel="deprecation"`)
+	}
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// envelopeMembers are the members of the API's success envelope,
+// handlers.SuccessResponse
+var envelopeMembers = map[string]bool{"message": true, "data": true, "pagination": true, "_links": true, "warnings": true}
+
+// Envelope controls the {"message", "data"} wrapper around success
+// responses. With it off, a 2xx JSON body in the wrapper is replaced by its
+// data alone, so clients get the bare task or array; pagination moves to
+// the X-Total-Count, Link and X-Next-Cursor headers, as for NDJSON lists,
+// and each warning to an X-Warning header. A wrapper with no data becomes
+// an empty 204 (or an empty body with its own 2xx status). Error bodies are
+// left alone. enabled is the server default; the envelope query parameter
+// overrides it per request.
+func Envelope(enabled bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrap := enabled
+			if v := r.URL.Query().Get("envelope"); v != "" {
+				parsed, err := strconv.ParseBool(v)
+				if err != nil {
+					writeCharsetError(w, http.StatusBadRequest, "Invalid envelope parameter", "envelope must be true or false")
+					return
+				}
+				wrap = parsed
+			}
+			if wrap {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &envelopeResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			ew.finish()
+		})
+	}
+}
+
+// envelopeResponseWriter buffers successful JSON bodies so they can be
+// unwrapped; everything else is written straight through
+type envelopeResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *envelopeResponseWriter) WriteHeader(statusCode int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if statusCode >= 200 && statusCode < 300 && mediaType == "application/json" {
+		w.buffering = true
+		w.status = statusCode
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *envelopeResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *envelopeResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack hands the connection over, e.g. for a WebSocket
+func (w *envelopeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// finish unwraps and writes a buffered body
+func (w *envelopeResponseWriter) finish() {
+	if !w.buffering {
+		return
+	}
+
+	body := w.buf.Bytes()
+	var members map[string]json.RawMessage
+	// Bodies that aren't the envelope, such as /health, are sent unchanged
+	if err := json.Unmarshal(body, &members); err != nil || !isEnvelope(members) {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(body)
+		return
+	}
+
+	var pagination struct {
+		Total      *int   `json:"total"`
+		Next       string `json:"next"`
+		Prev       string `json:"prev"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if raw, ok := members["pagination"]; ok && json.Unmarshal(raw, &pagination) == nil {
+		if pagination.Total != nil {
+			w.Header().Set("X-Total-Count", strconv.Itoa(*pagination.Total))
+		}
+		if pagination.NextCursor != "" {
+			w.Header().Set("X-Next-Cursor", pagination.NextCursor)
+		}
+		if pagination.Next != "" {
+			w.Header().Add("Link", "<"+pagination.Next+`>; rel="next"`)
+		}
+		if pagination.Prev != "" {
+			w.Header().Add("Link", "<"+pagination.Prev+`>; rel="prev"`)
+		}
+	}
+	var warnings []string
+	if raw, ok := members["warnings"]; ok && json.Unmarshal(raw, &warnings) == nil {
+		for _, warning := range warnings {
+			w.Header().Add("X-Warning", warning)
+		}
+	}
+
+	w.Header().Del("Content-Length")
+	data, ok := members["data"]
+	if !ok {
+		w.Header().Del("Content-Type")
+		if w.status == http.StatusOK {
+			w.status = http.StatusNoContent
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(data)
+	w.ResponseWriter.Write([]byte("\n"))
+}
+
+// isEnvelope reports whether a JSON object is the success envelope: it has
+// a message and no members besides the envelope's
+func isEnvelope(members map[string]json.RawMessage) bool {
+	if _, ok := members["message"]; !ok {
+		return false
+	}
+	for name := range members {
+		if !envelopeMembers[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,38 +0,0 @@
-package middleware
-
-import (
-	"compress/gzip"
-	"net/http"
-	"strings"
-)
-
-// gzipResponseWriter wraps http.ResponseWriter to support gzip encoding
-type gzipResponseWriter struct {
-	http.ResponseWriter
-	writer *gzip.Writer
-}
-
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	// Content-Length is not reliable with gzip
-	w.Header().Del("Content-Length")
-	return w.writer.Write(b)
-}
-
-// Gzip is a middleware that compresses HTTP responses when the client supports it
-func Gzip(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Vary", "Accept-Encoding")
-
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-
-		w.Header().Set("Content-Encoding", "gzip")
-		grw := &gzipResponseWriter{ResponseWriter: w, writer: gz}
-		next.ServeHTTP(grw, r)
-	})
-}
\ No newline at end of file
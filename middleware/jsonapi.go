@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// jsonAPIMediaType is the JSON:API media type, see handlers.JSONAPIMediaType
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// isJSONAPI reports whether a Content-Type or Accept entry is JSON:API
+func isJSONAPI(value string) bool {
+	mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+	return err == nil && mediaType == jsonAPIMediaType && len(params) == 0
+}
+
+// JSONAPIErrors rewrites error responses into JSON:API error documents for
+// clients that accept JSON:API, so their tooling can read the failures of
+// any endpoint. The API's {"error", "message"} body becomes an errors array
+// with the status, the error as title and the message as detail.
+func JSONAPIErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted := false
+		for _, accept := range r.Header.Values("Accept") {
+			for _, part := range strings.Split(accept, ",") {
+				if isJSONAPI(part) {
+					accepted = true
+				}
+			}
+		}
+		if !accepted {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ew := &jsonAPIErrorWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		ew.finish()
+	})
+}
+
+// jsonAPIErrorWriter buffers JSON error bodies so they can be rewritten;
+// everything else is written straight through
+type jsonAPIErrorWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *jsonAPIErrorWriter) WriteHeader(statusCode int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if statusCode >= 400 && mediaType == "application/json" {
+		w.buffering = true
+		w.status = statusCode
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *jsonAPIErrorWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *jsonAPIErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack hands the connection over, e.g. for a WebSocket
+func (w *jsonAPIErrorWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// finish rewrites and writes a buffered error body
+func (w *jsonAPIErrorWriter) finish() {
+	if !w.buffering {
+		return
+	}
+
+	body := w.buf.Bytes()
+	var in struct {
+		Error   string      `json:"error"`
+		Message string      `json:"message"`
+		Details interface{} `json:"details"`
+	}
+	// A body that does not parse is sent unchanged rather than lost
+	if err := json.Unmarshal(body, &in); err != nil || in.Error == "" {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(body)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status": strconv.Itoa(w.status),
+		"title":  in.Error,
+	}
+	if in.Message != "" {
+		out["detail"] = in.Message
+	}
+	if in.Details != nil {
+		out["meta"] = map[string]interface{}{"details": in.Details}
+	}
+	w.Header().Set("Content-Type", jsonAPIMediaType)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	json.NewEncoder(w.ResponseWriter).Encode(map[string]interface{}{
+		"errors": []interface{}{out},
+	})
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"to-do-api/i18n"
+
+	"github.com/gorilla/mux"
+)
+
+// localizedMembers are the top-level members of JSON bodies that hold
+// user-facing text: the error and message of every response, and the
+// warnings of success responses
+var localizedMembers = map[string]bool{"error": true, "message": true, "warnings": true}
+
+// Localize translates response messages into the language the client
+// prefers in Accept-Language, from catalog. The error, message and
+// warnings of JSON bodies are translated; data is never touched. The
+// language used is reported in Content-Language.
+func Localize(catalog *i18n.Catalog) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Language")
+			lang := catalog.Match(r.Header.Get("Accept-Language"))
+			w.Header().Set("Content-Language", lang)
+			if lang == i18n.SourceLanguage {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			lw := &localizeResponseWriter{ResponseWriter: w, catalog: catalog, lang: lang}
+			next.ServeHTTP(lw, r)
+			lw.finish()
+		})
+	}
+}
+
+// localizeResponseWriter buffers JSON bodies so their messages can be
+// translated; other content types are written straight through
+type localizeResponseWriter struct {
+	http.ResponseWriter
+	catalog   *i18n.Catalog
+	lang      string
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *localizeResponseWriter) WriteHeader(statusCode int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == "application/json" {
+		w.buffering = true
+		w.status = statusCode
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *localizeResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *localizeResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack hands the connection over, e.g. for a WebSocket
+func (w *localizeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// finish translates and writes a buffered body
+func (w *localizeResponseWriter) finish() {
+	if !w.buffering {
+		return
+	}
+
+	body := w.buf.Bytes()
+	out, err := w.translate(body)
+	// A body that does not parse is sent unchanged rather than lost
+	if err != nil {
+		out = body
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(out)
+}
+
+// translate re-encodes a JSON object member by member, keeping their
+// order, with the messages translated. Other values are left as they are.
+func (w *localizeResponseWriter) translate(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return body, nil
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for n := 0; dec.More(); n++ {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if localizedMembers[name] {
+			value = w.translateValue(value)
+		}
+		if n > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
+
+// translateValue translates a message string or a list of them
+func (w *localizeResponseWriter) translateValue(value json.RawMessage) json.RawMessage {
+	var message string
+	if json.Unmarshal(value, &message) == nil {
+		out, _ := marshalText(w.catalog.Translate(w.lang, message))
+		return out
+	}
+	var messages []string
+	if json.Unmarshal(value, &messages) == nil && messages != nil {
+		for i := range messages {
+			messages[i] = w.catalog.Translate(w.lang, messages[i])
+		}
+		out, _ := marshalText(messages)
+		return out
+	}
+	return value
+}
+
+// marshalText encodes v like encoding/json without escaping <, > and &,
+// which messages contain as plain text
+func marshalText(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
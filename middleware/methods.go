@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// routeMethods are the methods routes are registered for, in the order
+// Allow lists them
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Methods answers what router's routes leave to mux defaults, which get
+// method handling wrong for strict HTTP clients:
+//   - HEAD runs the GET route and sends its status and headers, ETag
+//     included, without the body
+//   - OPTIONS answers 204 with the path's methods in Allow, which also
+//     serves as the CORS preflight response
+//   - any other method a path has no route for gets 405 with Allow,
+//     instead of 404
+//
+// Paths with no route at all are left to the router.
+func Methods(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var match mux.RouteMatch
+		if r.Method != http.MethodHead && r.Method != http.MethodOptions && router.Match(r, &match) && match.MatchErr == nil {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := allowedMethods(router, r)
+		if len(allowed) == 0 {
+			router.ServeHTTP(w, r)
+			return
+		}
+		allow := strings.Join(allowed, ", ")
+
+		switch {
+		case r.Method == http.MethodOptions:
+			setCORSHeaders(w.Header())
+			w.Header().Set("Access-Control-Allow-Methods", allow)
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodHead && allowed[0] == http.MethodGet:
+			serveHead(router, w, r)
+		default:
+			w.Header().Set("Allow", allow)
+			writeCharsetError(w, http.StatusMethodNotAllowed, "Method not allowed", r.Method+" is not supported here; use "+allow)
+		}
+	})
+}
+
+// allowedMethods lists the methods router has a route for at r's path,
+// with HEAD after GET and OPTIONS last, or nothing when no route matches
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	probe := r.Clone(r.Context())
+	for _, method := range routeMethods {
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+			if method == http.MethodGet {
+				allowed = append(allowed, http.MethodHead)
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// serveHead answers a HEAD request with the headers of the GET route. The
+// request is cancelled once the headers are out, so streaming routes stop
+// instead of producing a body nobody reads.
+func serveHead(router *mux.Router, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	get := r.Clone(ctx)
+	get.Method = http.MethodGet
+	router.ServeHTTP(&headResponseWriter{ResponseWriter: w, cancel: cancel}, get)
+}
+
+// headResponseWriter passes the status and headers of a response through
+// and drops its body
+type headResponseWriter struct {
+	http.ResponseWriter
+	cancel      context.CancelFunc
+	wroteHeader bool
+}
+
+func (w *headResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+	w.cancel()
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return len(b), nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush has nothing to send; it keeps streaming handlers, which flush
+// after their headers, from failing
+func (w *headResponseWriter) Flush() {}
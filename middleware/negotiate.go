@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"mime"
+	"net"
+	"net/http"
+	"to-do-api/codec"
+)
+
+// ContentNegotiation encodes JSON responses in the format the client's
+// Accept header prefers, such as XML. Responses in other media types, and
+// clients that prefer JSON, are passed through untouched.
+func ContentNegotiation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		c := codec.Negotiate(r.Header.Get("Accept"))
+		if c == codec.JSON {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		nw := &negotiatedResponseWriter{ResponseWriter: w, codec: c}
+		next.ServeHTTP(nw, r)
+		nw.finish()
+	})
+}
+
+// negotiatedResponseWriter buffers JSON bodies so they can be re-encoded;
+// other content types are written straight through
+type negotiatedResponseWriter struct {
+	http.ResponseWriter
+	codec     codec.Codec
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *negotiatedResponseWriter) WriteHeader(statusCode int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == "application/json" {
+		w.buffering = true
+		w.status = statusCode
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *negotiatedResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *negotiatedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack hands the connection over, e.g. for a WebSocket
+func (w *negotiatedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// finish encodes and writes a buffered body
+func (w *negotiatedResponseWriter) finish() {
+	if !w.buffering {
+		return
+	}
+
+	// A body that does not parse is sent unchanged rather than lost
+	var out bytes.Buffer
+	if err := w.codec.Encode(&out, w.buf.Bytes()); err != nil {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buf.Bytes())
+		return
+	}
+	contentType := w.codec.ContentType()
+	if w.codec != codec.MsgPack {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(out.Bytes())
+}
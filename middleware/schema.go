@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"to-do-api/jsonpatch"
+	"to-do-api/schemas"
+
+	"github.com/gorilla/mux"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SchemaViolation locates a single schema validation failure in the request body
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// SchemaValidation validates JSON request bodies against the schema mapped to
+// the matched route. Keys of routes are "METHOD /path/template" as registered
+// with the router, e.g. "POST /api/tasks"; values are schema names.
+func SchemaValidation(routes map[string]string) (mux.MiddlewareFunc, error) {
+	names := make([]string, 0, len(routes))
+	for _, name := range routes {
+		names = append(names, name)
+	}
+	compiled, err := schemas.Compile(names...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// JSON:API documents wrap the body the schemas describe, and JSON
+			// Patch documents describe changes to it
+			route := mux.CurrentRoute(r)
+			if route == nil || r.Body == nil || isJSONAPI(r.Header.Get("Content-Type")) || isJSONPatch(r.Header.Get("Content-Type")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			schema, ok := compiled[routes[r.Method+" "+template]]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Malformed JSON is left for the handler to report
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			var doc interface{}
+			if err := dec.Decode(&doc); err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := schema.Validate(doc); err != nil {
+				verr, ok := err.(*jsonschema.ValidationError)
+				if !ok {
+					next.ServeHTTP(w, r)
+					return
+				}
+				writeSchemaError(w, verr)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// writeSchemaError reports the leaf validation failures with JSON pointers
+// into the request body
+func writeSchemaError(w http.ResponseWriter, verr *jsonschema.ValidationError) {
+	var violations []SchemaViolation
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			violations = append(violations, SchemaViolation{Pointer: e.InstanceLocation, Message: e.Message})
+			return
+		}
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+	}
+	collect(verr)
+
+	message := "Request body does not match the schema"
+	if len(violations) > 0 {
+		message = violations[0].Message
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   "Schema validation failed",
+		"message": message,
+		"details": violations,
+	})
+}
+
+// isJSONPatch reports whether a Content-Type is a JSON Patch document
+func isJSONPatch(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	return err == nil && mediaType == jsonpatch.MediaType
+}
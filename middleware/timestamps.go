@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TimestampFormatHeader lets a client override the server's timestamp format
+// for a single request
+const TimestampFormatHeader = "X-Timestamp-Format"
+
+// timestampFormats maps format names to functions that re-encode a timestamp
+// as a JSON value
+var timestampFormats = map[string]func(time.Time) interface{}{
+	// rfc3339nano is encoding/json's default and is passed through untouched
+	"rfc3339nano": nil,
+	"rfc3339": func(t time.Time) interface{} {
+		return t.Format(time.RFC3339)
+	},
+	"rfc3339ms": func(t time.Time) interface{} {
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	},
+	"epoch_ms": func(t time.Time) interface{} {
+		return t.UnixMilli()
+	},
+}
+
+// TimestampFormat rewrites the timestamps in JSON and NDJSON responses into
+// the configured format. Timestamps are RFC 3339 strings under keys ending in
+// "_at" or "_date". Clients may pick another format per request with the
+// X-Timestamp-Format header; an unknown name is rejected with a 400.
+func TimestampFormat(defaultFormat string) (mux.MiddlewareFunc, error) {
+	if defaultFormat == "" {
+		defaultFormat = "rfc3339nano"
+	}
+	if _, ok := timestampFormats[defaultFormat]; !ok {
+		return nil, fmt.Errorf("unknown timestamp format %q (want rfc3339nano, rfc3339, rfc3339ms or epoch_ms)", defaultFormat)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", TimestampFormatHeader)
+
+			name := defaultFormat
+			if override := r.Header.Get(TimestampFormatHeader); override != "" {
+				name = strings.ToLower(override)
+			}
+			format, ok := timestampFormats[name]
+			if !ok {
+				writeCharsetError(w, http.StatusBadRequest, "Invalid timestamp format", TimestampFormatHeader+" must be one of rfc3339nano, rfc3339, rfc3339ms or epoch_ms")
+				return
+			}
+			if format == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tw := &timestampResponseWriter{ResponseWriter: w, format: format}
+			next.ServeHTTP(tw, r)
+			tw.finish()
+		})
+	}, nil
+}
+
+// timestampResponseWriter buffers JSON bodies so their timestamps can be
+// rewritten; other content types are written straight through
+type timestampResponseWriter struct {
+	http.ResponseWriter
+	format    func(time.Time) interface{}
+	status    int
+	decided   bool
+	buffering bool
+	ndjson    bool
+	buf       bytes.Buffer
+}
+
+func (w *timestampResponseWriter) WriteHeader(statusCode int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	switch mediaType {
+	case "application/json", "application/vnd.api+json":
+		w.buffering = true
+	case "application/x-ndjson":
+		w.buffering, w.ndjson = true, true
+	}
+	if w.buffering {
+		w.status = statusCode
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timestampResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *timestampResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack hands the connection over, e.g. for a WebSocket, which needs the
+// http.Hijacker interface on the writer itself
+func (w *timestampResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// finish rewrites and writes a buffered body
+func (w *timestampResponseWriter) finish() {
+	if !w.buffering {
+		return
+	}
+
+	body := w.buf.Bytes()
+	var out bytes.Buffer
+	var err error
+	if w.ndjson {
+		for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				out.Write(line)
+				continue
+			}
+			if err = rewriteTimestamps(&out, line, w.format); err != nil {
+				break
+			}
+			out.WriteByte('\n')
+		}
+	} else if len(bytes.TrimSpace(body)) > 0 {
+		if err = rewriteTimestamps(&out, body, w.format); err == nil {
+			out.WriteByte('\n')
+		}
+	}
+	// A body that does not parse is sent unchanged rather than lost
+	if err != nil {
+		out.Reset()
+		out.Write(body)
+	}
+
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(out.Bytes())
+}
+
+// jsonFrame tracks the container being re-encoded by rewriteTimestamps
+type jsonFrame struct {
+	object bool
+	count  int
+	key    string
+}
+
+// rewriteTimestamps re-encodes a single JSON value token by token, keeping
+// key order, and converts timestamp strings with format
+func rewriteTimestamps(out *bytes.Buffer, data []byte, format func(time.Time) interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var stack []*jsonFrame
+	expectKey := func() bool {
+		if len(stack) == 0 {
+			return false
+		}
+		top := stack[len(stack)-1]
+		return top.object && top.count%2 == 0
+	}
+	separate := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		switch {
+		case top.object && top.count%2 == 1:
+			out.WriteByte(':')
+		case top.count > 0:
+			out.WriteByte(',')
+		}
+		top.count++
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(stack) != 0 {
+				return errors.New("unexpected end of JSON")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(byte(delim))
+			continue
+		}
+
+		isKey := expectKey()
+		separate()
+
+		var value interface{} = tok
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			stack = append(stack, &jsonFrame{object: v == '{'})
+			continue
+		case string:
+			if isKey {
+				stack[len(stack)-1].key = v
+			} else if len(stack) > 0 && isTimestampKey(stack[len(stack)-1].key) {
+				if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+					value = format(t)
+				}
+			}
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	}
+}
+
+// isTimestampKey reports whether a key names a timestamp field
+func isTimestampKey(key string) bool {
+	return strings.HasSuffix(key, "_at") || strings.HasSuffix(key, "_date")
+}
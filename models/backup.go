@@ -0,0 +1,225 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BackupVersion is the format version of backups written by this build
+const BackupVersion = 1
+
+// Backup is a complete copy of the stored data: every task with its links
+// and notes, the recurring schedules and the webhooks with their secrets
+type Backup struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Tasks     []TaskSnapshot `json:"tasks"`
+	Schedules []Schedule     `json:"schedules"`
+	Webhooks  []Webhook      `json:"webhooks"`
+}
+
+// BackupCounts summarizes what a backup holds
+type BackupCounts struct {
+	Tasks     int `json:"tasks"`
+	Links     int `json:"links"`
+	Notes     int `json:"notes"`
+	Schedules int `json:"schedules"`
+	Webhooks  int `json:"webhooks"`
+}
+
+// Counts returns how many records of each kind the backup holds
+func (b *Backup) Counts() BackupCounts {
+	counts := BackupCounts{Tasks: len(b.Tasks), Schedules: len(b.Schedules), Webhooks: len(b.Webhooks)}
+	for _, snapshot := range b.Tasks {
+		counts.Links += len(snapshot.Links)
+		counts.Notes += len(snapshot.Notes)
+	}
+	return counts
+}
+
+// ReadBackup copies everything in repo into a backup. The reads are not one
+// transaction, so writes made meanwhile may be caught half way.
+func ReadBackup(repo TaskRepository, now time.Time) (*Backup, error) {
+	tasks, err := repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	backup := &Backup{Version: BackupVersion, CreatedAt: now.UTC(), Tasks: make([]TaskSnapshot, 0, len(tasks))}
+	// Oldest first, so a restore recreates them in their original order
+	for i := len(tasks) - 1; i >= 0; i-- {
+		snapshot := TaskSnapshot{Task: tasks[i]}
+		if snapshot.Links, err = repo.ListLinks(tasks[i].ID); err != nil {
+			return nil, err
+		}
+		if snapshot.Notes, err = repo.ListNotes(tasks[i].ID); err != nil {
+			return nil, err
+		}
+		backup.Tasks = append(backup.Tasks, snapshot)
+	}
+	if backup.Schedules, err = repo.ListSchedules(); err != nil {
+		return nil, err
+	}
+	if backup.Webhooks, err = repo.ListWebhooks(); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// Validate checks a backup before it replaces the stored data. Records must
+// have unique positive IDs, links and notes must belong to their task, and
+// tasks, schedules and webhooks must pass the checks their requests do.
+func (b *Backup) Validate() error {
+	if b.Version != BackupVersion {
+		return &ValidationError{Field: "version", Message: fmt.Sprintf("version must be %d", BackupVersion)}
+	}
+
+	taskIDs := make(map[int]bool, len(b.Tasks))
+	linkIDs := make(map[int]bool)
+	noteIDs := make(map[int]bool)
+	externalIDs := make(map[string]bool)
+	uuids := make(map[string]bool)
+	for i, snapshot := range b.Tasks {
+		t := snapshot.Task
+		field := fmt.Sprintf("tasks[%d]", i)
+		if t.ID < 1 || taskIDs[t.ID] {
+			return &ValidationError{Field: field + ".id", Message: field + ": task IDs must be positive and unique"}
+		}
+		taskIDs[t.ID] = true
+		if t.Status == "" {
+			return &ValidationError{Field: field + ".status", Message: field + ": status is required"}
+		}
+		progress := t.Progress
+		req := TaskRequest{Title: t.Title, Description: t.Description, StartDate: t.StartDate, DueDate: t.DueDate, Status: t.Status, Progress: &progress, Color: t.Color, Encryption: t.Encryption, Location: t.Location, UUID: t.UUID}
+		if err := req.Validate(); err != nil {
+			return backupFieldError(field, err)
+		}
+		if t.ExternalID != "" {
+			key := t.Source + "\x00" + t.ExternalID
+			if externalIDs[key] {
+				return &ValidationError{Field: field + ".external_id", Message: field + ": external IDs must be unique within their source"}
+			}
+			externalIDs[key] = true
+		}
+		if t.UUID != "" {
+			if uuids[t.UUID] {
+				return &ValidationError{Field: field + ".uuid", Message: field + ": UUIDs must be unique"}
+			}
+			uuids[t.UUID] = true
+		}
+
+		for j, link := range snapshot.Links {
+			linkField := fmt.Sprintf("%s.links[%d]", field, j)
+			if link.ID < 1 || linkIDs[link.ID] {
+				return &ValidationError{Field: linkField + ".id", Message: linkField + ": link IDs must be positive and unique"}
+			}
+			linkIDs[link.ID] = true
+			if link.TaskID != t.ID {
+				return &ValidationError{Field: linkField + ".task_id", Message: linkField + ": task_id must be the ID of the task holding the link"}
+			}
+			if strings.TrimSpace(link.URL) == "" {
+				return &ValidationError{Field: linkField + ".url", Message: linkField + ": url is required"}
+			}
+		}
+		for j, note := range snapshot.Notes {
+			noteField := fmt.Sprintf("%s.notes[%d]", field, j)
+			if note.ID < 1 || noteIDs[note.ID] {
+				return &ValidationError{Field: noteField + ".id", Message: noteField + ": note IDs must be positive and unique"}
+			}
+			noteIDs[note.ID] = true
+			if note.TaskID != t.ID {
+				return &ValidationError{Field: noteField + ".task_id", Message: noteField + ": task_id must be the ID of the task holding the note"}
+			}
+		}
+	}
+
+	scheduleIDs := make(map[int]bool, len(b.Schedules))
+	for i, s := range b.Schedules {
+		field := fmt.Sprintf("schedules[%d]", i)
+		if s.ID < 1 || scheduleIDs[s.ID] {
+			return &ValidationError{Field: field + ".id", Message: field + ": schedule IDs must be positive and unique"}
+		}
+		scheduleIDs[s.ID] = true
+		req := ScheduleRequest{Title: s.Title, Description: s.Description, Color: s.Color, Frequency: s.Frequency, Interval: s.Interval, StartDate: s.StartDate, EndDate: s.EndDate}
+		if err := req.Validate(); err != nil {
+			return backupFieldError(field, err)
+		}
+		if s.Generated < 0 {
+			return &ValidationError{Field: field + ".generated", Message: field + ": generated must not be negative"}
+		}
+	}
+
+	webhookIDs := make(map[int]bool, len(b.Webhooks))
+	for i, wh := range b.Webhooks {
+		field := fmt.Sprintf("webhooks[%d]", i)
+		if wh.ID < 1 || webhookIDs[wh.ID] {
+			return &ValidationError{Field: field + ".id", Message: field + ": webhook IDs must be positive and unique"}
+		}
+		webhookIDs[wh.ID] = true
+		req := WebhookRequest{URL: wh.URL, Events: wh.Events}
+		if err := req.Validate(); err != nil {
+			return backupFieldError(field, err)
+		}
+		if wh.Secret == "" {
+			return &ValidationError{Field: field + ".secret", Message: field + ": secret is required"}
+		}
+	}
+	return nil
+}
+
+// backupFieldError places a record's validation error within the backup
+func backupFieldError(field string, err error) error {
+	if ve, ok := err.(*ValidationError); ok {
+		return &ValidationError{Field: field + "." + ve.Field, Message: field + ": " + ve.Message}
+	}
+	return err
+}
+
+// ReplaceAll deletes everything stored and loads the backup in its place,
+// in a single transaction, keeping every record's ID
+func (r *SQLiteTaskRepository) ReplaceAll(backup *Backup) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Children first, although ON DELETE CASCADE would catch them
+	for _, table := range []string{"task_notes", "task_links", "tasks", "schedules", "webhooks"} {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+
+	columns := strings.Split(taskColumns, ", ")
+	insertTask := `INSERT INTO tasks (` + taskColumns + `) VALUES (?` + strings.Repeat(", ?", len(columns)-1) + `)`
+	for _, snapshot := range backup.Tasks {
+		t := snapshot.Task
+		enc := encryptionColumns(t.Encryption)
+		latitude, longitude, place := locationColumns(t.Location)
+		if _, err := tx.Exec(insertTask, t.ID, t.Title, t.Description, utcTime(t.StartDate), utcTime(t.DueDate), t.Status, t.Progress, t.Position, t.Pinned, t.Archived, t.Color, enc.KeyID, enc.Algorithm, t.CreatedAt.UTC(), t.UpdatedAt.UTC(), utcTime(t.CompletedAt), t.Source, t.ExternalID, latitude, longitude, place, t.UUID); err != nil {
+			return fmt.Errorf("task %d: %w", t.ID, err)
+		}
+		for _, link := range snapshot.Links {
+			if _, err := tx.Exec(`INSERT INTO task_links (`+linkColumns+`) VALUES (?, ?, ?, ?, ?)`, link.ID, link.TaskID, link.Title, link.URL, link.CreatedAt.UTC()); err != nil {
+				return fmt.Errorf("link %d: %w", link.ID, err)
+			}
+		}
+		for _, note := range snapshot.Notes {
+			if _, err := tx.Exec(`INSERT INTO task_notes (`+noteColumns+`) VALUES (?, ?, ?, ?)`, note.ID, note.TaskID, note.Body, note.CreatedAt.UTC()); err != nil {
+				return fmt.Errorf("note %d: %w", note.ID, err)
+			}
+		}
+	}
+	for _, s := range backup.Schedules {
+		if _, err := tx.Exec(`INSERT INTO schedules (`+scheduleColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, s.ID, s.Title, s.Description, s.Color, s.Frequency, s.Interval, s.StartDate.UTC(), utcTime(s.EndDate), s.Generated, s.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("schedule %d: %w", s.ID, err)
+		}
+	}
+	for _, wh := range backup.Webhooks {
+		if _, err := tx.Exec(`INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?)`, wh.ID, wh.URL, strings.Join(wh.Events, ","), wh.Secret, wh.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("webhook %d: %w", wh.ID, err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,1412 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket names used by BoltTaskRepository; created by database.InitBolt
+var (
+	boltTasksBucket       = []byte("tasks")
+	boltStatusIndexBucket = []byte("idx_status")
+	boltDueIndexBucket    = []byte("idx_due_date")
+	boltLinksBucket       = []byte("links")
+	boltNotesBucket       = []byte("notes")
+	// boltExternalIndexBucket maps source and external ID to a task ID
+	boltExternalIndexBucket = []byte("idx_external")
+	boltSchedulesBucket     = []byte("schedules")
+	boltWebhooksBucket      = []byte("webhooks")
+	boltChangesBucket       = []byte("changes")
+	// boltChangeIndexBucket maps a task ID to the key of its latest change
+	boltChangeIndexBucket = []byte("idx_changes")
+	// boltUUIDIndexBucket maps a client-generated UUID to a task ID
+	boltUUIDIndexBucket = []byte("idx_uuid")
+	// boltMetaBucket holds repository-wide counters such as boltLastPositionKey
+	boltMetaBucket = []byte("meta")
+)
+
+// boltLastPositionKey holds the highest position given to a task, so new
+// tasks go to the end without scanning the tasks bucket
+var boltLastPositionKey = []byte("last_position")
+
+// dueKeyLayout is a fixed-width, lexically sortable timestamp layout
+const dueKeyLayout = "20060102T150405.000000000"
+
+// BoltTaskRepository implements TaskRepository on top of a bbolt key-value
+// store. Tasks are stored as JSON keyed by ID, with secondary index buckets
+// for status and due date.
+type BoltTaskRepository struct {
+	// db runs transactions: the database, or tx while one is open
+	db    boltStore
+	bolt  *bolt.DB
+	tx    *boltBatch
+	clock Clock
+	ids   IDGenerator
+}
+
+// NewBoltTaskRepository creates a new bbolt task repository
+func NewBoltTaskRepository(db *bolt.DB) *BoltTaskRepository {
+	return &BoltTaskRepository{db: db, bolt: db, clock: SystemClock}
+}
+
+// boltStore runs the transactions of repository methods
+type boltStore interface {
+	Update(fn func(*bolt.Tx) error) error
+	View(fn func(*bolt.Tx) error) error
+}
+
+// boltBatch runs every method in one open write transaction. bbolt has no
+// savepoints, so a method that fails part way can't take back what it
+// already wrote; the transaction then refuses to commit.
+type boltBatch struct {
+	tx  *bolt.Tx
+	err error
+}
+
+func (b *boltBatch) Update(fn func(*bolt.Tx) error) error {
+	err := fn(b.tx)
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return err
+}
+
+func (b *boltBatch) View(fn func(*bolt.Tx) error) error {
+	return fn(b.tx)
+}
+
+// Begin opens a write transaction that every later call runs in, until
+// Commit or Rollback. Other callers must stay off the repository
+// meanwhile: they would run in the transaction too.
+func (r *BoltTaskRepository) Begin() error {
+	if r.tx != nil {
+		return ErrTransactionOpen
+	}
+	tx, err := r.bolt.Begin(true)
+	if err != nil {
+		return err
+	}
+	r.tx = &boltBatch{tx: tx}
+	r.db = r.tx
+	return nil
+}
+
+// Commit ends the transaction opened by Begin, keeping its writes. It
+// rolls back instead when a call in the transaction failed.
+func (r *BoltTaskRepository) Commit() error {
+	if r.tx == nil {
+		return ErrNoTransaction
+	}
+	batch := r.tx
+	r.tx, r.db = nil, r.bolt
+	if batch.err != nil {
+		batch.tx.Rollback()
+		return fmt.Errorf("transaction rolled back after a failed write: %w", batch.err)
+	}
+	return batch.tx.Commit()
+}
+
+// Rollback ends the transaction opened by Begin, discarding its writes
+func (r *BoltTaskRepository) Rollback() error {
+	if r.tx == nil {
+		return ErrNoTransaction
+	}
+	batch := r.tx
+	r.tx, r.db = nil, r.bolt
+	return batch.tx.Rollback()
+}
+
+// SetClock replaces the clock used for created_at and updated_at
+func (r *BoltTaskRepository) SetClock(clock Clock) {
+	r.clock = clock
+}
+
+// SetIDGenerator makes new tasks take their IDs from gen instead of the
+// bucket sequence; nil restores the default
+func (r *BoltTaskRepository) SetIDGenerator(gen IDGenerator) {
+	r.ids = gen
+}
+
+// boltID encodes a task ID as a big-endian key so keys sort numerically
+func boltID(id int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func statusIndexKey(status string, id int) []byte {
+	return append([]byte(status+"\x00"), boltID(id)...)
+}
+
+func dueIndexKey(due time.Time, id int) []byte {
+	return append([]byte(due.UTC().Format(dueKeyLayout)), boltID(id)...)
+}
+
+func externalIndexKey(source, externalID string) []byte {
+	return []byte(source + "\x00" + externalID)
+}
+
+// boltGetTask loads a task by ID, returning nil when it does not exist
+func boltGetTask(tx *bolt.Tx, id int) (*Task, error) {
+	data := tx.Bucket(boltTasksBucket).Get(boltID(id))
+	if data == nil {
+		return nil, nil
+	}
+	var task Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// boltPutTask stores a task and keeps the secondary indexes in sync. old is
+// the previously stored version, or nil for a new task.
+func boltPutTask(tx *bolt.Tx, old *Task, task *Task) error {
+	if old != nil {
+		if err := boltDeleteIndexes(tx, old); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltTasksBucket).Put(boltID(task.ID), data); err != nil {
+		return err
+	}
+	if task.Position > boltLastPosition(tx) {
+		if err := tx.Bucket(boltMetaBucket).Put(boltLastPositionKey, boltID(task.Position)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Bucket(boltStatusIndexBucket).Put(statusIndexKey(task.Status, task.ID), nil); err != nil {
+		return err
+	}
+	if task.DueDate != nil {
+		if err := tx.Bucket(boltDueIndexBucket).Put(dueIndexKey(*task.DueDate, task.ID), nil); err != nil {
+			return err
+		}
+	}
+	if task.ExternalID != "" {
+		if err := tx.Bucket(boltExternalIndexBucket).Put(externalIndexKey(task.Source, task.ExternalID), boltID(task.ID)); err != nil {
+			return err
+		}
+	}
+	if task.UUID != "" {
+		if err := tx.Bucket(boltUUIDIndexBucket).Put([]byte(task.UUID), boltID(task.ID)); err != nil {
+			return err
+		}
+	}
+
+	changeType := ChangeUpdated
+	if old == nil {
+		changeType = ChangeCreated
+	}
+	return boltRecordChange(tx, task.ID, changeType, time.Now())
+}
+
+// boltLastPosition returns the highest position given to a task. It only
+// grows: deleting or renumbering tasks leaves gaps rather than reusing it.
+func boltLastPosition(tx *bolt.Tx) int {
+	data := tx.Bucket(boltMetaBucket).Get(boltLastPositionKey)
+	if data == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(data))
+}
+
+// BackfillBoltPosition records the highest task position for stores
+// written before it was tracked
+func BackfillBoltPosition(tx *bolt.Tx) error {
+	if tx.Bucket(boltMetaBucket).Get(boltLastPositionKey) != nil {
+		return nil
+	}
+	tasks, err := boltAllTasks(tx)
+	if err != nil {
+		return err
+	}
+	position := 0
+	for _, task := range tasks {
+		position = max(position, task.Position)
+	}
+	return tx.Bucket(boltMetaBucket).Put(boltLastPositionKey, boltID(position))
+}
+
+// boltDeleteIndexes removes a task's secondary index entries
+func boltDeleteIndexes(tx *bolt.Tx, task *Task) error {
+	if err := tx.Bucket(boltStatusIndexBucket).Delete(statusIndexKey(task.Status, task.ID)); err != nil {
+		return err
+	}
+	if task.DueDate != nil {
+		if err := tx.Bucket(boltDueIndexBucket).Delete(dueIndexKey(*task.DueDate, task.ID)); err != nil {
+			return err
+		}
+	}
+	if task.ExternalID != "" {
+		if err := tx.Bucket(boltExternalIndexBucket).Delete(externalIndexKey(task.Source, task.ExternalID)); err != nil {
+			return err
+		}
+	}
+	if task.UUID != "" {
+		if err := tx.Bucket(boltUUIDIndexBucket).Delete([]byte(task.UUID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltDeleteTask removes a task, its index entries, links and notes, and
+// leaves a tombstone in the changes feed
+func boltDeleteTask(tx *bolt.Tx, task *Task) error {
+	if err := boltDeleteIndexes(tx, task); err != nil {
+		return err
+	}
+	if err := boltRecordChange(tx, task.ID, ChangeDeleted, time.Now()); err != nil {
+		return err
+	}
+	for _, name := range [][]byte{boltLinksBucket, boltNotesBucket} {
+		if err := boltDeletePrefix(tx.Bucket(name), boltID(task.ID)); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(boltTasksBucket).Delete(boltID(task.ID))
+}
+
+// boltDeletePrefix removes every key in bucket that starts with prefix
+func boltDeletePrefix(bucket *bolt.Bucket, prefix []byte) error {
+	var keys [][]byte
+	c := bucket.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltAllTasks loads every task in ID order
+func boltAllTasks(tx *bolt.Tx) ([]Task, error) {
+	var tasks []Task
+	err := tx.Bucket(boltTasksBucket).ForEach(func(_, data []byte) error {
+		var task Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+		return nil
+	})
+	return tasks, err
+}
+
+// boltTasksByStatus loads the tasks with a status using the status index
+func boltTasksByStatus(tx *bolt.Tx, status string) ([]Task, error) {
+	var tasks []Task
+	prefix := []byte(status + "\x00")
+	c := tx.Bucket(boltStatusIndexBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		id := int(binary.BigEndian.Uint64(k[len(prefix):]))
+		task, err := boltGetTask(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if task != nil {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks, nil
+}
+
+// boltTasksByDueDate returns tasks ordered by due date using the due date
+// index. Tasks without a due date always come last. Every task is still
+// read, as filters and pinning apply to the whole list, but each only once.
+func boltTasksByDueDate(tx *bolt.Tx, desc bool) ([]Task, error) {
+	var dated []Task
+	seen := make(map[int]bool)
+	c := tx.Bucket(boltDueIndexBucket).Cursor()
+	visit := func(k []byte) error {
+		id := int(binary.BigEndian.Uint64(k[len(k)-8:]))
+		task, err := boltGetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if task != nil {
+			dated = append(dated, *task)
+			seen[id] = true
+		}
+		return nil
+	}
+	if desc {
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			if err := visit(k); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if err := visit(k); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var undated []Task
+	err := tx.Bucket(boltTasksBucket).ForEach(func(k, data []byte) error {
+		if seen[int(binary.BigEndian.Uint64(k))] {
+			return nil
+		}
+		var task Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return err
+		}
+		undated = append(undated, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(dated, undated...), nil
+}
+
+// boltSelect returns the tasks matching the given IDs, or the filter when no
+// IDs are given
+func boltSelect(tx *bolt.Tx, ids []int, filter TaskFilter) ([]Task, error) {
+	if len(ids) > 0 {
+		var tasks []Task
+		seen := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			task, err := boltGetTask(tx, id)
+			if err != nil {
+				return nil, err
+			}
+			if task != nil {
+				tasks = append(tasks, *task)
+			}
+		}
+		return tasks, nil
+	}
+
+	var candidates []Task
+	var err error
+	if len(filter.Statuses) > 0 {
+		seen := make(map[string]bool, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			if seen[status] {
+				continue
+			}
+			seen[status] = true
+			matched, err := boltTasksByStatus(tx, status)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, matched...)
+		}
+	} else {
+		candidates, err = boltAllTasks(tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var tasks []Task
+	for _, task := range candidates {
+		if filter.Matches(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// boltCreate inserts a new task at the end of the manual ordering. A
+// positive id is used as-is, advancing the bucket sequence past it;
+// otherwise the next sequence value is taken.
+func boltCreate(tx *bolt.Tx, taskReq *TaskRequest, now time.Time, id int) (*Task, error) {
+	bucket := tx.Bucket(boltTasksBucket)
+	if id > 0 {
+		if bucket.Get(boltID(id)) != nil {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateID, id)
+		}
+		if uint64(id) > bucket.Sequence() {
+			if err := bucket.SetSequence(uint64(id)); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return nil, err
+		}
+		id = int(seq)
+	}
+	if taskReq.UUID != "" && tx.Bucket(boltUUIDIndexBucket).Get([]byte(taskReq.UUID)) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUUIDTaken, taskReq.UUID)
+	}
+
+	status := taskReq.Status
+	if status == "" {
+		status = "pending"
+	}
+
+	task := &Task{
+		ID:          id,
+		Title:       taskReq.Title,
+		Description: taskReq.Description,
+		StartDate:   utcTime(taskReq.StartDate),
+		DueDate:     utcTime(taskReq.DueDate),
+		Status:      status,
+		Progress:    progressValue(taskReq.Progress),
+		Color:       taskReq.Color,
+		Encryption:  taskReq.Encryption,
+		Location:    taskReq.Location,
+		UUID:        taskReq.UUID,
+		Position:    boltLastPosition(tx) + 1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	task.MarkCompletion(now)
+	if err := boltPutTask(tx, nil, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Create creates a new task
+func (r *BoltTaskRepository) Create(taskReq *TaskRequest) (*Task, error) {
+	var task *Task
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		task, err = boltCreate(tx, taskReq, r.clock.Now(), nextID(r.ids))
+		return err
+	})
+	return task, err
+}
+
+// CreateBatch creates several tasks in a single transaction
+func (r *BoltTaskRepository) CreateBatch(taskReqs []*TaskRequest) ([]Task, error) {
+	tasks := make([]Task, 0, len(taskReqs))
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		for _, taskReq := range taskReqs {
+			task, err := boltCreate(tx, taskReq, r.clock.Now(), nextID(r.ids))
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, *task)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetAll retrieves all tasks
+func (r *BoltTaskRepository) GetAll() ([]Task, error) {
+	var tasks []Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		tasks, err = boltAllTasks(tx)
+		return err
+	})
+	SortTasks(tasks, "created_at", "desc")
+	return tasks, err
+}
+
+// GetAllPaginated retrieves tasks with optional filtering, sorting, and pagination
+func (r *BoltTaskRepository) GetAllPaginated(filter TaskFilter, limit int, offset int, sortBy string, sortOrder string) ([]Task, error) {
+	var tasks []Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		if sortBy != "due_date" {
+			var err error
+			tasks, err = boltSelect(tx, nil, filter)
+			SortTasks(tasks, sortBy, sortOrder)
+			return err
+		}
+
+		ordered, err := boltTasksByDueDate(tx, sortOrder != "asc" && sortOrder != "ASC")
+		if err != nil {
+			return err
+		}
+		for _, task := range ordered {
+			if filter.Matches(task) {
+				tasks = append(tasks, task)
+			}
+		}
+		pinnedFirst(tasks)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return PaginateTasks(tasks, limit, offset), nil
+}
+
+// Count returns the number of tasks matching the filter
+func (r *BoltTaskRepository) Count(filter TaskFilter) (int, error) {
+	var count int
+	err := r.db.View(func(tx *bolt.Tx) error {
+		tasks, err := boltSelect(tx, nil, filter)
+		count = len(tasks)
+		return err
+	})
+	return count, err
+}
+
+// Stats computes task statistics from a full scan
+func (r *BoltTaskRepository) Stats(now time.Time) (*TaskStats, error) {
+	var stats *TaskStats
+	err := r.db.View(func(tx *bolt.Tx) error {
+		tasks, err := boltAllTasks(tx)
+		if err != nil {
+			return err
+		}
+		stats = ComputeStats(tasks, now)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// CompletionTimes scans for tasks completed in [from, to)
+func (r *BoltTaskRepository) CompletionTimes(from, to time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.db.View(func(tx *bolt.Tx) error {
+		tasks, err := boltAllTasks(tx)
+		if err != nil {
+			return err
+		}
+		times = FilterCompletionTimes(tasks, from, to)
+		return nil
+	})
+	return times, err
+}
+
+// GetByID retrieves a task by ID
+func (r *BoltTaskRepository) GetByID(id int) (*Task, error) {
+	var task *Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		task, err = boltGetTask(tx, id)
+		return err
+	})
+	return task, err
+}
+
+// modify applies fn to a stored task and saves it, returning nil when the
+// task does not exist. check runs in the same transaction as the write.
+func (r *BoltTaskRepository) modify(id int, check VersionCheck, fn func(task *Task) error) (*Task, error) {
+	var task *Task
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		old, err := boltGetTask(tx, id)
+		if err != nil || old == nil {
+			return err
+		}
+		if err := check.Verify(old); err != nil {
+			return err
+		}
+		task, err = r.modifyTx(tx, old, fn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// modifyTx applies fn to a copy of old and stores it within tx
+func (r *BoltTaskRepository) modifyTx(tx *bolt.Tx, old *Task, fn func(task *Task) error) (*Task, error) {
+	updated := *old
+	if err := fn(&updated); err != nil {
+		return nil, err
+	}
+	updated.StartDate = utcTime(updated.StartDate)
+	updated.DueDate = utcTime(updated.DueDate)
+	updated.UpdatedAt = r.clock.Now()
+	updated.MarkCompletion(updated.UpdatedAt)
+	if err := boltPutTask(tx, old, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Update updates a task
+func (r *BoltTaskRepository) Update(id int, taskReq *TaskRequest, check VersionCheck) (*Task, error) {
+	return r.modify(id, check, taskReq.applyUpdate)
+}
+
+// Patch applies a partial update to a task
+func (r *BoltTaskRepository) Patch(id int, patch *TaskPatch, check VersionCheck) (*Task, error) {
+	return r.modify(id, check, patch.Apply)
+}
+
+// Delete deletes a task
+func (r *BoltTaskRepository) Delete(id int, check VersionCheck) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		task, err := boltGetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if task == nil {
+			return sql.ErrNoRows
+		}
+		if err := check.Verify(task); err != nil {
+			return err
+		}
+		if err := boltDeleteTask(tx, task); err != nil {
+			return err
+		}
+		return boltCompactPositions(tx)
+	})
+}
+
+// GetByStatus retrieves tasks by status
+func (r *BoltTaskRepository) GetByStatus(status string) ([]Task, error) {
+	var tasks []Task
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		tasks, err = boltTasksByStatus(tx, status)
+		return err
+	})
+	SortTasks(tasks, "created_at", "desc")
+	return tasks, err
+}
+
+// boltRenumber assigns positions 1..n following order
+func boltRenumber(tx *bolt.Tx, order []int) error {
+	for i, id := range order {
+		old, err := boltGetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		if old == nil || old.Position == i+1 {
+			continue
+		}
+		updated := *old
+		updated.Position = i + 1
+		if err := boltPutTask(tx, old, &updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltPositionOrder returns all task IDs sorted by position
+func boltPositionOrder(tx *bolt.Tx) ([]int, error) {
+	all, err := boltAllTasks(tx)
+	if err != nil {
+		return nil, err
+	}
+	SortTasks(all, "position", "asc")
+	ids := make([]int, len(all))
+	for i, task := range all {
+		ids[i] = task.ID
+	}
+	return ids, nil
+}
+
+// boltCompactPositions renumbers the tasks 1..n in position order, closing
+// the gaps deleted tasks leave
+func boltCompactPositions(tx *bolt.Tx) error {
+	order, err := boltPositionOrder(tx)
+	if err != nil {
+		return err
+	}
+	return boltRenumber(tx, order)
+}
+
+// TogglePin flips a task's pinned flag
+func (r *BoltTaskRepository) TogglePin(id int) (*Task, error) {
+	return r.modify(id, nil, func(task *Task) error {
+		task.Pinned = !task.Pinned
+		return nil
+	})
+}
+
+// Move places a task at the given 1-based position
+func (r *BoltTaskRepository) Move(id int, position int) (*Task, error) {
+	var task *Task
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		existing, err := boltGetTask(tx, id)
+		if err != nil || existing == nil {
+			return err
+		}
+
+		ids, err := boltPositionOrder(tx)
+		if err != nil {
+			return err
+		}
+		rest := make([]int, 0, len(ids))
+		for _, other := range ids {
+			if other != id {
+				rest = append(rest, other)
+			}
+		}
+		if position < 1 {
+			position = 1
+		}
+		if position > len(ids) {
+			position = len(ids)
+		}
+		order := append(append(append([]int{}, rest[:position-1]...), id), rest[position-1:]...)
+		if err := boltRenumber(tx, order); err != nil {
+			return err
+		}
+
+		task, err = boltGetTask(tx, id)
+		if err != nil {
+			return err
+		}
+		old := *task
+		task.UpdatedAt = r.clock.Now()
+		return boltPutTask(tx, &old, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Reorder assigns positions to the given task IDs in order; unlisted tasks
+// keep their relative order after them
+func (r *BoltTaskRepository) Reorder(ids []int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		listed := make(map[int]bool, len(ids))
+		order := make([]int, 0, len(ids))
+		for _, id := range ids {
+			task, err := boltGetTask(tx, id)
+			if err != nil {
+				return err
+			}
+			if task == nil {
+				return sql.ErrNoRows
+			}
+			if !listed[id] {
+				listed[id] = true
+				order = append(order, id)
+			}
+		}
+
+		all, err := boltPositionOrder(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range all {
+			if !listed[id] {
+				order = append(order, id)
+			}
+		}
+		return boltRenumber(tx, order)
+	})
+}
+
+// UpdateBatch applies a change set to the selected tasks in one transaction
+// and returns the number of tasks updated
+func (r *BoltTaskRepository) UpdateBatch(ids []int, filter TaskFilter, changes TaskChanges) (int, error) {
+	count := 0
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		selected, err := boltSelect(tx, ids, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, task := range selected {
+			startDate, dueDate := task.StartDate, task.DueDate
+			if changes.StartDate != nil {
+				startDate = changes.StartDate
+			}
+			if changes.DueDate != nil {
+				dueDate = changes.DueDate
+			}
+			if startDate != nil && dueDate != nil && startDate.After(*dueDate) {
+				return &ValidationError{Field: "changes", Message: "change would set start_date after due_date on one or more tasks"}
+			}
+		}
+
+		now := r.clock.Now()
+		for i := range selected {
+			old := selected[i]
+			updated := old
+			if changes.Status != nil {
+				updated.Status = *changes.Status
+			}
+			if changes.StartDate != nil {
+				updated.StartDate = utcTime(changes.StartDate)
+			}
+			if changes.DueDate != nil {
+				updated.DueDate = utcTime(changes.DueDate)
+			}
+			if changes.Archived != nil {
+				updated.Archived = *changes.Archived
+			}
+			updated.UpdatedAt = now
+			updated.MarkCompletion(now)
+			if err := boltPutTask(tx, &old, &updated); err != nil {
+				return err
+			}
+		}
+		count = len(selected)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// DeleteBatch deletes the selected tasks in one transaction and returns the
+// number deleted. With dryRun set it only counts the matching tasks.
+func (r *BoltTaskRepository) DeleteBatch(ids []int, filter TaskFilter, dryRun bool) (int, error) {
+	count := 0
+	run := r.db.Update
+	if dryRun {
+		run = r.db.View
+	}
+	err := run(func(tx *bolt.Tx) error {
+		selected, err := boltSelect(tx, ids, filter)
+		if err != nil {
+			return err
+		}
+		count = len(selected)
+		if dryRun {
+			return nil
+		}
+		for i := range selected {
+			if err := boltDeleteTask(tx, &selected[i]); err != nil {
+				return err
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		return boltCompactPositions(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// childKey keys a link or note by its task, then by its own ID, so a task's
+// children sort together in insertion order
+func childKey(taskID, childID int) []byte {
+	return append(boltID(taskID), boltID(childID)...)
+}
+
+// ListLinks returns a task's links in the order they were added, or nil
+// when the task does not exist
+func (r *BoltTaskRepository) ListLinks(taskID int) ([]Link, error) {
+	var links []Link
+	err := r.db.View(func(tx *bolt.Tx) error {
+		task, err := boltGetTask(tx, taskID)
+		if err != nil || task == nil {
+			return err
+		}
+		links = []Link{}
+		prefix := boltID(taskID)
+		c := tx.Bucket(boltLinksBucket).Cursor()
+		for k, data := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = c.Next() {
+			var link Link
+			if err := json.Unmarshal(data, &link); err != nil {
+				return err
+			}
+			links = append(links, link)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// CreateLink attaches a new link to a task, returning nil when the task does
+// not exist
+func (r *BoltTaskRepository) CreateLink(taskID int, req *LinkRequest) (*Link, error) {
+	var link *Link
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		task, err := boltGetTask(tx, taskID)
+		if err != nil || task == nil {
+			return err
+		}
+		bucket := tx.Bucket(boltLinksBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		created := Link{ID: int(seq), TaskID: taskID, Title: req.Title, URL: req.URL, CreatedAt: r.clock.Now()}
+		data, err := json.Marshal(created)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(childKey(taskID, created.ID), data); err != nil {
+			return err
+		}
+		link = &created
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// UpdateLink replaces a link's title and URL, returning nil when the task or
+// link does not exist
+func (r *BoltTaskRepository) UpdateLink(taskID, linkID int, req *LinkRequest) (*Link, error) {
+	var link *Link
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLinksBucket)
+		data := bucket.Get(childKey(taskID, linkID))
+		if data == nil {
+			return nil
+		}
+		var updated Link
+		if err := json.Unmarshal(data, &updated); err != nil {
+			return err
+		}
+		updated.Title = req.Title
+		updated.URL = req.URL
+		data, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(childKey(taskID, linkID), data); err != nil {
+			return err
+		}
+		link = &updated
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// DeleteLink removes a link from a task
+func (r *BoltTaskRepository) DeleteLink(taskID, linkID int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLinksBucket)
+		if bucket.Get(childKey(taskID, linkID)) == nil {
+			return sql.ErrNoRows
+		}
+		return bucket.Delete(childKey(taskID, linkID))
+	})
+}
+
+// ListNotes returns a task's notes oldest first, or nil when the task does
+// not exist
+func (r *BoltTaskRepository) ListNotes(taskID int) ([]Note, error) {
+	var notes []Note
+	err := r.db.View(func(tx *bolt.Tx) error {
+		task, err := boltGetTask(tx, taskID)
+		if err != nil || task == nil {
+			return err
+		}
+		notes = []Note{}
+		prefix := boltID(taskID)
+		c := tx.Bucket(boltNotesBucket).Cursor()
+		for k, data := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = c.Next() {
+			var note Note
+			if err := json.Unmarshal(data, &note); err != nil {
+				return err
+			}
+			notes = append(notes, note)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// AddNote appends a note to a task, returning nil when the task does not
+// exist
+func (r *BoltTaskRepository) AddNote(taskID int, req *NoteRequest) (*Note, error) {
+	var note *Note
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		task, err := boltGetTask(tx, taskID)
+		if err != nil || task == nil {
+			return err
+		}
+		bucket := tx.Bucket(boltNotesBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		created := Note{ID: int(seq), TaskID: taskID, Body: req.Body, CreatedAt: r.clock.Now()}
+		data, err := json.Marshal(created)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(childKey(taskID, created.ID), data); err != nil {
+			return err
+		}
+		note = &created
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// UpsertExternal updates the task imported from source under externalID, or
+// creates it when there is none yet, and reports whether it was created
+func (r *BoltTaskRepository) UpsertExternal(source, externalID string, req *TaskRequest) (*Task, bool, error) {
+	var task *Task
+	created := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if id := tx.Bucket(boltExternalIndexBucket).Get(externalIndexKey(source, externalID)); id != nil {
+			old, err := boltGetTask(tx, int(binary.BigEndian.Uint64(id)))
+			if err != nil {
+				return err
+			}
+			if old != nil {
+				task, err = r.modifyTx(tx, old, req.applyUpdate)
+				return err
+			}
+		}
+
+		fresh, err := boltCreate(tx, req, r.clock.Now(), nextID(r.ids))
+		if err != nil {
+			return err
+		}
+		updated := *fresh
+		updated.Source, updated.ExternalID = source, externalID
+		if err := boltPutTask(tx, fresh, &updated); err != nil {
+			return err
+		}
+		task, created = &updated, true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return task, created, nil
+}
+
+// RestoreTasks writes snapshots back in a single transaction. Tasks that
+// still exist are overwritten; deleted ones are recreated with their
+// original IDs, links and notes.
+func (r *BoltTaskRepository) RestoreTasks(snapshots []TaskSnapshot) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		for _, snapshot := range snapshots {
+			task := snapshot.Task
+			if task.ExternalID != "" {
+				if id := tx.Bucket(boltExternalIndexBucket).Get(externalIndexKey(task.Source, task.ExternalID)); id != nil && int(binary.BigEndian.Uint64(id)) != task.ID {
+					return fmt.Errorf("%w: task %d: external ID %s/%s is taken", ErrRestoreConflict, task.ID, task.Source, task.ExternalID)
+				}
+			}
+			if task.UUID != "" {
+				if id := tx.Bucket(boltUUIDIndexBucket).Get([]byte(task.UUID)); id != nil && int(binary.BigEndian.Uint64(id)) != task.ID {
+					return fmt.Errorf("%w: task %d: uuid %s is taken", ErrRestoreConflict, task.ID, task.UUID)
+				}
+			}
+			old, err := boltGetTask(tx, task.ID)
+			if err != nil {
+				return err
+			}
+			if err := boltPutTask(tx, old, &task); err != nil {
+				return err
+			}
+
+			for _, link := range snapshot.Links {
+				if err := boltPutChild(tx.Bucket(boltLinksBucket), childKey(link.TaskID, link.ID), link); err != nil {
+					return err
+				}
+			}
+			for _, note := range snapshot.Notes {
+				if err := boltPutChild(tx.Bucket(boltNotesBucket), childKey(note.TaskID, note.ID), note); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceAll deletes everything stored and loads the backup in its place,
+// in a single transaction, keeping every record's ID
+func (r *BoltTaskRepository) ReplaceAll(backup *Backup) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		// The changes feed is kept: every task replaced is deleted as far
+		// as clients syncing from it know, and the restored ones created
+		existing, err := boltAllTasks(tx)
+		if err != nil {
+			return err
+		}
+		for _, task := range existing {
+			if err := boltRecordChange(tx, task.ID, ChangeDeleted, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		names := [][]byte{boltTasksBucket, boltStatusIndexBucket, boltDueIndexBucket, boltLinksBucket, boltNotesBucket, boltExternalIndexBucket, boltUUIDIndexBucket, boltMetaBucket, boltSchedulesBucket, boltWebhooksBucket}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+
+		// Sequences continue after the highest restored ID
+		var maxTask, maxLink, maxNote, maxSchedule, maxWebhook int
+		for _, snapshot := range backup.Tasks {
+			task := snapshot.Task
+			if err := boltPutTask(tx, nil, &task); err != nil {
+				return err
+			}
+			maxTask = max(maxTask, task.ID)
+			for _, link := range snapshot.Links {
+				if err := boltPutChild(tx.Bucket(boltLinksBucket), childKey(link.TaskID, link.ID), link); err != nil {
+					return err
+				}
+				maxLink = max(maxLink, link.ID)
+			}
+			for _, note := range snapshot.Notes {
+				if err := boltPutChild(tx.Bucket(boltNotesBucket), childKey(note.TaskID, note.ID), note); err != nil {
+					return err
+				}
+				maxNote = max(maxNote, note.ID)
+			}
+		}
+		for _, schedule := range backup.Schedules {
+			data, err := json.Marshal(schedule)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(boltSchedulesBucket).Put(boltID(schedule.ID), data); err != nil {
+				return err
+			}
+			maxSchedule = max(maxSchedule, schedule.ID)
+		}
+		for _, webhook := range backup.Webhooks {
+			data, err := json.Marshal(webhook)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(boltWebhooksBucket).Put(boltID(webhook.ID), data); err != nil {
+				return err
+			}
+			maxWebhook = max(maxWebhook, webhook.ID)
+		}
+
+		sequences := []struct {
+			name []byte
+			max  int
+		}{
+			{boltTasksBucket, maxTask}, {boltLinksBucket, maxLink}, {boltNotesBucket, maxNote},
+			{boltSchedulesBucket, maxSchedule}, {boltWebhooksBucket, maxWebhook},
+		}
+		for _, seq := range sequences {
+			if err := tx.Bucket(seq.name).SetSequence(uint64(seq.max)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltPutChild stores a link or note unless its key is already taken
+func boltPutChild(bucket *bolt.Bucket, key []byte, v interface{}) error {
+	if bucket.Get(key) != nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, data)
+}
+
+// boltGetSchedule loads a schedule by ID, returning nil when it does not exist
+func boltGetSchedule(tx *bolt.Tx, id int) (*Schedule, error) {
+	data := tx.Bucket(boltSchedulesBucket).Get(boltID(id))
+	if data == nil {
+		return nil, nil
+	}
+	var schedule Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, err
+	}
+	schedule.SetNextDueDate()
+	return &schedule, nil
+}
+
+// ListSchedules returns every schedule, oldest first
+func (r *BoltTaskRepository) ListSchedules() ([]Schedule, error) {
+	schedules := []Schedule{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSchedulesBucket).ForEach(func(k, v []byte) error {
+			var schedule Schedule
+			if err := json.Unmarshal(v, &schedule); err != nil {
+				return err
+			}
+			schedule.SetNextDueDate()
+			schedules = append(schedules, schedule)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule by ID, or nil when it does not exist
+func (r *BoltTaskRepository) GetSchedule(id int) (*Schedule, error) {
+	var schedule *Schedule
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		schedule, err = boltGetSchedule(tx, id)
+		return err
+	})
+	return schedule, err
+}
+
+// CreateSchedule stores a new schedule
+func (r *BoltTaskRepository) CreateSchedule(req *ScheduleRequest) (*Schedule, error) {
+	schedule := req.Schedule()
+	schedule.CreatedAt = r.clock.Now()
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltSchedulesBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		schedule.ID = int(seq)
+		data, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(boltID(schedule.ID), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	schedule.SetNextDueDate()
+	return schedule, nil
+}
+
+// DeleteSchedule removes a schedule; tasks it already generated are kept.
+// It returns sql.ErrNoRows when the schedule does not exist.
+func (r *BoltTaskRepository) DeleteSchedule(id int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltSchedulesBucket)
+		if bucket.Get(boltID(id)) == nil {
+			return sql.ErrNoRows
+		}
+		return bucket.Delete(boltID(id))
+	})
+}
+
+// SetScheduleGenerated records how many occurrences of a schedule have been
+// materialized. A deleted schedule is ignored.
+func (r *BoltTaskRepository) SetScheduleGenerated(id, generated int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		schedule, err := boltGetSchedule(tx, id)
+		if err != nil || schedule == nil {
+			return err
+		}
+		schedule.Generated = generated
+		schedule.NextDueDate = nil
+		data, err := json.Marshal(schedule)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(boltSchedulesBucket).Put(boltID(id), data)
+	})
+}
+
+// ListWebhooks returns every webhook, oldest first
+func (r *BoltTaskRepository) ListWebhooks() ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltWebhooksBucket).ForEach(func(k, v []byte) error {
+			var wh Webhook
+			if err := json.Unmarshal(v, &wh); err != nil {
+				return err
+			}
+			webhooks = append(webhooks, wh)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook by ID, or nil when it does not exist
+func (r *BoltTaskRepository) GetWebhook(id int) (*Webhook, error) {
+	var webhook *Webhook
+	err := r.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltWebhooksBucket).Get(boltID(id))
+		if data == nil {
+			return nil
+		}
+		webhook = &Webhook{}
+		return json.Unmarshal(data, webhook)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// CreateWebhook stores a new webhook
+func (r *BoltTaskRepository) CreateWebhook(req *WebhookRequest) (*Webhook, error) {
+	webhook, err := req.Webhook()
+	if err != nil {
+		return nil, err
+	}
+	webhook.CreatedAt = r.clock.Now()
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltWebhooksBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		webhook.ID = int(seq)
+		data, err := json.Marshal(webhook)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(boltID(webhook.ID), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook. It returns sql.ErrNoRows when the
+// webhook does not exist.
+func (r *BoltTaskRepository) DeleteWebhook(id int) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltWebhooksBucket)
+		if bucket.Get(boltID(id)) == nil {
+			return sql.ErrNoRows
+		}
+		return bucket.Delete(boltID(id))
+	})
+}
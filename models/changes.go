@@ -0,0 +1,201 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Change types. A task's change is the latest write to it: created or
+// updated tasks are sent with their current state, deleted ones as
+// tombstones with only their ID.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// Change is an entry of the changes feed. Seq orders the feed; only
+// each task's latest change is kept, under a new Seq every time the task
+// changes, so reading from a Seq on yields every task changed since.
+type Change struct {
+	Seq       int64     `json:"seq"`
+	Type      string    `json:"type"`
+	TaskID    int       `json:"task_id"`
+	Task      *Task     `json:"task,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ListChanges returns up to limit changes after since, in order. The
+// task_changes table is kept by triggers on tasks, so every write path is
+// covered, batch and restore included.
+func (r *SQLiteTaskRepository) ListChanges(since int64, limit int) ([]Change, error) {
+	rows, err := r.db.Query(`SELECT seq, task_id, kind, changed_at FROM task_changes WHERE seq > ? ORDER BY seq LIMIT ?`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []Change{}
+	var ids []interface{}
+	for rows.Next() {
+		var change Change
+		if err := rows.Scan(&change.Seq, &change.TaskID, &change.Type, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		if change.Type != ChangeDeleted {
+			ids = append(ids, change.TaskID)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return changes, nil
+	}
+
+	taskRows, err := r.db.Query(`SELECT `+taskColumns+` FROM tasks WHERE id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer taskRows.Close()
+	tasks, err := scanTasks(taskRows)
+	if err != nil {
+		return nil, err
+	}
+	return attachChangedTasks(changes, tasks), nil
+}
+
+// GetChange returns the latest change of a task, without the task, or nil
+// when it has none
+func (r *SQLiteTaskRepository) GetChange(taskID int) (*Change, error) {
+	change := Change{TaskID: taskID}
+	err := r.db.QueryRow(`SELECT seq, kind, changed_at FROM task_changes WHERE task_id = ?`, taskID).Scan(&change.Seq, &change.Type, &change.ChangedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// attachChangedTasks sets the current state of the created and updated
+// tasks in changes. A task deleted since its change was read is left out;
+// its tombstone comes later in the feed.
+func attachChangedTasks(changes []Change, tasks []Task) []Change {
+	byID := make(map[int]*Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+	kept := changes[:0]
+	for _, change := range changes {
+		if change.Type != ChangeDeleted {
+			if change.Task = byID[change.TaskID]; change.Task == nil {
+				continue
+			}
+		}
+		kept = append(kept, change)
+	}
+	return kept
+}
+
+// boltChange is the stored form of a Change
+type boltChange struct {
+	TaskID    int       `json:"task_id"`
+	Type      string    `json:"type"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// boltRecordChange makes a change the latest change of task id, replacing
+// the one before
+func boltRecordChange(tx *bolt.Tx, id int, changeType string, at time.Time) error {
+	changes, index := tx.Bucket(boltChangesBucket), tx.Bucket(boltChangeIndexBucket)
+	if old := index.Get(boltID(id)); old != nil {
+		if err := changes.Delete(old); err != nil {
+			return err
+		}
+	}
+	seq, err := changes.NextSequence()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(boltChange{TaskID: id, Type: changeType, ChangedAt: at.UTC()})
+	if err != nil {
+		return err
+	}
+	key := boltID(int(seq))
+	if err := changes.Put(key, data); err != nil {
+		return err
+	}
+	return index.Put(boltID(id), key)
+}
+
+// BackfillBoltChanges records a change for every task that has none, such
+// as tasks stored before the changes feed existed
+func BackfillBoltChanges(tx *bolt.Tx) error {
+	index := tx.Bucket(boltChangeIndexBucket)
+	tasks, err := boltAllTasks(tx)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if index.Get(boltID(task.ID)) != nil {
+			continue
+		}
+		if err := boltRecordChange(tx, task.ID, ChangeCreated, task.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListChanges returns up to limit changes after since, in order
+func (r *BoltTaskRepository) ListChanges(since int64, limit int) ([]Change, error) {
+	changes := []Change{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltChangesBucket).Cursor()
+		for k, v := c.Seek(boltID(int(since + 1))); k != nil && len(changes) < limit; k, v = c.Next() {
+			var stored boltChange
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return err
+			}
+			change := Change{Seq: int64(binary.BigEndian.Uint64(k)), Type: stored.Type, TaskID: stored.TaskID, ChangedAt: stored.ChangedAt}
+			if change.Type != ChangeDeleted {
+				task, err := boltGetTask(tx, change.TaskID)
+				if err != nil {
+					return err
+				}
+				// Written in the same transaction as the change, so it exists
+				change.Task = task
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// GetChange returns the latest change of a task, without the task, or nil
+// when it has none
+func (r *BoltTaskRepository) GetChange(taskID int) (*Change, error) {
+	var change *Change
+	err := r.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(boltChangeIndexBucket).Get(boltID(taskID))
+		if key == nil {
+			return nil
+		}
+		var stored boltChange
+		if err := json.Unmarshal(tx.Bucket(boltChangesBucket).Get(key), &stored); err != nil {
+			return err
+		}
+		change = &Change{Seq: int64(binary.BigEndian.Uint64(key)), Type: stored.Type, TaskID: taskID, ChangedAt: stored.ChangedAt}
+		return nil
+	})
+	return change, err
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"expvar"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescingStats exposes hit/miss counters under /debug/vars
+var coalescingStats = expvar.NewMap("coalescing")
+
+// CoalescingTaskRepository wraps a TaskRepository so that identical concurrent
+// list reads share a single database query. Results are shared between callers
+// and must be treated as read-only.
+type CoalescingTaskRepository struct {
+	TaskRepository
+	group singleflight.Group
+}
+
+// NewCoalescingTaskRepository creates a coalescing wrapper around repo
+func NewCoalescingTaskRepository(repo TaskRepository) *CoalescingTaskRepository {
+	return &CoalescingTaskRepository{TaskRepository: repo}
+}
+
+// GetAllPaginated coalesces identical in-flight list queries
+func (r *CoalescingTaskRepository) GetAllPaginated(filter TaskFilter, limit int, offset int, sortBy string, sortOrder string) ([]Task, error) {
+	key, err := json.Marshal(struct {
+		Filter    TaskFilter
+		Limit     int
+		Offset    int
+		SortBy    string
+		SortOrder string
+	}{filter, limit, offset, sortBy, sortOrder})
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := r.do("list:"+string(key), func() (interface{}, error) {
+		return r.TaskRepository.GetAllPaginated(filter, limit, offset, sortBy, sortOrder)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]Task), nil
+}
+
+// Count coalesces identical in-flight count queries
+func (r *CoalescingTaskRepository) Count(filter TaskFilter) (int, error) {
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := r.do("count:"+string(key), func() (interface{}, error) {
+		return r.TaskRepository.Count(filter)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// Stats coalesces concurrent stats queries for the same instant
+func (r *CoalescingTaskRepository) Stats(now time.Time) (*TaskStats, error) {
+	v, err := r.do("stats:"+now.UTC().Format(time.RFC3339Nano), func() (interface{}, error) {
+		return r.TaskRepository.Stats(now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*TaskStats), nil
+}
+
+// do runs fn once per key among concurrent callers and records whether this
+// caller executed the query (miss) or joined one already in flight (hit)
+func (r *CoalescingTaskRepository) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	executed := false
+	v, err, _ := r.group.Do(key, func() (interface{}, error) {
+		executed = true
+		return fn()
+	})
+	if executed {
+		coalescingStats.Add("misses", 1)
+	} else {
+		coalescingStats.Add("hits", 1)
+	}
+	return v, err
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ColorPalette lists the named colors clients are expected to render
+var ColorPalette = []string{"red", "orange", "yellow", "green", "teal", "blue", "purple", "pink", "gray"}
+
+// hexColorPattern matches #rgb and #rrggbb colors
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// normalizeColor lowercases a color so palette names and hex values
+// compare and store consistently
+func normalizeColor(color string) string {
+	return strings.ToLower(strings.TrimSpace(color))
+}
+
+// validateColor checks that a color is empty, a palette name or a hex value
+func validateColor(color string) error {
+	if color == "" || containsString(ColorPalette, color) || hexColorPattern.MatchString(color) {
+		return nil
+	}
+	return &ValidationError{Field: "color", Message: "color must be one of " + strings.Join(ColorPalette, ", ") + " or a hex value such as #1e90ff"}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Encryption describes the client-held key a task's title and description
+// were encrypted with. The server never sees the key; it only stores this
+// metadata so clients know which key to decrypt with.
+type Encryption struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+}
+
+// maxEncryptionFieldLength bounds key_id and algorithm
+const maxEncryptionFieldLength = 128
+
+// EncryptionMode is the workspace-wide policy for encrypted task content
+type EncryptionMode string
+
+const (
+	// EncryptionOff rejects encrypted tasks
+	EncryptionOff EncryptionMode = "off"
+	// EncryptionOptional accepts both plaintext and encrypted tasks
+	EncryptionOptional EncryptionMode = "optional"
+	// EncryptionRequired rejects plaintext tasks
+	EncryptionRequired EncryptionMode = "required"
+)
+
+// ParseEncryptionMode parses an ENCRYPTION_MODE value; empty means off
+func ParseEncryptionMode(s string) (EncryptionMode, error) {
+	switch mode := EncryptionMode(s); mode {
+	case "":
+		return EncryptionOff, nil
+	case EncryptionOff, EncryptionOptional, EncryptionRequired:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("encryption mode must be off, optional or required, got %q", s)
+	}
+}
+
+// Check reports whether content with the given encryption metadata (nil for
+// plaintext) is allowed under the mode
+func (m EncryptionMode) Check(enc *Encryption) error {
+	if enc != nil && m != EncryptionOptional && m != EncryptionRequired {
+		return &ValidationError{Field: "encryption", Message: "encrypted tasks are disabled for this workspace"}
+	}
+	if enc == nil && m == EncryptionRequired {
+		return &ValidationError{Field: "encryption", Message: "this workspace requires encrypted tasks"}
+	}
+	return nil
+}
+
+// validateEncryption checks the key metadata and, for encrypted content,
+// that title and description are base64 ciphertext. Ciphertext is otherwise
+// opaque to the server.
+func validateEncryption(enc *Encryption, title, description string) error {
+	if enc == nil {
+		return nil
+	}
+	if enc.KeyID == "" || len(enc.KeyID) > maxEncryptionFieldLength {
+		return &ValidationError{Field: "encryption.key_id", Message: "key_id is required and must be at most 128 characters"}
+	}
+	if enc.Algorithm == "" || len(enc.Algorithm) > maxEncryptionFieldLength {
+		return &ValidationError{Field: "encryption.algorithm", Message: "algorithm is required and must be at most 128 characters"}
+	}
+	if !isBase64(title) {
+		return &ValidationError{Field: "title", Message: "encrypted title must be base64"}
+	}
+	if !isBase64(description) {
+		return &ValidationError{Field: "description", Message: "encrypted description must be base64"}
+	}
+	return nil
+}
+
+func isBase64(s string) bool {
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
+}
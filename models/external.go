@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// maxSourceLength and maxExternalIDLength bound an external reference
+const (
+	maxSourceLength     = 64
+	maxExternalIDLength = 255
+)
+
+// ValidateExternalRef checks the source and external ID a task is upserted
+// by. Sources are short slugs such as "todoist" or "jira"; external IDs are
+// whatever the other system uses.
+func ValidateExternalRef(source, externalID string) error {
+	if source == "" || len(source) > maxSourceLength {
+		return &ValidationError{Field: "source", Message: "source is required and must be at most 64 characters"}
+	}
+	for _, c := range source {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return &ValidationError{Field: "source", Message: "source may only contain lowercase letters, digits, '-', '_' and '.'"}
+		}
+	}
+	if externalID == "" || len(externalID) > maxExternalIDLength {
+		return &ValidationError{Field: "external_id", Message: "external_id is required and must be at most 255 characters"}
+	}
+	return nil
+}
+
+// UpsertExternal updates the task imported from source under externalID, or
+// creates it when there is none yet, and reports whether it was created.
+// Updates follow the rules of Update.
+func (r *SQLiteTaskRepository) UpsertExternal(source, externalID string, req *TaskRequest) (*Task, bool, error) {
+	for attempt := 0; ; attempt++ {
+		var id int
+		err := r.db.QueryRow(`SELECT id FROM tasks WHERE source = ? AND external_id = ?`, source, externalID).Scan(&id)
+		if err == nil {
+			task, err := r.Update(id, req, nil)
+			if task != nil || err != nil {
+				return task, false, err
+			}
+			// Deleted since the lookup; create it again
+		} else if err != sql.ErrNoRows {
+			return nil, false, err
+		}
+
+		task, err := r.createExternal(source, externalID, req)
+		if err != nil && attempt == 0 && isUniqueViolation(err) {
+			// A concurrent upsert created it first; update that one
+			continue
+		}
+		return task, err == nil, err
+	}
+}
+
+// createExternal inserts a task carrying an external reference
+func (r *SQLiteTaskRepository) createExternal(source, externalID string, req *TaskRequest) (*Task, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertTask(tx, req, r.clock.Now(), nextID(r.ids))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE tasks SET source = ?, external_id = ? WHERE id = ?`, source, externalID, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE constraint
+// failure; every supported driver reports it with this message
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// taskFieldColumns maps the task's JSON fields to the columns they are read
+// from. Computed fields need the columns they are computed from.
+var taskFieldColumns = map[string][]string{
+	"id":               {"id"},
+	"title":            {"title"},
+	"description":      {"description"},
+	"summary":          {"description", "encryption_key_id", "encryption_algorithm"},
+	"description_html": {"description", "encryption_key_id", "encryption_algorithm"},
+	"start_date":       {"start_date"},
+	"due_date":         {"due_date"},
+	"status":           {"status"},
+	"progress":         {"progress"},
+	"position":         {"position"},
+	"pinned":           {"pinned"},
+	"archived":         {"archived"},
+	"color":            {"color"},
+	"encryption":       {"encryption_key_id", "encryption_algorithm"},
+	"location":         {"latitude", "longitude", "place"},
+	"created_at":       {"created_at"},
+	"updated_at":       {"updated_at"},
+	"completed_at":     {"completed_at"},
+	"source":           {"source"},
+	"external_id":      {"external_id"},
+	"uuid":             {"uuid"},
+}
+
+// IsTaskField reports whether name is a field of the task's JSON
+func IsTaskField(name string) bool {
+	_, ok := taskFieldColumns[name]
+	return ok
+}
+
+// TaskFieldNames lists the task's JSON fields in alphabetical order
+func TaskFieldNames() []string {
+	names := make([]string, 0, len(taskFieldColumns))
+	for name := range taskFieldColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectColumns returns the column list for a query reading the given task
+// fields, or every column when there are none. The columns that cursors and
+// pinned-first ordering rely on are always read.
+func selectColumns(fields []string) []string {
+	if len(fields) == 0 {
+		return strings.Split(taskColumns, ", ")
+	}
+	wanted := map[string]bool{"id": true, "created_at": true, "pinned": true}
+	for _, field := range fields {
+		for _, column := range taskFieldColumns[field] {
+			wanted[column] = true
+		}
+	}
+	// Keep taskColumns' order
+	var columns []string
+	for _, column := range strings.Split(taskColumns, ", ") {
+		if wanted[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// scanTaskColumns scans a row selected with some of taskColumns, leaving
+// the other fields zero
+func scanTaskColumns(row rowScanner, columns []string) (Task, error) {
+	var task Task
+	var enc Encryption
+	var latitude, longitude *float64
+	var place string
+	targets := map[string]interface{}{
+		"id": &task.ID, "title": &task.Title, "description": &task.Description,
+		"start_date": &task.StartDate, "due_date": &task.DueDate, "status": &task.Status,
+		"progress": &task.Progress, "position": &task.Position, "pinned": &task.Pinned,
+		"archived": &task.Archived, "color": &task.Color,
+		"encryption_key_id": &enc.KeyID, "encryption_algorithm": &enc.Algorithm,
+		"created_at": &task.CreatedAt, "updated_at": &task.UpdatedAt, "completed_at": &task.CompletedAt,
+		"source": &task.Source, "external_id": &task.ExternalID,
+		"latitude": &latitude, "longitude": &longitude, "place": &place,
+		"uuid": &task.UUID,
+	}
+	dest := make([]interface{}, len(columns))
+	for i, column := range columns {
+		dest[i] = targets[column]
+	}
+	err := row.Scan(dest...)
+	if enc.KeyID != "" {
+		task.Encryption = &enc
+	}
+	if latitude != nil && longitude != nil {
+		task.Location = &Location{Latitude: *latitude, Longitude: *longitude, Place: place}
+	}
+	return task, err
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TaskFilter narrows the tasks returned by GetAllPaginated.
+// Nil or empty fields are not applied.
+type TaskFilter struct {
+	// Statuses matches tasks with any of the given statuses
+	Statuses    []string
+	StartAfter  *time.Time
+	StartBefore *time.Time
+	// StartableBy matches open tasks whose start date is unset or not later than this time
+	StartableBy *time.Time
+	// OverdueAt matches open tasks whose due date is before this time
+	OverdueAt     *time.Time
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// ProgressBelow and ProgressAtLeast bound the task's progress percentage
+	ProgressBelow   *int
+	ProgressAtLeast *int
+	// Archived, when set, matches only archived or only unarchived tasks
+	Archived *bool
+	// Open excludes completed tasks
+	Open bool
+	// Near matches tasks located within a circle
+	Near *GeoCircle
+	// Text matches tasks whose title or description contains it, ignoring
+	// case
+	Text string
+	// Query matches tasks satisfying a filter expression
+	Query *Query
+	// After matches tasks past a cursor position, for cursor pagination
+	After *Cursor
+	// External matches the task imported under a source and external ID
+	External *ExternalRef
+	// UUID selects the task created under this client-generated UUID
+	UUID string
+	// Fields limits list queries to the columns these task JSON fields are
+	// read from; empty reads them all. Selection ignores it, and storage
+	// that can't read single columns returns whole tasks.
+	Fields []string
+}
+
+// ExternalRef names a task imported from another system
+type ExternalRef struct {
+	Source     string
+	ExternalID string
+}
+
+// Cursor is a position in the (pinned, created_at, id) ordering used by
+// cursor pagination. Pinned tasks come first whatever the direction.
+type Cursor struct {
+	Pinned    bool
+	CreatedAt time.Time
+	ID        int
+	// Desc selects the tasks that follow the position in descending order
+	Desc bool
+}
+
+// follows reports whether task comes after the cursor position
+func (c Cursor) follows(task Task) bool {
+	if task.Pinned != c.Pinned {
+		return c.Pinned
+	}
+	if task.CreatedAt.Equal(c.CreatedAt) {
+		if c.Desc {
+			return task.ID < c.ID
+		}
+		return task.ID > c.ID
+	}
+	if c.Desc {
+		return task.CreatedAt.Before(c.CreatedAt)
+	}
+	return task.CreatedAt.After(c.CreatedAt)
+}
+
+// whereClause builds the SQL WHERE clause and arguments for the filter
+func (f TaskFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(f.Statuses) > 0 {
+		placeholders := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			placeholders[i] = "?"
+			args = append(args, status)
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if f.StartAfter != nil {
+		conditions = append(conditions, "start_date >= ?")
+		args = append(args, f.StartAfter.UTC())
+	}
+	if f.StartBefore != nil {
+		conditions = append(conditions, "start_date <= ?")
+		args = append(args, f.StartBefore.UTC())
+	}
+	if f.StartableBy != nil {
+		conditions = append(conditions, "(start_date IS NULL OR start_date <= ?) AND status != 'completed'")
+		args = append(args, f.StartableBy.UTC())
+	}
+	if f.OverdueAt != nil {
+		conditions = append(conditions, "due_date < ? AND status != 'completed'")
+		args = append(args, f.OverdueAt.UTC())
+	}
+	if f.DueAfter != nil {
+		conditions = append(conditions, "due_date >= ?")
+		args = append(args, f.DueAfter.UTC())
+	}
+	if f.DueBefore != nil {
+		conditions = append(conditions, "due_date <= ?")
+		args = append(args, f.DueBefore.UTC())
+	}
+	if f.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.CreatedAfter.UTC())
+	}
+	if f.CreatedBefore != nil {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, f.CreatedBefore.UTC())
+	}
+	if f.ProgressBelow != nil {
+		conditions = append(conditions, "progress < ?")
+		args = append(args, *f.ProgressBelow)
+	}
+	if f.ProgressAtLeast != nil {
+		conditions = append(conditions, "progress >= ?")
+		args = append(args, *f.ProgressAtLeast)
+	}
+	if f.Archived != nil {
+		conditions = append(conditions, "archived = ?")
+		args = append(args, *f.Archived)
+	}
+	if f.Open {
+		conditions = append(conditions, "status != 'completed'")
+	}
+	if f.Near != nil {
+		condition, nearArgs := f.Near.sqlCondition()
+		conditions = append(conditions, condition)
+		args = append(args, nearArgs...)
+	}
+	if f.Text != "" {
+		conditions = append(conditions, `(title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`)
+		pattern := "%" + likeEscaper.Replace(f.Text) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if f.Query != nil {
+		condition, queryArgs := f.Query.whereCondition()
+		conditions = append(conditions, condition)
+		args = append(args, queryArgs...)
+	}
+	if f.After != nil {
+		op := ">"
+		if f.After.Desc {
+			op = "<"
+		}
+		conditions = append(conditions, "(pinned < ? OR (pinned = ? AND (created_at "+op+" ? OR (created_at = ? AND id "+op+" ?))))")
+		pinned := 0
+		if f.After.Pinned {
+			pinned = 1
+		}
+		createdAt := f.After.CreatedAt.UTC()
+		args = append(args, pinned, pinned, createdAt, createdAt, f.After.ID)
+	}
+	if f.External != nil {
+		conditions = append(conditions, "source = ? AND external_id = ?")
+		args = append(args, f.External.Source, f.External.ExternalID)
+	}
+	if f.UUID != "" {
+		conditions = append(conditions, "uuid = ?")
+		args = append(args, f.UUID)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Matches reports whether a task satisfies the filter
+func (f TaskFilter) Matches(task Task) bool {
+	if len(f.Statuses) > 0 && !containsString(f.Statuses, task.Status) {
+		return false
+	}
+	if f.StartAfter != nil && (task.StartDate == nil || task.StartDate.Before(*f.StartAfter)) {
+		return false
+	}
+	if f.StartBefore != nil && (task.StartDate == nil || task.StartDate.After(*f.StartBefore)) {
+		return false
+	}
+	if f.StartableBy != nil {
+		if task.Status == "completed" {
+			return false
+		}
+		if task.StartDate != nil && task.StartDate.After(*f.StartableBy) {
+			return false
+		}
+	}
+	if f.OverdueAt != nil {
+		if task.Status == "completed" || task.DueDate == nil || !task.DueDate.Before(*f.OverdueAt) {
+			return false
+		}
+	}
+	if f.DueAfter != nil && (task.DueDate == nil || task.DueDate.Before(*f.DueAfter)) {
+		return false
+	}
+	if f.DueBefore != nil && (task.DueDate == nil || task.DueDate.After(*f.DueBefore)) {
+		return false
+	}
+	if f.CreatedAfter != nil && task.CreatedAt.Before(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && task.CreatedAt.After(*f.CreatedBefore) {
+		return false
+	}
+	if f.ProgressBelow != nil && task.Progress >= *f.ProgressBelow {
+		return false
+	}
+	if f.ProgressAtLeast != nil && task.Progress < *f.ProgressAtLeast {
+		return false
+	}
+	if f.Archived != nil && task.Archived != *f.Archived {
+		return false
+	}
+	if f.Open && task.Status == "completed" {
+		return false
+	}
+	if f.Near != nil && !f.Near.Contains(task.Location) {
+		return false
+	}
+	if f.Text != "" && !containsFold(task.Title, f.Text) && !containsFold(task.Description, f.Text) {
+		return false
+	}
+	if f.Query != nil && !f.Query.Matches(task) {
+		return false
+	}
+	if f.After != nil && !f.After.follows(task) {
+		return false
+	}
+	if f.External != nil && (task.Source != f.External.Source || task.ExternalID != f.External.ExternalID) {
+		return false
+	}
+	if f.UUID != "" && task.UUID != f.UUID {
+		return false
+	}
+	return true
+}
+
+// likeEscaper escapes the LIKE wildcards in text searched for
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsFold reports whether s contains substr, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrDuplicateID is returned when an IDGenerator hands out an ID that is
+// already taken
+var ErrDuplicateID = errors.New("task id already in use")
+
+// IDGenerator chooses the ID of each new task. NextID returning 0 leaves
+// the choice to the storage backend's own sequence.
+type IDGenerator interface {
+	NextID() int
+}
+
+// SequentialIDs hands out consecutive IDs starting from a fixed value
+type SequentialIDs struct {
+	mu   sync.Mutex
+	next int
+}
+
+// NewSequentialIDs creates a generator whose first ID is start
+func NewSequentialIDs(start int) *SequentialIDs {
+	return &SequentialIDs{next: start}
+}
+
+// NextID returns the next ID in the sequence
+func (g *SequentialIDs) NextID() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := g.next
+	g.next++
+	return id
+}
+
+// FixedIDs hands out a predetermined list of IDs, such as the source IDs of
+// an import or the IDs a golden file expects, then defers to the backend
+type FixedIDs struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+// NewFixedIDs creates a generator that returns ids in order
+func NewFixedIDs(ids ...int) *FixedIDs {
+	return &FixedIDs{ids: ids}
+}
+
+// NextID returns the next queued ID, or 0 once the list is exhausted
+func (g *FixedIDs) NextID() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.ids) == 0 {
+		return 0
+	}
+	id := g.ids[0]
+	g.ids = g.ids[1:]
+	return id
+}
+
+// nextID asks gen for an ID, treating a nil generator as "use the backend"
+func nextID(gen IDGenerator) int {
+	if gen == nil {
+		return 0
+	}
+	return gen.NextID()
+}
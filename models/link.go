@@ -0,0 +1,148 @@
+package models
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxLinkURLLength caps the stored URL so links stay reasonable to render
+const maxLinkURLLength = 2048
+
+// Link is a titled URL attached to a task, such as a pull request, design
+// doc or ticket
+type Link struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LinkRequest represents the request payload for creating or replacing a link
+type LinkRequest struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Normalize cleans up the link's free-text fields
+func (lr *LinkRequest) Normalize() {
+	lr.Title = strings.TrimSpace(normalizeTitle(lr.Title))
+	lr.URL = strings.TrimSpace(lr.URL)
+}
+
+// Validate validates the link request. The URL must be absolute http or
+// https; the title is optional.
+func (lr *LinkRequest) Validate() error {
+	if lr.URL == "" {
+		return &ValidationError{Field: "url", Message: "url is required"}
+	}
+	if len(lr.URL) > maxLinkURLLength {
+		return &ValidationError{Field: "url", Message: "url must be at most 2048 characters"}
+	}
+	u, err := url.Parse(lr.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Field: "url", Message: "url must be an absolute http or https URL"}
+	}
+	return nil
+}
+
+// linkColumns is the column list shared by every link SELECT
+const linkColumns = "id, task_id, title, url, created_at"
+
+func scanLink(row rowScanner) (Link, error) {
+	var link Link
+	err := row.Scan(&link.ID, &link.TaskID, &link.Title, &link.URL, &link.CreatedAt)
+	return link, err
+}
+
+// taskExists reports whether a task with the given ID is stored
+func (r *SQLiteTaskRepository) taskExists(id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM tasks WHERE id = ?)`, id).Scan(&exists)
+	return exists, err
+}
+
+// ListLinks returns a task's links in the order they were added, or nil
+// when the task does not exist
+func (r *SQLiteTaskRepository) ListLinks(taskID int) ([]Link, error) {
+	exists, err := r.taskExists(taskID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT `+linkColumns+` FROM task_links WHERE task_id = ? ORDER BY id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []Link{}
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// CreateLink attaches a new link to a task, returning nil when the task does
+// not exist
+func (r *SQLiteTaskRepository) CreateLink(taskID int, req *LinkRequest) (*Link, error) {
+	exists, err := r.taskExists(taskID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	now := r.clock.Now()
+	result, err := r.db.Exec(`INSERT INTO task_links (task_id, title, url, created_at) VALUES (?, ?, ?, ?)`, taskID, req.Title, req.URL, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &Link{ID: int(id), TaskID: taskID, Title: req.Title, URL: req.URL, CreatedAt: now}, nil
+}
+
+// UpdateLink replaces a link's title and URL, returning nil when the task or
+// link does not exist
+func (r *SQLiteTaskRepository) UpdateLink(taskID, linkID int, req *LinkRequest) (*Link, error) {
+	result, err := r.db.Exec(`UPDATE task_links SET title = ?, url = ? WHERE id = ? AND task_id = ?`, req.Title, req.URL, linkID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, nil
+	}
+
+	link, err := scanLink(r.db.QueryRow(`SELECT `+linkColumns+` FROM task_links WHERE id = ?`, linkID))
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// DeleteLink removes a link from a task
+func (r *SQLiteTaskRepository) DeleteLink(taskID, linkID int) error {
+	result, err := r.db.Exec(`DELETE FROM task_links WHERE id = ? AND task_id = ?`, linkID, taskID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// maxPlaceLength bounds a location's place name
+const maxPlaceLength = 200
+
+// maxRadiusKm bounds a proximity search; the distance approximation is
+// poor beyond this
+const maxRadiusKm = 1000
+
+// kmPerDegree is the length of one degree of latitude, and of longitude at
+// the equator, on a spherical Earth
+const kmPerDegree = 111.195
+
+// Location ties a task to a point, such as the store for "buy milk", with an
+// optional human-readable place name
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Place     string  `json:"place,omitempty"`
+}
+
+// Normalize cleans up the place name
+func (l *Location) Normalize() {
+	if l != nil {
+		l.Place = strings.TrimSpace(normalizeTitle(l.Place))
+	}
+}
+
+// validateLocation checks the coordinates and place name; nil is valid
+func validateLocation(l *Location) error {
+	if l == nil {
+		return nil
+	}
+	if math.IsNaN(l.Latitude) || l.Latitude < -90 || l.Latitude > 90 {
+		return &ValidationError{Field: "location.latitude", Message: "latitude must be between -90 and 90"}
+	}
+	if math.IsNaN(l.Longitude) || l.Longitude < -180 || l.Longitude > 180 {
+		return &ValidationError{Field: "location.longitude", Message: "longitude must be between -180 and 180"}
+	}
+	if len(l.Place) > maxPlaceLength {
+		return &ValidationError{Field: "location.place", Message: "place must be at most 200 characters"}
+	}
+	return nil
+}
+
+// locationColumns flattens a location into its latitude, longitude and
+// place columns; tasks without one store NULL coordinates
+func locationColumns(l *Location) (*float64, *float64, string) {
+	if l == nil {
+		return nil, nil, ""
+	}
+	return &l.Latitude, &l.Longitude, l.Place
+}
+
+// OptionalLocation distinguishes an omitted location from an explicit null
+// that removes it
+type OptionalLocation struct {
+	Set   bool
+	Value *Location
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (o *OptionalLocation) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		o.Value = nil
+		return nil
+	}
+	var l Location
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	o.Value = &l
+	return nil
+}
+
+// GeoCircle matches tasks located within RadiusKm of a point. Distances use
+// an equirectangular approximation, which is accurate to well under 1% for
+// the few-kilometre radii location reminders use, and can be evaluated in
+// plain SQL. Circles spanning the antimeridian are not handled.
+type GeoCircle struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// Validate checks the centre and radius
+func (c GeoCircle) Validate() error {
+	if err := validateLocation(&Location{Latitude: c.Latitude, Longitude: c.Longitude}); err != nil {
+		return err
+	}
+	if !(c.RadiusKm > 0 && c.RadiusKm <= maxRadiusKm) {
+		return &ValidationError{Field: "radius_km", Message: "radius_km must be greater than 0 and at most 1000"}
+	}
+	return nil
+}
+
+// lngScale shrinks longitude differences to match latitude degrees at the
+// circle's latitude
+func (c GeoCircle) lngScale() float64 {
+	return math.Cos(c.Latitude * math.Pi / 180)
+}
+
+// maxDegrees2 is the squared radius in degrees of latitude
+func (c GeoCircle) maxDegrees2() float64 {
+	r := c.RadiusKm / kmPerDegree
+	return r * r
+}
+
+// Contains reports whether a location lies within the circle
+func (c GeoCircle) Contains(l *Location) bool {
+	if l == nil {
+		return false
+	}
+	dx := (l.Longitude - c.Longitude) * c.lngScale()
+	dy := l.Latitude - c.Latitude
+	return dx*dx+dy*dy <= c.maxDegrees2()
+}
+
+// sqlCondition returns the WHERE condition and arguments matching Contains
+func (c GeoCircle) sqlCondition() (string, []interface{}) {
+	scale := c.lngScale()
+	return "latitude IS NOT NULL AND ((longitude - ?) * ?) * ((longitude - ?) * ?) + (latitude - ?) * (latitude - ?) <= ?",
+		[]interface{}{c.Longitude, scale, c.Longitude, scale, c.Latitude, c.Latitude, c.maxDegrees2()}
+}
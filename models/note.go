@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Note is a timestamped journal entry appended to a task. Notes are kept
+// separately from the description so progress logs survive description edits.
+type Note struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NoteRequest represents the request payload for appending a note
+type NoteRequest struct {
+	Body string `json:"body"`
+}
+
+// Normalize cleans up the note body
+func (nr *NoteRequest) Normalize() {
+	nr.Body = strings.TrimSpace(normalizeDescription(nr.Body))
+}
+
+// Validate validates the note request
+func (nr *NoteRequest) Validate() error {
+	if nr.Body == "" {
+		return &ValidationError{Field: "body", Message: "body is required"}
+	}
+	return nil
+}
+
+// noteColumns is the column list shared by every note SELECT
+const noteColumns = "id, task_id, body, created_at"
+
+// ListNotes returns a task's notes oldest first, or nil when the task does
+// not exist
+func (r *SQLiteTaskRepository) ListNotes(taskID int) ([]Note, error) {
+	exists, err := r.taskExists(taskID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT `+noteColumns+` FROM task_notes WHERE task_id = ? ORDER BY created_at, id`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		var note Note
+		if err := rows.Scan(&note.ID, &note.TaskID, &note.Body, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// AddNote appends a note to a task, returning nil when the task does not
+// exist
+func (r *SQLiteTaskRepository) AddNote(taskID int, req *NoteRequest) (*Note, error) {
+	exists, err := r.taskExists(taskID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	now := r.clock.Now()
+	result, err := r.db.Exec(`INSERT INTO task_notes (task_id, body, created_at) VALUES (?, ?, ?)`, taskID, req.Body, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &Note{ID: int(id), TaskID: taskID, Body: req.Body, CreatedAt: now}, nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// OptionalTime distinguishes an omitted JSON field (Set is false) from an
+// explicit null (Set is true, Value is nil)
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (o *OptionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		o.Value = nil
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+// TaskPatch represents a partial update. Omitted fields are left unchanged;
+// description may be set to "" and dates may be cleared with null.
+type TaskPatch struct {
+	Title       *string      `json:"title"`
+	Description *string      `json:"description"`
+	StartDate   OptionalTime `json:"start_date"`
+	DueDate     OptionalTime `json:"due_date"`
+	Status      *string      `json:"status"`
+	Progress    *int         `json:"progress"`
+	Archived    *bool        `json:"archived"`
+	Color       *string      `json:"color"`
+	// Encryption replaces the key metadata of an encrypted task; turning
+	// encryption off needs a full PUT with plaintext title and description
+	Encryption *Encryption `json:"encryption"`
+	// Location replaces the task's location; null removes it
+	Location OptionalLocation `json:"location"`
+}
+
+// Validate validates the patch on its own
+func (p *TaskPatch) Validate() error {
+	if p.Title != nil && *p.Title == "" {
+		return &ValidationError{Field: "title", Message: "title cannot be empty"}
+	}
+	if p.Status != nil && !isValidStatus(*p.Status) {
+		return &ValidationError{Field: "status", Message: "status must be one of: pending, in_progress, completed"}
+	}
+	if p.Color != nil {
+		if err := validateColor(*p.Color); err != nil {
+			return err
+		}
+	}
+	if err := validateLocation(p.Location.Value); err != nil {
+		return err
+	}
+	return validateProgress(p.Progress)
+}
+
+// Apply merges the patch into task and validates the result
+func (p *TaskPatch) Apply(task *Task) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	startDate, dueDate := task.StartDate, task.DueDate
+	if p.StartDate.Set {
+		startDate = p.StartDate.Value
+	}
+	if p.DueDate.Set {
+		dueDate = p.DueDate.Value
+	}
+	if err := validateSchedule(startDate, dueDate); err != nil {
+		return err
+	}
+
+	if p.Title != nil {
+		task.Title = *p.Title
+	}
+	if p.Description != nil {
+		task.Description = *p.Description
+	}
+	if p.Status != nil {
+		task.Status = *p.Status
+	}
+	if p.Progress != nil {
+		task.Progress = *p.Progress
+	}
+	if p.Archived != nil {
+		task.Archived = *p.Archived
+	}
+	if p.Color != nil {
+		task.Color = *p.Color
+	}
+	if p.Encryption != nil {
+		task.Encryption = p.Encryption
+	}
+	if p.Location.Set {
+		task.Location = p.Location.Value
+	}
+	task.StartDate = startDate
+	task.DueDate = dueDate
+	return validateEncryption(task.Encryption, task.Title, task.Description)
+}
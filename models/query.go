@@ -0,0 +1,560 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query limits, so one filter can't make an expensive statement
+const (
+	maxQueryLength = 1000
+	maxQueryTerms  = 50
+	maxQueryDepth  = 10
+)
+
+// queryFields are the fields a query can filter on
+var queryFields = []string{"status", "title", "description", "progress", "pinned", "archived", "color", "source", "due", "start", "created", "completed"}
+
+// Query is a parsed filter expression such as
+//
+//	status:pending AND (due<today OR pinned:true) NOT "weekly review"
+//
+// Terms are field:value, or field<value, <=, > and >= for progress and
+// dates; a bare word or "quoted phrase" matches the title or description.
+// Terms are combined with AND, OR, NOT and parentheses; adjacent terms are
+// ANDed. A query becomes a parameterized SQL condition, so values never
+// reach the SQL text, and can also be evaluated on a task directly.
+type Query struct {
+	source string
+	root   queryNode
+	fields map[string]bool
+}
+
+// queryNode is a node of a parsed query
+type queryNode interface {
+	sql() (string, []interface{})
+	matches(task Task) bool
+}
+
+type andNode struct{ left, right queryNode }
+
+func (n andNode) sql() (string, []interface{}) {
+	l, largs := n.left.sql()
+	r, rargs := n.right.sql()
+	return "(" + l + " AND " + r + ")", append(largs, rargs...)
+}
+
+func (n andNode) matches(task Task) bool {
+	return n.left.matches(task) && n.right.matches(task)
+}
+
+type orNode struct{ left, right queryNode }
+
+func (n orNode) sql() (string, []interface{}) {
+	l, largs := n.left.sql()
+	r, rargs := n.right.sql()
+	return "(" + l + " OR " + r + ")", append(largs, rargs...)
+}
+
+func (n orNode) matches(task Task) bool {
+	return n.left.matches(task) || n.right.matches(task)
+}
+
+type notNode struct{ node queryNode }
+
+func (n notNode) sql() (string, []interface{}) {
+	s, args := n.node.sql()
+	return "NOT " + s, args
+}
+
+func (n notNode) matches(task Task) bool {
+	return !n.node.matches(task)
+}
+
+// termNode is a single condition. Conditions on nullable columns test for
+// NULL themselves, so NOT of a condition is true wherever the condition is
+// false, as it is in matches.
+type termNode struct {
+	condition string
+	args      []interface{}
+	match     func(task Task) bool
+}
+
+func (n termNode) sql() (string, []interface{}) {
+	return "(" + n.condition + ")", n.args
+}
+
+func (n termNode) matches(task Task) bool {
+	return n.match(task)
+}
+
+// ParseQuery parses a filter expression. Dates may be "today", which is
+// resolved against now.
+func ParseQuery(source string, now time.Time) (*Query, error) {
+	if len(source) > maxQueryLength {
+		return nil, fmt.Errorf("filter must be at most %d characters", maxQueryLength)
+	}
+	tokens, err := lexQuery(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("filter is empty")
+	}
+	p := &queryParser{tokens: tokens, now: now, fields: map[string]bool{}}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+	return &Query{source: source, root: root, fields: p.fields}, nil
+}
+
+// String returns the expression the query was parsed from
+func (q *Query) String() string {
+	return q.source
+}
+
+// MarshalJSON encodes the query as its expression, so filters holding
+// different queries never encode alike
+func (q *Query) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.source)
+}
+
+// Refers reports whether the query filters on field
+func (q *Query) Refers(field string) bool {
+	return q.fields[field]
+}
+
+// Matches reports whether a task satisfies the query
+func (q *Query) Matches(task Task) bool {
+	return q.root.matches(task)
+}
+
+// whereCondition returns the query as a SQL condition with its arguments
+func (q *Query) whereCondition() (string, []interface{}) {
+	return q.root.sql()
+}
+
+// Token kinds
+const (
+	tokenWord = iota
+	tokenTerm
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// queryToken is a lexed token. A term has a field, operator and value; a
+// word has only a value.
+type queryToken struct {
+	kind  int
+	field string
+	op    string
+	value string
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokenOpen:
+		return `"("`
+	case tokenClose:
+		return `")"`
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenTerm:
+		return strconv.Quote(t.field + t.op + t.value)
+	}
+	return strconv.Quote(t.value)
+}
+
+// lexQuery splits an expression into tokens
+func lexQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose})
+			i++
+			continue
+		}
+
+		// A term starts with a field name followed by an operator
+		j := i
+		for j < len(s) && (s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z' || s[j] == '_') {
+			j++
+		}
+		if j > i && j < len(s) && strings.IndexByte(":=<>", s[j]) >= 0 {
+			field := strings.ToLower(s[i:j])
+			op := string(s[j])
+			j++
+			if (op == "<" || op == ">") && j < len(s) && s[j] == '=' {
+				op += "="
+				j++
+			}
+			if op == "=" {
+				op = ":"
+			}
+			value, next, err := lexValue(s, j)
+			if err != nil {
+				return nil, err
+			}
+			if value == "" {
+				return nil, fmt.Errorf("%s%s needs a value", field, op)
+			}
+			tokens = append(tokens, queryToken{kind: tokenTerm, field: field, op: op, value: value})
+			i = next
+			continue
+		}
+
+		quoted := c == '"'
+		value, next, err := lexValue(s, i)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+		if !quoted {
+			switch strings.ToUpper(value) {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd})
+				continue
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot})
+				continue
+			}
+		}
+		tokens = append(tokens, queryToken{kind: tokenWord, value: value})
+	}
+	return tokens, nil
+}
+
+// lexValue reads a value at s[i:]: a "quoted string", in which \" and \\
+// are escapes, or text up to a space or parenthesis. It returns the value
+// and the index after it.
+func lexValue(s string, i int) (string, int, error) {
+	if i < len(s) && s[i] == '"' {
+		var b strings.Builder
+		for j := i + 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				if j+1 < len(s) {
+					j++
+					b.WriteByte(s[j])
+				}
+			case '"':
+				return b.String(), j + 1, nil
+			default:
+				b.WriteByte(s[j])
+			}
+		}
+		return "", 0, errors.New("unterminated quoted string")
+	}
+	j := i
+	for j < len(s) && strings.IndexByte(" \t\n\r()", s[j]) < 0 {
+		j++
+	}
+	return s[i:j], j, nil
+}
+
+// queryParser is a recursive descent parser over the tokens:
+//
+//	or   = and { OR and }
+//	and  = not { [AND] not }
+//	not  = NOT not | atom
+//	atom = "(" or ")" | term | word
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	terms  int
+	now    time.Time
+	fields map[string]bool
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr(depth int) (queryNode, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *queryParser) parseAnd(depth int) (queryNode, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenClose {
+			return left, nil
+		}
+		if tok.kind == tokenAnd {
+			p.pos++
+		}
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *queryParser) parseNot(depth int) (queryNode, error) {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokenNot {
+		p.pos++
+		node, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return p.parseAtom(depth)
+}
+
+func (p *queryParser) parseAtom(depth int) (queryNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, errors.New("filter ends where a term was expected")
+	}
+	p.pos++
+	switch tok.kind {
+	case tokenOpen:
+		if depth >= maxQueryDepth {
+			return nil, fmt.Errorf("filter may nest parentheses at most %d deep", maxQueryDepth)
+		}
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenClose {
+			return nil, errors.New(`missing ")"`)
+		}
+		p.pos++
+		return node, nil
+	case tokenTerm, tokenWord:
+		p.terms++
+		if p.terms > maxQueryTerms {
+			return nil, fmt.Errorf("filter may have at most %d terms", maxQueryTerms)
+		}
+		if tok.kind == tokenWord {
+			p.fields["title"], p.fields["description"] = true, true
+			return textTerm(tok.value), nil
+		}
+		p.fields[tok.field] = true
+		return p.term(tok)
+	}
+	return nil, fmt.Errorf("unexpected %s", tok)
+}
+
+// textTerm matches tasks whose title or description contains text
+func textTerm(text string) queryNode {
+	pattern := "%" + likeEscaper.Replace(text) + "%"
+	return termNode{
+		condition: `title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\'`,
+		args:      []interface{}{pattern, pattern},
+		match: func(task Task) bool {
+			return containsFold(task.Title, text) || containsFold(task.Description, text)
+		},
+	}
+}
+
+// term builds the condition of a field term
+func (p *queryParser) term(tok queryToken) (queryNode, error) {
+	if !containsString(queryFields, tok.field) {
+		return nil, fmt.Errorf("%q is not a field; filters can use %s", tok.field, strings.Join(queryFields, ", "))
+	}
+	ordered := tok.op != ":"
+	switch tok.field {
+	case "due", "start", "created", "completed":
+		return p.dateTerm(tok)
+	case "progress":
+		n, err := strconv.Atoi(tok.value)
+		if err != nil || n < 0 || n > 100 {
+			return nil, fmt.Errorf("progress must be a number from 0 to 100, not %q", tok.value)
+		}
+		op := tok.op
+		if op == ":" {
+			op = "="
+		}
+		return termNode{
+			condition: "progress " + op + " ?",
+			args:      []interface{}{n},
+			match:     func(task Task) bool { return compareInts(task.Progress, op, n) },
+		}, nil
+	}
+	if ordered {
+		return nil, fmt.Errorf("%s can't be compared with %s; use %s:value", tok.field, tok.op, tok.field)
+	}
+
+	switch tok.field {
+	case "status":
+		if !isValidStatus(tok.value) {
+			return nil, fmt.Errorf("status must be one of: pending, in_progress, completed, not %q", tok.value)
+		}
+		status := tok.value
+		return termNode{condition: "status = ?", args: []interface{}{status}, match: func(task Task) bool { return task.Status == status }}, nil
+	case "title":
+		pattern, text := "%"+likeEscaper.Replace(tok.value)+"%", tok.value
+		return termNode{condition: `title LIKE ? ESCAPE '\'`, args: []interface{}{pattern}, match: func(task Task) bool { return containsFold(task.Title, text) }}, nil
+	case "description":
+		pattern, text := "%"+likeEscaper.Replace(tok.value)+"%", tok.value
+		return termNode{condition: `description LIKE ? ESCAPE '\'`, args: []interface{}{pattern}, match: func(task Task) bool { return containsFold(task.Description, text) }}, nil
+	case "pinned", "archived":
+		b, err := strconv.ParseBool(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, not %q", tok.field, tok.value)
+		}
+		field := tok.field
+		return termNode{condition: field + " = ?", args: []interface{}{b}, match: func(task Task) bool {
+			if field == "pinned" {
+				return task.Pinned == b
+			}
+			return task.Archived == b
+		}}, nil
+	case "color":
+		color := strings.ToLower(tok.value)
+		return termNode{condition: "color = ?", args: []interface{}{color}, match: func(task Task) bool { return task.Color == color }}, nil
+	case "source":
+		source := tok.value
+		return termNode{condition: "source = ?", args: []interface{}{source}, match: func(task Task) bool { return task.Source == source }}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", tok.field)
+}
+
+// dateColumns maps the date fields to their columns
+var dateColumns = map[string]string{"due": "due_date", "start": "start_date", "created": "created_at", "completed": "completed_at"}
+
+// dateTerm builds the condition of a date field. A date without a time
+// covers the whole day: due:2025-06-30 is any time that day, due<2025-06-30
+// before it and due<=2025-06-30 until its end. due:none and due:any match
+// tasks without and with a due date.
+func (p *queryParser) dateTerm(tok queryToken) (queryNode, error) {
+	column := dateColumns[tok.field]
+	field := tok.field
+	value := func(task Task) *time.Time {
+		switch field {
+		case "due":
+			return task.DueDate
+		case "start":
+			return task.StartDate
+		case "completed":
+			return task.CompletedAt
+		}
+		created := task.CreatedAt
+		return &created
+	}
+
+	if tok.op == ":" && (tok.value == "none" || tok.value == "any") {
+		set := tok.value == "any"
+		condition := column + " IS NULL"
+		if set {
+			condition = column + " IS NOT NULL"
+		}
+		return termNode{condition: condition, match: func(task Task) bool { return (value(task) != nil) == set }}, nil
+	}
+
+	from, until, err := p.parseDate(tok.value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", tok.field, err)
+	}
+	var condition string
+	var args []interface{}
+	var match func(t time.Time) bool
+	switch tok.op {
+	case ":":
+		condition, args = column+" >= ? AND "+column+" <= ?", []interface{}{from.UTC(), until.UTC()}
+		match = func(t time.Time) bool { return !t.Before(from) && !t.After(until) }
+	case "<":
+		condition, args = column+" < ?", []interface{}{from.UTC()}
+		match = func(t time.Time) bool { return t.Before(from) }
+	case "<=":
+		condition, args = column+" <= ?", []interface{}{until.UTC()}
+		match = func(t time.Time) bool { return !t.After(until) }
+	case ">":
+		condition, args = column+" > ?", []interface{}{until.UTC()}
+		match = func(t time.Time) bool { return t.After(until) }
+	case ">=":
+		condition, args = column+" >= ?", []interface{}{from.UTC()}
+		match = func(t time.Time) bool { return !t.Before(from) }
+	}
+	return termNode{
+		condition: column + " IS NOT NULL AND " + condition,
+		args:      args,
+		match: func(task Task) bool {
+			t := value(task)
+			return t != nil && match(*t)
+		},
+	}, nil
+}
+
+// parseDate parses an RFC 3339 timestamp, which is a single instant, or a
+// YYYY-MM-DD date or "today", which span the day
+func (p *queryParser) parseDate(v string) (from, until time.Time, err error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, t, nil
+	}
+	var day time.Time
+	if strings.EqualFold(v, "today") {
+		day = time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+	} else if day, err = time.Parse("2006-01-02", v); err != nil {
+		return from, until, fmt.Errorf("%q must be an RFC 3339 timestamp, a YYYY-MM-DD date, \"today\", \"none\" or \"any\"", v)
+	}
+	return day, day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// compareInts applies a SQL comparison operator to two ints
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return a == b
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRestoreConflict is returned when a snapshot can no longer be restored,
+// because another task has since taken its external ID
+var ErrRestoreConflict = errors.New("task cannot be restored")
+
+// TaskSnapshot is a task as it was before a destructive change, with the
+// links and notes that were deleted along with it
+type TaskSnapshot struct {
+	Task  Task   `json:"task"`
+	Links []Link `json:"links,omitempty"`
+	Notes []Note `json:"notes,omitempty"`
+}
+
+// RestoreTasks writes snapshots back in a single transaction. Tasks that
+// still exist are overwritten; deleted ones are recreated with their
+// original IDs, links and notes.
+func (r *SQLiteTaskRepository) RestoreTasks(snapshots []TaskSnapshot) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	columns := strings.Split(taskColumns, ", ")
+	set := make([]string, 0, len(columns)-1)
+	for _, column := range columns[1:] {
+		set = append(set, column+" = excluded."+column)
+	}
+	query := `INSERT INTO tasks (` + taskColumns + `) VALUES (?` + strings.Repeat(", ?", len(columns)-1) + `)
+		ON CONFLICT(id) DO UPDATE SET ` + strings.Join(set, ", ")
+
+	for _, snapshot := range snapshots {
+		t := snapshot.Task
+		enc := encryptionColumns(t.Encryption)
+		latitude, longitude, place := locationColumns(t.Location)
+		_, err := tx.Exec(query, t.ID, t.Title, t.Description, utcTime(t.StartDate), utcTime(t.DueDate), t.Status, t.Progress, t.Position, t.Pinned, t.Archived, t.Color, enc.KeyID, enc.Algorithm, t.CreatedAt.UTC(), t.UpdatedAt.UTC(), utcTime(t.CompletedAt), t.Source, t.ExternalID, latitude, longitude, place, t.UUID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: task %d: %v", ErrRestoreConflict, t.ID, err)
+			}
+			return err
+		}
+		for _, link := range snapshot.Links {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO task_links (`+linkColumns+`) VALUES (?, ?, ?, ?, ?)`, link.ID, link.TaskID, link.Title, link.URL, link.CreatedAt.UTC()); err != nil {
+				return err
+			}
+		}
+		for _, note := range snapshot.Notes {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO task_notes (`+noteColumns+`) VALUES (?, ?, ?, ?)`, note.ID, note.TaskID, note.Body, note.CreatedAt.UTC()); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ScheduleSource is the source of tasks generated from schedules; their
+// external ID names the schedule and occurrence
+const ScheduleSource = "schedule"
+
+// maxScheduleInterval bounds the number of periods between occurrences
+const maxScheduleInterval = 365
+
+// Schedule describes a recurring task. Occurrences fall every Interval days,
+// weeks or months from StartDate until EndDate, and each is materialized as
+// an ordinary task due at the occurrence time.
+type Schedule struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Color       string     `json:"color,omitempty"`
+	Frequency   string     `json:"frequency"`
+	Interval    int        `json:"interval"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+	// Generated counts the occurrences already materialized (or skipped
+	// because they fell before the schedule was created)
+	Generated int `json:"generated"`
+	// NextDueDate is the next occurrence still to be materialized, nil once
+	// the schedule has ended
+	NextDueDate *time.Time `json:"next_due_date,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ScheduleRequest represents the request payload for creating a schedule
+type ScheduleRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Color       string     `json:"color,omitempty"`
+	Frequency   string     `json:"frequency"`
+	Interval    int        `json:"interval,omitempty"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+}
+
+// Normalize cleans up the schedule's free-text fields and defaults the
+// interval to 1
+func (sr *ScheduleRequest) Normalize() {
+	sr.Title = normalizeTitle(sr.Title)
+	sr.Description = normalizeDescription(sr.Description)
+	sr.Color = normalizeColor(sr.Color)
+	if sr.Interval == 0 {
+		sr.Interval = 1
+	}
+}
+
+// Validate validates the schedule request. Title, description and color
+// follow the rules for tasks.
+func (sr *ScheduleRequest) Validate() error {
+	switch sr.Frequency {
+	case "daily", "weekly", "monthly":
+	default:
+		return &ValidationError{Field: "frequency", Message: "frequency must be one of: daily, weekly, monthly"}
+	}
+	if sr.Interval < 1 || sr.Interval > maxScheduleInterval {
+		return &ValidationError{Field: "interval", Message: fmt.Sprintf("interval must be between 1 and %d", maxScheduleInterval)}
+	}
+	if sr.StartDate.IsZero() {
+		return &ValidationError{Field: "start_date", Message: "start_date is required"}
+	}
+	if sr.EndDate != nil && sr.EndDate.Before(sr.StartDate) {
+		return &ValidationError{Field: "end_date", Message: "end_date must not be before start_date"}
+	}
+	task := sr.Schedule().TaskRequest(sr.StartDate)
+	return task.Validate()
+}
+
+// Schedule returns an unsaved schedule built from the request
+func (sr *ScheduleRequest) Schedule() *Schedule {
+	return &Schedule{
+		Title:       sr.Title,
+		Description: sr.Description,
+		Color:       sr.Color,
+		Frequency:   sr.Frequency,
+		Interval:    sr.Interval,
+		StartDate:   sr.StartDate.UTC(),
+		EndDate:     utcTime(sr.EndDate),
+	}
+}
+
+// Occurrence returns the due date of the nth occurrence, counting from 0.
+// Each is computed from StartDate, so monthly schedules starting on the 31st
+// don't drift after a short month.
+func (s *Schedule) Occurrence(n int) time.Time {
+	switch s.Frequency {
+	case "weekly":
+		return s.StartDate.AddDate(0, 0, 7*s.Interval*n)
+	case "monthly":
+		return s.StartDate.AddDate(0, s.Interval*n, 0)
+	default:
+		return s.StartDate.AddDate(0, 0, s.Interval*n)
+	}
+}
+
+// Ended reports whether due lies past the schedule's end date
+func (s *Schedule) Ended(due time.Time) bool {
+	return s.EndDate != nil && due.After(*s.EndDate)
+}
+
+// SetNextDueDate fills in NextDueDate from Generated
+func (s *Schedule) SetNextDueDate() {
+	s.NextDueDate = nil
+	if next := s.Occurrence(s.Generated); !s.Ended(next) {
+		s.NextDueDate = &next
+	}
+}
+
+// TaskRequest returns the task to create for the occurrence due at due
+func (s *Schedule) TaskRequest(due time.Time) *TaskRequest {
+	return &TaskRequest{Title: s.Title, Description: s.Description, Color: s.Color, DueDate: &due}
+}
+
+// OccurrenceID returns the external ID of the task generated for the
+// occurrence due at due
+func (s *Schedule) OccurrenceID(due time.Time) string {
+	return fmt.Sprintf("%d/%s", s.ID, due.UTC().Format(time.RFC3339))
+}
+
+// scheduleColumns is the column list shared by every schedule SELECT
+const scheduleColumns = "id, title, description, color, frequency, interval, start_date, end_date, generated, created_at"
+
+func scanSchedule(row rowScanner) (Schedule, error) {
+	var s Schedule
+	if err := row.Scan(&s.ID, &s.Title, &s.Description, &s.Color, &s.Frequency, &s.Interval, &s.StartDate, &s.EndDate, &s.Generated, &s.CreatedAt); err != nil {
+		return s, err
+	}
+	s.SetNextDueDate()
+	return s, nil
+}
+
+// ListSchedules returns every schedule, oldest first
+func (r *SQLiteTaskRepository) ListSchedules() ([]Schedule, error) {
+	rows, err := r.db.Query(`SELECT ` + scheduleColumns + ` FROM schedules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// GetSchedule returns a schedule by ID, or nil when it does not exist
+func (r *SQLiteTaskRepository) GetSchedule(id int) (*Schedule, error) {
+	s, err := scanSchedule(r.db.QueryRow(`SELECT `+scheduleColumns+` FROM schedules WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSchedule stores a new schedule
+func (r *SQLiteTaskRepository) CreateSchedule(req *ScheduleRequest) (*Schedule, error) {
+	s := req.Schedule()
+	s.CreatedAt = r.clock.Now()
+	result, err := r.db.Exec(`INSERT INTO schedules (title, description, color, frequency, interval, start_date, end_date, generated, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)`,
+		s.Title, s.Description, s.Color, s.Frequency, s.Interval, s.StartDate, s.EndDate, s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	s.ID = int(id)
+	s.SetNextDueDate()
+	return s, nil
+}
+
+// DeleteSchedule removes a schedule; tasks it already generated are kept.
+// It returns sql.ErrNoRows when the schedule does not exist.
+func (r *SQLiteTaskRepository) DeleteSchedule(id int) error {
+	result, err := r.db.Exec(`DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetScheduleGenerated records how many occurrences of a schedule have been
+// materialized. A deleted schedule is ignored.
+func (r *SQLiteTaskRepository) SetScheduleGenerated(id, generated int) error {
+	_, err := r.db.Exec(`UPDATE schedules SET generated = ? WHERE id = ?`, generated, id)
+	return err
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSnooze bounds how far a single snooze may push a due date
+const maxSnooze = 365 * 24 * time.Hour
+
+// SnoozeRequest represents the request payload for snoozing a task. Exactly
+// one of Duration and Until must be given.
+type SnoozeRequest struct {
+	// Duration is a Go duration such as "90m" or "2h", or a number of days such as "3d"
+	Duration string     `json:"duration,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+// Validate validates the snooze request
+func (sr *SnoozeRequest) Validate() error {
+	if (sr.Duration == "") == (sr.Until == nil) {
+		return &ValidationError{Field: "duration", Message: "exactly one of duration or until is required"}
+	}
+	if sr.Duration != "" {
+		d, ok := ParseDayDuration(sr.Duration)
+		if !ok || d <= 0 || d > maxSnooze {
+			return &ValidationError{Field: "duration", Message: "duration must be a positive duration such as 2h or 3d, at most 365d"}
+		}
+	}
+	return nil
+}
+
+// Patch builds the change that snoozes task at time now. A duration pushes
+// the due date forward from whichever is later of the current due date and
+// now, so snoozing an overdue task moves it into the future; until sets the
+// due date outright and must lie in the future.
+func (sr *SnoozeRequest) Patch(task *Task, now time.Time) (*TaskPatch, error) {
+	var due time.Time
+	if sr.Until != nil {
+		if !sr.Until.After(now) {
+			return nil, &ValidationError{Field: "until", Message: "until must be in the future"}
+		}
+		due = *sr.Until
+	} else {
+		d, _ := ParseDayDuration(sr.Duration)
+		base := now
+		if task.DueDate != nil && task.DueDate.After(now) {
+			base = *task.DueDate
+		}
+		due = base.Add(d)
+	}
+	return &TaskPatch{DueDate: OptionalTime{Set: true, Value: &due}}, nil
+}
+
+// ParseDayDuration parses a Go duration or a whole number of days ("3d")
+func ParseDayDuration(s string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
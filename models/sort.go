@@ -0,0 +1,130 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// statusRankSQL orders statuses by workflow stage rather than alphabetically.
+// database.createTables indexes this exact expression.
+const statusRankSQL = "(CASE status WHEN 'pending' THEN 0 WHEN 'in_progress' THEN 1 ELSE 2 END)"
+
+// sortExpressions maps the allowed sort_by keys to their ORDER BY expressions
+var sortExpressions = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"due_date":   "due_date",
+	"start_date": "start_date",
+	"id":         "id",
+	"position":   "position",
+	"title":      "title COLLATE NOCASE",
+	"status":     statusRankSQL,
+}
+
+// orderByClause builds the ORDER BY clause for a sort key and direction.
+// Pinned tasks always come first. Unknown keys fall back to created_at DESC;
+// created_at ties are broken by id so cursors stay stable, and tasks without
+// a due date always sort after dated ones when ordering by due_date.
+func orderByClause(sortBy string, sortOrder string) string {
+	expr, ok := sortExpressions[sortBy]
+	if !ok {
+		sortBy, expr = "created_at", sortExpressions["created_at"]
+	}
+	sortOrder = strings.ToUpper(sortOrder)
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		sortOrder = "DESC"
+	}
+	if sortBy == "due_date" {
+		return " ORDER BY pinned DESC, due_date IS NULL, due_date " + sortOrder
+	}
+	if sortBy == "created_at" {
+		return " ORDER BY pinned DESC, created_at " + sortOrder + ", id " + sortOrder
+	}
+	return " ORDER BY pinned DESC, " + expr + " " + sortOrder
+}
+
+// statusRank mirrors statusRankSQL for in-memory sorting
+func statusRank(status string) int {
+	switch status {
+	case "pending":
+		return 0
+	case "in_progress":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// SortTasks orders tasks in place the way GetAllPaginated orders SQL results,
+// for repositories that sort in memory. Pinned tasks come first. Unknown keys
+// fall back to created_at; unset start dates sort first in ascending order,
+// as NULLs do in SQLite, while unset due dates always sort last.
+func SortTasks(tasks []Task, sortBy string, sortOrder string) {
+	desc := !strings.EqualFold(sortOrder, "asc")
+
+	less := func(a, b *Task) bool {
+		switch sortBy {
+		case "id":
+			return a.ID < b.ID
+		case "position":
+			return a.Position < b.Position
+		case "updated_at":
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		case "due_date":
+			return timeLess(a.DueDate, b.DueDate)
+		case "start_date":
+			return timeLess(a.StartDate, b.StartDate)
+		case "title":
+			return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+		case "status":
+			return statusRank(a.Status) < statusRank(b.Status)
+		default:
+			if a.CreatedAt.Equal(b.CreatedAt) {
+				return a.ID < b.ID
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := &tasks[i], &tasks[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if sortBy == "due_date" && (a.DueDate == nil) != (b.DueDate == nil) {
+			return b.DueDate == nil
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+// pinnedFirst stably moves pinned tasks ahead of the rest
+func pinnedFirst(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Pinned && !tasks[j].Pinned
+	})
+}
+
+// timeLess compares optional times with nil ordered first
+func timeLess(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return a.Before(*b)
+}
+
+// PaginateTasks returns the page of tasks selected by limit and offset
+func PaginateTasks(tasks []Task, limit int, offset int) []Task {
+	if offset >= len(tasks) {
+		return []Task{}
+	}
+	end := offset + limit
+	if end > len(tasks) {
+		end = len(tasks)
+	}
+	return tasks[offset:end]
+}
@@ -0,0 +1,206 @@
+package models
+
+import "time"
+
+// TaskStats summarizes the task list for dashboards
+type TaskStats struct {
+	Total    int            `json:"total"`
+	ByStatus map[string]int `json:"by_status"`
+	// Overdue counts unarchived open tasks whose due date has passed
+	Overdue    int         `json:"overdue"`
+	Archived   int         `json:"archived"`
+	Last7Days  PeriodStats `json:"last_7_days"`
+	Last30Days PeriodStats `json:"last_30_days"`
+}
+
+// PeriodStats counts the tasks created and completed within a period
+type PeriodStats struct {
+	Created   int `json:"created"`
+	Completed int `json:"completed"`
+}
+
+// newTaskStats returns empty stats with every status present
+func newTaskStats() *TaskStats {
+	return &TaskStats{ByStatus: map[string]int{"pending": 0, "in_progress": 0, "completed": 0}}
+}
+
+// statsWindows returns the start of the 7 and 30 day windows ending at now
+func statsWindows(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	return now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)
+}
+
+// ComputeStats builds stats from a full task list, for backends without
+// aggregate queries
+func ComputeStats(tasks []Task, now time.Time) *TaskStats {
+	stats := newTaskStats()
+	week, month := statsWindows(now)
+	overdue := TaskFilter{OverdueAt: &now}
+	for _, task := range tasks {
+		stats.Total++
+		stats.ByStatus[task.Status]++
+		if task.Archived {
+			stats.Archived++
+		} else if overdue.Matches(task) {
+			stats.Overdue++
+		}
+		if !task.CreatedAt.Before(week) {
+			stats.Last7Days.Created++
+		}
+		if !task.CreatedAt.Before(month) {
+			stats.Last30Days.Created++
+		}
+		if task.CompletedAt != nil {
+			if !task.CompletedAt.Before(week) {
+				stats.Last7Days.Completed++
+			}
+			if !task.CompletedAt.Before(month) {
+				stats.Last30Days.Completed++
+			}
+		}
+	}
+	return stats
+}
+
+// Stats computes task statistics with aggregate queries
+func (r *SQLiteTaskRepository) Stats(now time.Time) (*TaskStats, error) {
+	stats := newTaskStats()
+
+	rows, err := r.db.Query(`SELECT status, COUNT(*) FROM tasks GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.ByStatus[status] = count
+		stats.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	week, month := statsWindows(now)
+	query := `
+		SELECT
+			COALESCE(SUM(archived = 0 AND status != 'completed' AND due_date < ?), 0),
+			COALESCE(SUM(archived != 0), 0),
+			COALESCE(SUM(created_at >= ?), 0),
+			COALESCE(SUM(created_at >= ?), 0),
+			COALESCE(SUM(completed_at >= ?), 0),
+			COALESCE(SUM(completed_at >= ?), 0)
+		FROM tasks
+	`
+	err = r.db.QueryRow(query, now.UTC(), week, month, week, month).Scan(
+		&stats.Overdue, &stats.Archived,
+		&stats.Last7Days.Created, &stats.Last30Days.Created,
+		&stats.Last7Days.Completed, &stats.Last30Days.Completed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// CompletionPoint is one period of a completion time series
+type CompletionPoint struct {
+	StartDate time.Time `json:"start_date"`
+	Completed int       `json:"completed"`
+}
+
+// CompletionSeries counts completed tasks per day or week
+type CompletionSeries struct {
+	Granularity string            `json:"granularity"`
+	StartDate   time.Time         `json:"start_date"`
+	EndDate     time.Time         `json:"end_date"`
+	Total       int               `json:"total"`
+	Points      []CompletionPoint `json:"points"`
+	// CurrentStreak is the number of consecutive days, up to today, with at
+	// least one completion. Today does not break the streak until it is over.
+	// Only reported for daily series.
+	CurrentStreak *int `json:"current_streak,omitempty"`
+}
+
+// BuildCompletionSeries buckets completion times into periods consecutive
+// days or weeks (granularity "day" or "week") starting at start, which must
+// be the beginning of a period in the caller's time zone
+func BuildCompletionSeries(times []time.Time, start time.Time, periods int, granularity string) *CompletionSeries {
+	step := func(t time.Time, n int) time.Time {
+		if granularity == "week" {
+			return t.AddDate(0, 0, 7*n)
+		}
+		return t.AddDate(0, 0, n)
+	}
+
+	series := &CompletionSeries{
+		Granularity: granularity,
+		StartDate:   start,
+		EndDate:     step(start, periods),
+		Points:      make([]CompletionPoint, periods),
+	}
+	for i := range series.Points {
+		series.Points[i].StartDate = step(start, i)
+	}
+
+	for _, t := range times {
+		if t.Before(series.StartDate) || !t.Before(series.EndDate) {
+			continue
+		}
+		// Periods can differ in length across DST changes, so search
+		// rather than divide
+		i := len(series.Points) - 1
+		for i > 0 && t.Before(series.Points[i].StartDate) {
+			i--
+		}
+		series.Points[i].Completed++
+		series.Total++
+	}
+
+	if granularity == "day" {
+		streak := 0
+		points := series.Points
+		if n := len(points); n > 0 && points[n-1].Completed == 0 {
+			points = points[:n-1]
+		}
+		for i := len(points) - 1; i >= 0 && points[i].Completed > 0; i-- {
+			streak++
+		}
+		series.CurrentStreak = &streak
+	}
+	return series
+}
+
+// CompletionTimes returns when tasks completed in [from, to) were completed
+func (r *SQLiteTaskRepository) CompletionTimes(from, to time.Time) ([]time.Time, error) {
+	rows, err := r.db.Query(`SELECT completed_at FROM tasks WHERE completed_at >= ? AND completed_at < ? ORDER BY completed_at`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+// FilterCompletionTimes returns the completion times in [from, to) from a
+// full task list, for backends without range queries
+func FilterCompletionTimes(tasks []Task, from, to time.Time) []time.Time {
+	var times []time.Time
+	for _, task := range tasks {
+		if task.CompletedAt != nil && !task.CompletedAt.Before(from) && task.CompletedAt.Before(to) {
+			times = append(times, *task.CompletedAt)
+		}
+	}
+	return times
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -11,18 +12,199 @@ type Task struct {
 	ID          int       `json:"id" db:"id"`
 	Title       string    `json:"title" db:"title"`
 	Description string    `json:"description" db:"description"`
+	Summary     string    `json:"summary,omitempty" db:"-"`
+	// DescriptionHTML is the description rendered from Markdown; only set
+	// when a client asks for it with ?render=html
+	DescriptionHTML string `json:"description_html,omitempty" db:"-"`
+	StartDate   *time.Time `json:"start_date,omitempty" db:"start_date"`
 	DueDate     *time.Time `json:"due_date,omitempty" db:"due_date"`
 	Status      string    `json:"status" db:"status"`
+	Progress    int       `json:"progress" db:"progress"`
+	Position    int       `json:"position" db:"position"`
+	Pinned      bool      `json:"pinned" db:"pinned"`
+	Archived    bool      `json:"archived" db:"archived"`
+	Color       string    `json:"color,omitempty" db:"color"`
+	Encryption  *Encryption `json:"encryption,omitempty" db:"-"`
+	Location    *Location  `json:"location,omitempty" db:"-"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	// Source and ExternalID identify a task imported from another system;
+	// set only through the external upsert endpoint
+	Source      string    `json:"source,omitempty" db:"source"`
+	ExternalID  string    `json:"external_id,omitempty" db:"external_id"`
+	// UUID is an ID the client chose when creating the task, so offline
+	// apps can refer to it before the server has assigned its ID
+	UUID        string    `json:"uuid,omitempty" db:"uuid"`
 }
 
 // TaskRequest represents the request payload for creating/updating tasks
 type TaskRequest struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
 	Status      string     `json:"status"`
+	Progress    *int       `json:"progress,omitempty"`
+	Color       string     `json:"color,omitempty"`
+	Encryption  *Encryption `json:"encryption,omitempty"`
+	Location    *Location  `json:"location,omitempty"`
+	// UUID is only read when creating a task
+	UUID        string     `json:"uuid,omitempty"`
+}
+
+// MoveRequest represents the request payload for moving a task
+type MoveRequest struct {
+	Position int `json:"position"`
+}
+
+// Validate validates the move request
+func (mr *MoveRequest) Validate() error {
+	if mr.Position < 1 {
+		return &ValidationError{Field: "position", Message: "position must be 1 or greater"}
+	}
+	return nil
+}
+
+// maxShiftDays bounds how far a duplicate's dates may be shifted
+const maxShiftDays = 3650
+
+// DuplicateRequest represents the optional payload for duplicating a task
+type DuplicateRequest struct {
+	// ShiftDays moves the copy's start and due dates by this many days
+	ShiftDays int `json:"shift_days"`
+}
+
+// Validate validates the duplicate request
+func (dr *DuplicateRequest) Validate() error {
+	if dr.ShiftDays < -maxShiftDays || dr.ShiftDays > maxShiftDays {
+		return &ValidationError{Field: "shift_days", Message: "shift_days must be between -3650 and 3650"}
+	}
+	return nil
+}
+
+// Copy builds the request that creates a duplicate of task. The copy starts
+// out pending and keeps the title, description, color and (shifted) dates.
+func (dr *DuplicateRequest) Copy(task *Task) *TaskRequest {
+	shift := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		shifted := t.AddDate(0, 0, dr.ShiftDays)
+		return &shifted
+	}
+	return &TaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		StartDate:   shift(task.StartDate),
+		DueDate:     shift(task.DueDate),
+		Color:       task.Color,
+		Encryption:  task.Encryption,
+		Location:    task.Location,
+	}
+}
+
+// ReorderRequest represents the request payload for reordering tasks
+type ReorderRequest struct {
+	IDs []int `json:"ids"`
+}
+
+// Validate validates the reorder request
+func (rr *ReorderRequest) Validate() error {
+	if len(rr.IDs) == 0 {
+		return &ValidationError{Field: "ids", Message: "ids must contain at least one task ID"}
+	}
+	return nil
+}
+
+// BulkFilter selects tasks for bulk operations by attribute. A filter
+// must name at least one criterion, or set All to select every task, so an
+// empty filter never selects everything by accident.
+type BulkFilter struct {
+	Status string `json:"status"`
+	All    bool   `json:"all,omitempty"`
+}
+
+// validate checks the filter of a bulk request
+func (bf *BulkFilter) validate() error {
+	if bf.Status != "" && !isValidStatus(bf.Status) {
+		return &ValidationError{Field: "filter.status", Message: "status must be one of: pending, in_progress, completed"}
+	}
+	if bf.Status == "" && !bf.All {
+		return &ValidationError{Field: "filter", Message: `filter must set status, or "all": true to select every task`}
+	}
+	if bf.Status != "" && bf.All {
+		return &ValidationError{Field: "filter.all", Message: "all cannot be combined with other criteria"}
+	}
+	return nil
+}
+
+// TaskChanges is the change set applied by a bulk update
+type TaskChanges struct {
+	Status    *string    `json:"status,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	DueDate   *time.Time `json:"due_date,omitempty"`
+	Archived  *bool      `json:"archived,omitempty"`
+}
+
+// BulkUpdateRequest represents the request payload for updating many tasks
+type BulkUpdateRequest struct {
+	IDs     []int       `json:"ids,omitempty"`
+	Filter  *BulkFilter `json:"filter,omitempty"`
+	Changes TaskChanges `json:"changes"`
+}
+
+// Validate validates the bulk update request
+func (br *BulkUpdateRequest) Validate() error {
+	if len(br.IDs) == 0 && br.Filter == nil {
+		return &ValidationError{Field: "ids", Message: "either ids or filter is required"}
+	}
+	if len(br.IDs) > 0 && br.Filter != nil {
+		return &ValidationError{Field: "filter", Message: "ids and filter cannot be combined"}
+	}
+	if br.Filter != nil {
+		if err := br.Filter.validate(); err != nil {
+			return err
+		}
+	}
+	c := br.Changes
+	if c.Status == nil && c.StartDate == nil && c.DueDate == nil && c.Archived == nil {
+		return &ValidationError{Field: "changes", Message: "changes must set at least one field"}
+	}
+	if c.Status != nil && !isValidStatus(*c.Status) {
+		return &ValidationError{Field: "changes.status", Message: "status must be one of: pending, in_progress, completed"}
+	}
+	return validateSchedule(c.StartDate, c.DueDate)
+}
+
+// BulkDeleteRequest represents the request payload for deleting many tasks
+type BulkDeleteRequest struct {
+	IDs    []int       `json:"ids,omitempty"`
+	Filter *BulkFilter `json:"filter,omitempty"`
+	DryRun bool        `json:"dry_run"`
+}
+
+// Validate validates the bulk delete request
+func (br *BulkDeleteRequest) Validate() error {
+	if len(br.IDs) == 0 && br.Filter == nil {
+		return &ValidationError{Field: "ids", Message: "either ids or filter is required"}
+	}
+	if len(br.IDs) > 0 && br.Filter != nil {
+		return &ValidationError{Field: "filter", Message: "ids and filter cannot be combined"}
+	}
+	if br.Filter != nil {
+		return br.Filter.validate()
+	}
+	return nil
+}
+
+// Selection returns the task filter described by the request's filter, if any
+func (bf *BulkFilter) Selection() TaskFilter {
+	var filter TaskFilter
+	if bf != nil && bf.Status != "" {
+		filter.Statuses = []string{bf.Status}
+	}
+	return filter
 }
 
 // Validate validates the task request
@@ -35,9 +217,147 @@ func (tr *TaskRequest) Validate() error {
 		return &ValidationError{Field: "status", Message: "status must be one of: pending, in_progress, completed"}
 	}
 	
+	if err := validateProgress(tr.Progress); err != nil {
+		return err
+	}
+	
+	if err := validateColor(tr.Color); err != nil {
+		return err
+	}
+	
+	if err := validateEncryption(tr.Encryption, tr.Title, tr.Description); err != nil {
+		return err
+	}
+	
+	if err := validateLocation(tr.Location); err != nil {
+		return err
+	}
+	
+	if err := validateUUID(tr.UUID); err != nil {
+		return err
+	}
+	
+	return validateSchedule(tr.StartDate, tr.DueDate)
+}
+
+// ValidateUpdate validates the fields of an update request that can be
+// checked without the task it applies to. Every field is optional.
+func (tr *TaskRequest) ValidateUpdate() error {
+	if err := validateProgress(tr.Progress); err != nil {
+		return err
+	}
+	if err := validateColor(tr.Color); err != nil {
+		return err
+	}
+	return validateLocation(tr.Location)
+}
+
+// applyUpdate merges an update request into an existing task. Empty title,
+// status, progress, color and dates keep their current values; description,
+// encryption and location are always replaced.
+func (tr *TaskRequest) applyUpdate(task *Task) error {
+	if err := tr.ValidateUpdate(); err != nil {
+		return err
+	}
+	
+	startDate := tr.StartDate
+	if startDate == nil {
+		startDate = task.StartDate
+	}
+	
+	dueDate := tr.DueDate
+	if dueDate == nil {
+		dueDate = task.DueDate
+	}
+	
+	if err := validateSchedule(startDate, dueDate); err != nil {
+		return err
+	}
+	
+	// A kept title would be left in the wrong form if encryption is
+	// switched on or off
+	if tr.Title == "" && (tr.Encryption == nil) != (task.Encryption == nil) {
+		return &ValidationError{Field: "title", Message: "title is required when turning encryption on or off"}
+	}
+	
+	if tr.Title != "" {
+		task.Title = tr.Title
+	}
+	task.Description = tr.Description
+	task.Encryption = tr.Encryption
+	task.Location = tr.Location
+	if err := validateEncryption(task.Encryption, task.Title, task.Description); err != nil {
+		return err
+	}
+	if tr.Status != "" {
+		task.Status = tr.Status
+	}
+	if tr.Progress != nil {
+		task.Progress = *tr.Progress
+	}
+	if tr.Color != "" {
+		task.Color = tr.Color
+	}
+	task.StartDate = startDate
+	task.DueDate = dueDate
 	return nil
 }
 
+// MarkCompletion keeps completed_at in step with status: it is set to now
+// when the task becomes completed and cleared when the task is reopened
+func (t *Task) MarkCompletion(now time.Time) {
+	if t.Status != "completed" {
+		t.CompletedAt = nil
+		return
+	}
+	if t.CompletedAt == nil {
+		completedAt := now.UTC()
+		t.CompletedAt = &completedAt
+	}
+}
+
+// encryptionColumns flattens encryption metadata into its two columns;
+// plaintext tasks store empty strings
+func encryptionColumns(enc *Encryption) Encryption {
+	if enc == nil {
+		return Encryption{}
+	}
+	return *enc
+}
+
+// validateSchedule checks that a task does not start after it is due
+func validateSchedule(startDate *time.Time, dueDate *time.Time) error {
+	if startDate != nil && dueDate != nil && startDate.After(*dueDate) {
+		return &ValidationError{Field: "start_date", Message: "start_date must not be after due_date"}
+	}
+	return nil
+}
+
+// validateProgress checks that an optional progress is a percentage
+func validateProgress(progress *int) error {
+	if progress != nil && (*progress < 0 || *progress > 100) {
+		return &ValidationError{Field: "progress", Message: "progress must be between 0 and 100"}
+	}
+	return nil
+}
+
+// progressValue returns the progress to store for an optional request value
+func progressValue(progress *int) int {
+	if progress == nil {
+		return 0
+	}
+	return *progress
+}
+
+// utcTime normalizes an optional timestamp to UTC so stored values compare correctly
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
 // isValidStatus checks if the status is valid
 func isValidStatus(status string) bool {
 	validStatuses := []string{"pending", "in_progress", "completed"}
@@ -62,55 +382,216 @@ func (e *ValidationError) Error() string {
 // TaskRepository defines the interface for task database operations
 type TaskRepository interface {
 	Create(task *TaskRequest) (*Task, error)
+	CreateBatch(tasks []*TaskRequest) ([]Task, error)
+	UpdateBatch(ids []int, filter TaskFilter, changes TaskChanges) (int, error)
+	DeleteBatch(ids []int, filter TaskFilter, dryRun bool) (int, error)
 	GetAll() ([]Task, error)
 	GetByID(id int) (*Task, error)
-	Update(id int, task *TaskRequest) (*Task, error)
-	Delete(id int) error
+	Update(id int, task *TaskRequest, check VersionCheck) (*Task, error)
+	Patch(id int, patch *TaskPatch, check VersionCheck) (*Task, error)
+	Delete(id int, check VersionCheck) error
 	GetByStatus(status string) ([]Task, error)
-	GetAllPaginated(filterStatus *string, limit int, offset int, sortBy string, sortOrder string) ([]Task, error)
+	GetAllPaginated(filter TaskFilter, limit int, offset int, sortBy string, sortOrder string) ([]Task, error)
+	Count(filter TaskFilter) (int, error)
+	Stats(now time.Time) (*TaskStats, error)
+	CompletionTimes(from, to time.Time) ([]time.Time, error)
+	Move(id int, position int) (*Task, error)
+	TogglePin(id int) (*Task, error)
+	Reorder(ids []int) error
+	ListLinks(taskID int) ([]Link, error)
+	CreateLink(taskID int, req *LinkRequest) (*Link, error)
+	UpdateLink(taskID, linkID int, req *LinkRequest) (*Link, error)
+	DeleteLink(taskID, linkID int) error
+	ListNotes(taskID int) ([]Note, error)
+	AddNote(taskID int, req *NoteRequest) (*Note, error)
+	UpsertExternal(source, externalID string, req *TaskRequest) (*Task, bool, error)
+	RestoreTasks(snapshots []TaskSnapshot) error
+	ReplaceAll(backup *Backup) error
+	ListSchedules() ([]Schedule, error)
+	GetSchedule(id int) (*Schedule, error)
+	CreateSchedule(req *ScheduleRequest) (*Schedule, error)
+	DeleteSchedule(id int) error
+	SetScheduleGenerated(id, generated int) error
+	ListWebhooks() ([]Webhook, error)
+	GetWebhook(id int) (*Webhook, error)
+	CreateWebhook(req *WebhookRequest) (*Webhook, error)
+	DeleteWebhook(id int) error
+	// Begin opens a transaction every later call runs in until Commit or
+	// Rollback; the caller keeps everyone else off the repository meanwhile
+	Begin() error
+	Commit() error
+	Rollback() error
+	ListChanges(since int64, limit int) ([]Change, error)
+	GetChange(taskID int) (*Change, error)
+}
+
+// taskColumns is the column list shared by every task SELECT
+const taskColumns = "id, title, description, start_date, due_date, status, progress, position, pinned, archived, color, encryption_key_id, encryption_algorithm, created_at, updated_at, completed_at, source, external_id, latitude, longitude, place, uuid"
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask scans a single task row selected with taskColumns
+func scanTask(row rowScanner) (Task, error) {
+	var task Task
+	var enc Encryption
+	var latitude, longitude *float64
+	var place string
+	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.StartDate, &task.DueDate, &task.Status, &task.Progress, &task.Position, &task.Pinned, &task.Archived, &task.Color, &enc.KeyID, &enc.Algorithm, &task.CreatedAt, &task.UpdatedAt, &task.CompletedAt, &task.Source, &task.ExternalID, &latitude, &longitude, &place, &task.UUID)
+	if enc.KeyID != "" {
+		task.Encryption = &enc
+	}
+	if latitude != nil && longitude != nil {
+		task.Location = &Location{Latitude: *latitude, Longitude: *longitude, Place: place}
+	}
+	return task, err
+}
+
+// scanTasks drains rows into a slice of tasks
+func scanTasks(rows *sql.Rows) ([]Task, error) {
+	var tasks []Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
 }
 
 // SQLiteTaskRepository implements TaskRepository for SQLite
 type SQLiteTaskRepository struct {
-	db *sql.DB
+	// db runs statements: the pool, or tx while one is open
+	db    sqlConn
+	pool  *sql.DB
+	tx    *sqlBatch
+	clock Clock
+	ids   IDGenerator
 }
 
 // NewSQLiteTaskRepository creates a new SQLite task repository
 func NewSQLiteTaskRepository(db *sql.DB) *SQLiteTaskRepository {
-	return &SQLiteTaskRepository{db: db}
+	return &SQLiteTaskRepository{db: sqlDB{db}, pool: db, clock: SystemClock}
+}
+
+// SetClock replaces the clock used for created_at and updated_at
+func (r *SQLiteTaskRepository) SetClock(clock Clock) {
+	r.clock = clock
+}
+
+// SetIDGenerator makes new tasks take their IDs from gen instead of the
+// table's autoincrement; nil restores the default
+func (r *SQLiteTaskRepository) SetIDGenerator(gen IDGenerator) {
+	r.ids = gen
+}
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 // Create creates a new task
 func (r *SQLiteTaskRepository) Create(taskReq *TaskRequest) (*Task, error) {
+	id, err := insertTask(r.db, taskReq, r.clock.Now(), nextID(r.ids))
+	if err != nil {
+		return nil, err
+	}
+	
+	return r.GetByID(id)
+}
+
+// CreateBatch creates several tasks in a single transaction
+func (r *SQLiteTaskRepository) CreateBatch(taskReqs []*TaskRequest) ([]Task, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := r.clock.Now()
+	tasks := make([]Task, 0, len(taskReqs))
+	for _, taskReq := range taskReqs {
+		id, err := insertTask(tx, taskReq, now, nextID(r.ids))
+		if err != nil {
+			return nil, err
+		}
+		task, err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// insertTask inserts a task and returns its new ID. A positive id is used
+// as-is; otherwise SQLite assigns one.
+func insertTask(db dbExecutor, taskReq *TaskRequest, now time.Time, id int) (int, error) {
 	// Set default status if not provided
 	status := taskReq.Status
 	if status == "" {
 		status = "pending"
 	}
 	
+	if id > 0 {
+		var exists int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM tasks WHERE id = ?`, id).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if exists > 0 {
+			return 0, fmt.Errorf("%w: %d", ErrDuplicateID, id)
+		}
+	}
+	
+	// New tasks are appended to the end of the manual ordering; a NULL id
+	// lets SQLite pick the next rowid
 	query := `
-		INSERT INTO tasks (title, description, due_date, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO tasks (id, title, description, start_date, due_date, status, progress, color, encryption_key_id, encryption_algorithm, latitude, longitude, place, uuid, position, created_at, updated_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM tasks), ?, ?, ?)
 	`
 	
-	now := time.Now()
-	result, err := r.db.Exec(query, taskReq.Title, taskReq.Description, taskReq.DueDate, status, now, now)
+	var idArg interface{}
+	if id > 0 {
+		idArg = id
+	}
+	
+	// Store creation time in UTC so created_at range filters compare correctly
+	now = now.UTC()
+	enc := encryptionColumns(taskReq.Encryption)
+	latitude, longitude, place := locationColumns(taskReq.Location)
+	var completedAt *time.Time
+	if status == "completed" {
+		completedAt = &now
+	}
+	result, err := db.Exec(query, idArg, taskReq.Title, taskReq.Description, utcTime(taskReq.StartDate), utcTime(taskReq.DueDate), status, progressValue(taskReq.Progress), taskReq.Color, enc.KeyID, enc.Algorithm, latitude, longitude, place, taskReq.UUID, now, now, completedAt)
 	if err != nil {
-		return nil, err
+		if taskReq.UUID != "" && isUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: %s", ErrUUIDTaken, taskReq.UUID)
+		}
+		return 0, err
 	}
 	
-	id, err := result.LastInsertId()
+	lastID, err := result.LastInsertId()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	
-	return r.GetByID(int(id))
+	return int(lastID), nil
 }
 
 // GetAll retrieves all tasks
 func (r *SQLiteTaskRepository) GetAll() ([]Task, error) {
 	query := `
-		SELECT id, title, description, due_date, status, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM tasks
 		ORDER BY created_at DESC
 	`
@@ -121,45 +602,21 @@ func (r *SQLiteTaskRepository) GetAll() ([]Task, error) {
 	}
 	defer rows.Close()
 	
-	var tasks []Task
-	for rows.Next() {
-		var task Task
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.DueDate, &task.Status, &task.CreatedAt, &task.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
-	}
-	
-	return tasks, nil
+	return scanTasks(rows)
 }
 
 // GetAllPaginated retrieves tasks with optional filtering, sorting, and pagination
-func (r *SQLiteTaskRepository) GetAllPaginated(filterStatus *string, limit int, offset int, sortBy string, sortOrder string) ([]Task, error) {
-	allowedSort := map[string]bool{
-		"created_at": true,
-		"updated_at": true,
-		"due_date":   true,
-		"id":         true,
-	}
-	if !allowedSort[sortBy] {
-		sortBy = "created_at"
-	}
-	sortOrder = strings.ToUpper(sortOrder)
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "DESC"
+func (r *SQLiteTaskRepository) GetAllPaginated(filter TaskFilter, limit int, offset int, sortBy string, sortOrder string) ([]Task, error) {
+	if len(filter.Fields) > 0 {
+		return r.getFieldsPaginated(filter, limit, offset, sortBy, sortOrder)
 	}
-
 	base := `
-		SELECT id, title, description, due_date, status, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM tasks
 	`
-	args := make([]interface{}, 0, 3)
-	if filterStatus != nil && *filterStatus != "" {
-		base += " WHERE status = ?"
-		args = append(args, *filterStatus)
-	}
-	base += " ORDER BY " + sortBy + " " + sortOrder + " LIMIT ? OFFSET ?"
+	where, args := filter.whereClause()
+	base += where
+	base += orderByClause(sortBy, sortOrder) + " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := r.db.Query(base, args...)
@@ -168,27 +625,51 @@ func (r *SQLiteTaskRepository) GetAllPaginated(filterStatus *string, limit int,
 	}
 	defer rows.Close()
 
+	return scanTasks(rows)
+}
+
+// getFieldsPaginated is GetAllPaginated reading only the columns of
+// filter.Fields
+func (r *SQLiteTaskRepository) getFieldsPaginated(filter TaskFilter, limit int, offset int, sortBy string, sortOrder string) ([]Task, error) {
+	columns := selectColumns(filter.Fields)
+	where, args := filter.whereClause()
+	query := `SELECT ` + strings.Join(columns, ", ") + ` FROM tasks` + where + orderByClause(sortBy, sortOrder) + " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
 	var tasks []Task
 	for rows.Next() {
-		var task Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.DueDate, &task.Status, &task.CreatedAt, &task.UpdatedAt); err != nil {
+		task, err := scanTaskColumns(rows, columns)
+		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
 	}
-	return tasks, nil
+	return tasks, rows.Err()
+}
+
+// Count returns the number of tasks matching the filter
+func (r *SQLiteTaskRepository) Count(filter TaskFilter) (int, error) {
+	where, args := filter.whereClause()
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM tasks`+where, args...).Scan(&count)
+	return count, err
 }
 
 // GetByID retrieves a task by ID
 func (r *SQLiteTaskRepository) GetByID(id int) (*Task, error) {
 	query := `
-		SELECT id, title, description, due_date, status, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM tasks
 		WHERE id = ?
 	`
 	
-	var task Task
-	err := r.db.QueryRow(query, id).Scan(&task.ID, &task.Title, &task.Description, &task.DueDate, &task.Status, &task.CreatedAt, &task.UpdatedAt)
+	task, err := scanTask(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -200,72 +681,152 @@ func (r *SQLiteTaskRepository) GetByID(id int) (*Task, error) {
 }
 
 // Update updates a task
-func (r *SQLiteTaskRepository) Update(id int, taskReq *TaskRequest) (*Task, error) {
-	// First check if task exists
-	existingTask, err := r.GetByID(id)
+func (r *SQLiteTaskRepository) Update(id int, taskReq *TaskRequest, check VersionCheck) (*Task, error) {
+	return r.modify(id, check, taskReq.applyUpdate)
+}
+
+// Patch applies a partial update to a task
+func (r *SQLiteTaskRepository) Patch(id int, patch *TaskPatch, check VersionCheck) (*Task, error) {
+	return r.modify(id, check, patch.Apply)
+}
+
+// versionClause limits a write to a task whose change sequence is still the
+// one read; every write to a task advances it
+const versionClause = ` AND COALESCE((SELECT seq FROM task_changes WHERE task_id = tasks.id), 0) = ?`
+
+// getVersion reads a task together with its change sequence, returning a
+// nil task when it does not exist
+func (r *SQLiteTaskRepository) getVersion(id int) (*Task, int64, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	if existingTask == nil {
-		return nil, nil
-	}
-	
-	// Update only provided fields
-	title := taskReq.Title
-	if title == "" {
-		title = existingTask.Title
+	defer tx.Rollback()
+
+	task, err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, 0, nil
 	}
-	
-	description := taskReq.Description
-	status := taskReq.Status
-	if status == "" {
-		status = existingTask.Status
+	if err != nil {
+		return nil, 0, err
 	}
-	
-	dueDate := taskReq.DueDate
-	if dueDate == nil {
-		dueDate = existingTask.DueDate
+	var seq int64
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM task_changes WHERE task_id = ?`, id).Scan(&seq); err != nil {
+		return nil, 0, err
 	}
-	
+	return &task, seq, nil
+}
+
+// modify applies fn to a stored task and saves it, returning nil when the
+// task does not exist. The write only lands if the task is still the
+// version check and fn saw; otherwise the task is read and checked again.
+func (r *SQLiteTaskRepository) modify(id int, check VersionCheck, fn func(task *Task) error) (*Task, error) {
 	query := `
 		UPDATE tasks
-		SET title = ?, description = ?, due_date = ?, status = ?, updated_at = ?
-		WHERE id = ?
-	`
-	
-	now := time.Now()
-	_, err = r.db.Exec(query, title, description, dueDate, status, now, id)
-	if err != nil {
-		return nil, err
+		SET title = ?, description = ?, start_date = ?, due_date = ?, status = ?, progress = ?, archived = ?, color = ?, encryption_key_id = ?, encryption_algorithm = ?, latitude = ?, longitude = ?, place = ?, updated_at = ?, completed_at = ?
+		WHERE id = ?` + versionClause
+
+	for {
+		task, seq, err := r.getVersion(id)
+		if err != nil || task == nil {
+			return nil, err
+		}
+		if err := check.Verify(task); err != nil {
+			return nil, err
+		}
+		if err := fn(task); err != nil {
+			return nil, err
+		}
+
+		now := r.clock.Now()
+		task.MarkCompletion(now)
+		enc := encryptionColumns(task.Encryption)
+		latitude, longitude, place := locationColumns(task.Location)
+		result, err := r.db.Exec(query, task.Title, task.Description, utcTime(task.StartDate), utcTime(task.DueDate), task.Status, task.Progress, task.Archived, task.Color, enc.KeyID, enc.Algorithm, latitude, longitude, place, now, task.CompletedAt, id, seq)
+		if err != nil {
+			return nil, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected > 0 {
+			return r.GetByID(id)
+		}
 	}
-	
-	return r.GetByID(id)
 }
 
 // Delete deletes a task
-func (r *SQLiteTaskRepository) Delete(id int) error {
-	query := `DELETE FROM tasks WHERE id = ?`
-	result, err := r.db.Exec(query, id)
+func (r *SQLiteTaskRepository) Delete(id int, check VersionCheck) error {
+	for {
+		task, seq, err := r.getVersion(id)
+		if err != nil {
+			return err
+		}
+		if task == nil {
+			return sql.ErrNoRows
+		}
+		if err := check.Verify(task); err != nil {
+			return err
+		}
+		deleted, err := r.deleteVersion(id, seq)
+		if err != nil || deleted {
+			return err
+		}
+	}
+}
+
+// deleteVersion deletes a task if its change sequence is still seq and
+// reports whether it did
+func (r *SQLiteTaskRepository) deleteVersion(id int, seq int64) (bool, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Links and notes are removed explicitly rather than relying on ON DELETE
+	// CASCADE, since foreign_keys is a per-connection setting
+	if _, err := tx.Exec(`DELETE FROM task_links WHERE task_id = ?`, id); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_notes WHERE task_id = ?`, id); err != nil {
+		return false, err
+	}
+
+	var position int
+	if err := tx.QueryRow(`SELECT position FROM tasks WHERE id = ?`, id).Scan(&position); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`+versionClause, id, seq)
+	if err != nil {
+		return false, err
 	}
-	
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
-	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		// Changed or deleted since it was read; the rollback keeps its
+		// links and notes
+		return false, nil
 	}
-	
-	return nil
+
+	// Close the gap, so positions stay the ranks Move takes
+	if _, err := tx.Exec(`UPDATE tasks SET position = position - 1 WHERE position > ?`, position); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
 }
 
 // GetByStatus retrieves tasks by status
 func (r *SQLiteTaskRepository) GetByStatus(status string) ([]Task, error) {
 	query := `
-		SELECT id, title, description, due_date, status, created_at, updated_at
+		SELECT ` + taskColumns + `
 		FROM tasks
 		WHERE status = ?
 		ORDER BY created_at DESC
@@ -277,15 +838,333 @@ func (r *SQLiteTaskRepository) GetByStatus(status string) ([]Task, error) {
 	}
 	defer rows.Close()
 	
-	var tasks []Task
+	return scanTasks(rows)
+}
+
+// TogglePin flips a task's pinned flag, returning nil when the task does
+// not exist
+func (r *SQLiteTaskRepository) TogglePin(id int) (*Task, error) {
+	result, err := r.db.Exec(`UPDATE tasks SET pinned = NOT pinned, updated_at = ? WHERE id = ?`, r.clock.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+	
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, nil
+	}
+	
+	return r.GetByID(id)
+}
+
+// Move places a task at the given 1-based position, shifting the tasks in
+// between so positions stay contiguous
+func (r *SQLiteTaskRepository) Move(id int, position int) (*Task, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRow(`SELECT position FROM tasks WHERE id = ?`, id).Scan(&current); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var maxPosition int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(position), 0) FROM tasks`).Scan(&maxPosition); err != nil {
+		return nil, err
+	}
+	if position < 1 {
+		position = 1
+	}
+	if position > maxPosition {
+		position = maxPosition
+	}
+
+	if position < current {
+		_, err = tx.Exec(`UPDATE tasks SET position = position + 1 WHERE position >= ? AND position < ?`, position, current)
+	} else if position > current {
+		_, err = tx.Exec(`UPDATE tasks SET position = position - 1 WHERE position > ? AND position <= ?`, current, position)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE tasks SET position = ?, updated_at = ? WHERE id = ?`, position, r.clock.Now(), id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(id)
+}
+
+// Reorder assigns positions 1..n to the given task IDs in order. Tasks not
+// listed keep their relative order and are placed after the listed ones.
+func (r *SQLiteTaskRepository) Reorder(ids []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	listed := make(map[int]bool, len(ids))
+	order := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !listed[id] {
+			listed[id] = true
+			order = append(order, id)
+		}
+	}
+
+	rows, err := tx.Query(`SELECT id FROM tasks ORDER BY position ASC, id ASC`)
+	if err != nil {
+		return err
+	}
+	existing := make(map[int]bool)
+	var rest []int
 	for rows.Next() {
-		var task Task
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.DueDate, &task.Status, &task.CreatedAt, &task.UpdatedAt)
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = true
+		if !listed[id] {
+			rest = append(rest, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		if !existing[id] {
+			return sql.ErrNoRows
+		}
+	}
+
+	stmt, err := tx.Prepare(`UPDATE tasks SET position = ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, id := range append(order, rest...) {
+		if _, err := stmt.Exec(i+1, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// compactPositions renumbers the tasks 1..n in their current order,
+// closing the gaps deleted tasks leave
+func compactPositions(tx sqlTx) error {
+	rows, err := tx.Query(`SELECT id, position FROM tasks ORDER BY position ASC, id ASC`)
+	if err != nil {
+		return err
+	}
+	var moved, positions []int
+	for n := 1; rows.Next(); n++ {
+		var id, position int
+		if err := rows.Scan(&id, &position); err != nil {
+			rows.Close()
+			return err
+		}
+		if position != n {
+			moved = append(moved, id)
+			positions = append(positions, n)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(`UPDATE tasks SET position = ? WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, id := range moved {
+		if _, err := stmt.Exec(positions[i], id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectionWhere builds a WHERE clause matching the given IDs, or the filter
+// when no IDs are given
+func selectionWhere(ids []int, filter TaskFilter) (string, []interface{}) {
+	if len(ids) == 0 {
+		return filter.whereClause()
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return " WHERE id IN (" + strings.Join(placeholders, ", ") + ")", args
+}
+
+// UpdateBatch applies a change set to the selected tasks in one transaction
+// and returns the number of tasks updated
+func (r *SQLiteTaskRepository) UpdateBatch(ids []int, filter TaskFilter, changes TaskChanges) (int, error) {
+	where, whereArgs := selectionWhere(ids, filter)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Reject change sets that would leave a task starting after it is due
+	var conflictQuery string
+	var conflictArgs []interface{}
+	if changes.StartDate != nil && changes.DueDate == nil {
+		conflictQuery = "due_date IS NOT NULL AND due_date < ?"
+		conflictArgs = []interface{}{changes.StartDate.UTC()}
+	} else if changes.DueDate != nil && changes.StartDate == nil {
+		conflictQuery = "start_date IS NOT NULL AND start_date > ?"
+		conflictArgs = []interface{}{changes.DueDate.UTC()}
+	}
+	if conflictQuery != "" {
+		prefix := " WHERE "
+		if where != "" {
+			prefix = where + " AND "
+		}
+		var conflicts int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM tasks`+prefix+conflictQuery, append(whereArgs, conflictArgs...)...).Scan(&conflicts)
 		if err != nil {
-			return nil, err
+			return 0, err
+		}
+		if conflicts > 0 {
+			return 0, &ValidationError{Field: "changes", Message: "change would set start_date after due_date on one or more tasks"}
 		}
-		tasks = append(tasks, task)
 	}
-	
-	return tasks, nil
+
+	now := r.clock.Now()
+	sets := []string{"updated_at = ?"}
+	args := []interface{}{now}
+	if changes.Status != nil {
+		sets = append(sets, "status = ?")
+		args = append(args, *changes.Status)
+		// Tasks already completed keep their original completion time
+		if *changes.Status == "completed" {
+			sets = append(sets, "completed_at = COALESCE(completed_at, ?)")
+			args = append(args, now.UTC())
+		} else {
+			sets = append(sets, "completed_at = NULL")
+		}
+	}
+	if changes.StartDate != nil {
+		sets = append(sets, "start_date = ?")
+		args = append(args, changes.StartDate.UTC())
+	}
+	if changes.DueDate != nil {
+		sets = append(sets, "due_date = ?")
+		args = append(args, changes.DueDate.UTC())
+	}
+	if changes.Archived != nil {
+		sets = append(sets, "archived = ?")
+		args = append(args, *changes.Archived)
+	}
+
+	result, err := tx.Exec(`UPDATE tasks SET `+strings.Join(sets, ", ")+where, append(args, whereArgs...)...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
+
+// DeleteBatch deletes the selected tasks in one transaction and returns the
+// number deleted. With dryRun set it only counts the matching tasks.
+func (r *SQLiteTaskRepository) DeleteBatch(ids []int, filter TaskFilter, dryRun bool) (int, error) {
+	where, args := selectionWhere(ids, filter)
+
+	if dryRun {
+		var count int
+		if err := r.db.QueryRow(`SELECT COUNT(*) FROM tasks`+where, args...).Scan(&count); err != nil {
+			return 0, err
+		}
+		return count, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM task_links WHERE task_id IN (SELECT id FROM tasks`+where+`)`, args...); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_notes WHERE task_id IN (SELECT id FROM tasks`+where+`)`, args...); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM tasks`+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected > 0 {
+		if err := compactPositions(tx); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
+
+// CheckSchema fails when a table or column this repository reads is missing,
+// which means a migration has not been applied
+func (r *SQLiteTaskRepository) CheckSchema() error {
+	for _, query := range []string{
+		`SELECT ` + taskColumns + ` FROM tasks LIMIT 0`,
+		`SELECT ` + linkColumns + ` FROM task_links LIMIT 0`,
+		`SELECT ` + noteColumns + ` FROM task_notes LIMIT 0`,
+		`SELECT ` + scheduleColumns + ` FROM schedules LIMIT 0`,
+		`SELECT ` + webhookColumns + ` FROM webhooks LIMIT 0`,
+		`SELECT seq, task_id, kind, changed_at FROM task_changes LIMIT 0`,
+	} {
+		rows, err := r.db.Query(query)
+		if err != nil {
+			return err
+		}
+		rows.Close()
+	}
+	return nil
 }
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"path/filepath"
+	"testing"
+	"to-do-api/database"
+	"to-do-api/models"
+)
+
+// positionRepository is the part of a repository the position tests use
+type positionRepository interface {
+	Create(taskReq *models.TaskRequest) (*models.Task, error)
+	Delete(id int, check models.VersionCheck) error
+	DeleteBatch(ids []int, filter models.TaskFilter, dryRun bool) (int, error)
+	Move(id int, position int) (*models.Task, error)
+	GetByID(id int) (*models.Task, error)
+}
+
+// positionBackends opens a fresh repository of each storage backend
+func positionBackends(t *testing.T) map[string]positionRepository {
+	t.Helper()
+	dir := t.TempDir()
+
+	t.Setenv("LIBSQL_URL", "")
+	t.Setenv("DB_PATH", filepath.Join(dir, "tasks.db"))
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	t.Setenv("BOLT_PATH", filepath.Join(dir, "tasks.bolt"))
+	boltDB, err := database.InitBolt()
+	if err != nil {
+		t.Fatalf("InitBolt: %v", err)
+	}
+	t.Cleanup(func() { boltDB.Close() })
+
+	return map[string]positionRepository{
+		"sqlite": models.NewSQLiteTaskRepository(db),
+		"bolt":   models.NewBoltTaskRepository(boltDB),
+	}
+}
+
+// wantPositions fails the test unless each task has the position it is
+// listed at, counting from 1
+func wantPositions(t *testing.T, repo positionRepository, ids ...int) {
+	t.Helper()
+	for i, id := range ids {
+		task, err := repo.GetByID(id)
+		if err != nil || task == nil {
+			t.Fatalf("GetByID(%d) = %v, %v", id, task, err)
+		}
+		if task.Position != i+1 {
+			t.Errorf("task %d: position = %d, want %d", id, task.Position, i+1)
+		}
+	}
+}
+
+func TestMoveAfterDelete(t *testing.T) {
+	for name, repo := range positionBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			var ids []int
+			for _, title := range []string{"a", "b", "c", "d", "e", "f"} {
+				task, err := repo.Create(&models.TaskRequest{Title: title, Status: "pending"})
+				if err != nil {
+					t.Fatalf("Create(%q): %v", title, err)
+				}
+				ids = append(ids, task.ID)
+			}
+			a, b, c, d, e, f := ids[0], ids[1], ids[2], ids[3], ids[4], ids[5]
+
+			if err := repo.Delete(b, nil); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			wantPositions(t, repo, a, c, d, e, f)
+
+			if n, err := repo.DeleteBatch([]int{a, e}, models.TaskFilter{}, false); err != nil || n != 2 {
+				t.Fatalf("DeleteBatch = %d, %v; want 2", n, err)
+			}
+			wantPositions(t, repo, c, d, f)
+
+			// Position 2 is the second of the remaining tasks
+			if _, err := repo.Move(f, 2); err != nil {
+				t.Fatalf("Move: %v", err)
+			}
+			wantPositions(t, repo, c, f, d)
+
+			// Positions past the end go last
+			if _, err := repo.Move(c, 10); err != nil {
+				t.Fatalf("Move: %v", err)
+			}
+			wantPositions(t, repo, f, d, c)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SummaryLength is the maximum number of runes in a task summary
+const SummaryLength = 140
+
+// normalizeTitle converts a title to NFC and removes all control characters
+func normalizeTitle(s string) string {
+	return norm.NFC.String(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s))
+}
+
+// normalizeDescription converts a description to NFC and removes control
+// characters other than newlines and tabs
+func normalizeDescription(s string) string {
+	return norm.NFC.String(strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s))
+}
+
+// Normalize cleans up the request's free-text fields so search, sorting and
+// deduplication see a single canonical form
+func (tr *TaskRequest) Normalize() {
+	// Encrypted title and description are opaque ciphertext
+	if tr.Encryption == nil {
+		tr.Title = normalizeTitle(tr.Title)
+		tr.Description = normalizeDescription(tr.Description)
+	}
+	tr.Color = normalizeColor(tr.Color)
+	tr.Location.Normalize()
+	tr.UUID = normalizeUUID(tr.UUID)
+}
+
+// Normalize cleans up the patch's free-text fields. Ciphertext for an
+// encrypted task is base64, which normalization leaves unchanged.
+func (p *TaskPatch) Normalize() {
+	if p.Title != nil {
+		title := normalizeTitle(*p.Title)
+		p.Title = &title
+	}
+	if p.Description != nil {
+		description := normalizeDescription(*p.Description)
+		p.Description = &description
+	}
+	if p.Color != nil {
+		color := normalizeColor(*p.Color)
+		p.Color = &color
+	}
+	p.Location.Value.Normalize()
+}
+
+// TruncateGraphemes returns the longest prefix of s holding at most maxRunes
+// runes without splitting a grapheme cluster, so emoji sequences and
+// combining marks are never cut in half
+func TruncateGraphemes(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+
+	end, runes := 0, 0
+	state := -1
+	rest := s
+	for len(rest) > 0 {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		n := utf8.RuneCountInString(cluster)
+		if runes+n > maxRunes {
+			break
+		}
+		runes += n
+		end += len(cluster)
+	}
+	return s[:end]
+}
+
+// Summarize fills the computed summary field from the description. Encrypted
+// descriptions have no meaningful summary.
+func (t *Task) Summarize() {
+	if t.Encryption != nil {
+		return
+	}
+	t.Summary = TruncateGraphemes(t.Description, SummaryLength)
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Errors from Begin, Commit and Rollback
+var (
+	ErrTransactionOpen = errors.New("a transaction is already open")
+	ErrNoTransaction   = errors.New("no transaction is open")
+)
+
+// sqlTx is a transaction a repository method runs its statements in: a
+// *sql.Tx, or a savepoint within the transaction a batch runs in
+type sqlTx interface {
+	dbExecutor
+	Prepare(query string) (*sql.Stmt, error)
+	Commit() error
+	Rollback() error
+}
+
+// sqlConn is what a repository runs its statements on: the database, or
+// the transaction opened by Begin
+type sqlConn interface {
+	dbExecutor
+	Begin() (sqlTx, error)
+}
+
+// sqlDB runs statements on the database, each method call in its own
+// transaction
+type sqlDB struct {
+	*sql.DB
+}
+
+func (db sqlDB) Begin() (sqlTx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// sqlBatch runs every statement in one open transaction. A method that
+// needs a transaction of its own gets a savepoint, so a failed call still
+// leaves no partial writes behind.
+type sqlBatch struct {
+	*sql.Tx
+	depth int
+}
+
+func (b *sqlBatch) Begin() (sqlTx, error) {
+	name := fmt.Sprintf("sp%d", b.depth+1)
+	if _, err := b.Exec(`SAVEPOINT ` + name); err != nil {
+		return nil, err
+	}
+	b.depth++
+	return &sqlSavepoint{batch: b, name: name}, nil
+}
+
+// sqlSavepoint is a nested transaction within a sqlBatch
+type sqlSavepoint struct {
+	batch *sqlBatch
+	name  string
+	done  bool
+}
+
+func (s *sqlSavepoint) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.batch.Exec(query, args...)
+}
+
+func (s *sqlSavepoint) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.batch.Query(query, args...)
+}
+
+func (s *sqlSavepoint) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.batch.QueryRow(query, args...)
+}
+
+func (s *sqlSavepoint) Prepare(query string) (*sql.Stmt, error) {
+	return s.batch.Prepare(query)
+}
+
+// Commit releases the savepoint, keeping its writes in the transaction
+func (s *sqlSavepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	s.batch.depth--
+	_, err := s.batch.Exec(`RELEASE ` + s.name)
+	return err
+}
+
+// Rollback discards the savepoint's writes
+func (s *sqlSavepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	s.batch.depth--
+	if _, err := s.batch.Exec(`ROLLBACK TO ` + s.name); err != nil {
+		return err
+	}
+	_, err := s.batch.Exec(`RELEASE ` + s.name)
+	return err
+}
+
+// Begin opens a transaction that every later call runs in, until Commit or
+// Rollback. Other callers must stay off the repository meanwhile: they
+// would run in the transaction too.
+func (r *SQLiteTaskRepository) Begin() error {
+	if r.tx != nil {
+		return ErrTransactionOpen
+	}
+	tx, err := r.pool.Begin()
+	if err != nil {
+		return err
+	}
+	r.tx = &sqlBatch{Tx: tx}
+	r.db = r.tx
+	return nil
+}
+
+// Commit ends the transaction opened by Begin, keeping its writes
+func (r *SQLiteTaskRepository) Commit() error {
+	if r.tx == nil {
+		return ErrNoTransaction
+	}
+	tx := r.tx
+	r.tx, r.db = nil, sqlDB{r.pool}
+	return tx.Commit()
+}
+
+// Rollback ends the transaction opened by Begin, discarding its writes
+func (r *SQLiteTaskRepository) Rollback() error {
+	if r.tx == nil {
+		return ErrNoTransaction
+	}
+	tx := r.tx
+	r.tx, r.db = nil, sqlDB{r.pool}
+	return tx.Rollback()
+}
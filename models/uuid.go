@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrUUIDTaken is returned when a new task's UUID already belongs to
+// another task
+var ErrUUIDTaken = errors.New("uuid is already in use")
+
+// uuidPattern matches a UUID in its canonical lowercase form
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// normalizeUUID lowercases a UUID so each one has a single stored form
+func normalizeUUID(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// IsUUID reports whether s is a UUID in canonical lowercase form
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// validateUUID checks a client-generated UUID; empty is valid
+func validateUUID(s string) error {
+	if s != "" && !IsUUID(s) {
+		return &ValidationError{Field: "uuid", Message: "uuid must be a UUID such as 123e4567-e89b-12d3-a456-426614174000"}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import "errors"
+
+// ErrVersionMismatch is returned by a conditional write when the stored
+// task is no longer the version the caller expected
+var ErrVersionMismatch = errors.New("task was modified since it was read")
+
+// VersionCheck reports whether the stored task is the version a
+// conditional write expects, such as the one an If-Match ETag was taken
+// from. Repositories run it under the same lock or transaction as the
+// write, so no other write can land in between. A nil check makes the
+// write unconditional.
+type VersionCheck func(current *Task) bool
+
+// Verify runs the check against the stored task, returning
+// ErrVersionMismatch when it fails
+func (check VersionCheck) Verify(current *Task) error {
+	if check != nil && !check(current) {
+		return ErrVersionMismatch
+	}
+	return nil
+}
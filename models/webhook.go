@@ -0,0 +1,173 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to.
+// task.completed is sent alongside task.created or task.updated when the
+// write completed the task.
+var WebhookEvents = []string{"task.created", "task.updated", "task.completed", "task.deleted", "task.restored", "tasks.changed"}
+
+// maxWebhookURLLength bounds a webhook URL
+const maxWebhookURLLength = 2048
+
+// Webhook is a URL that receives task events
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Events are the subscribed event types; empty means all of them
+	Events []string `json:"events"`
+	// Secret keys the HMAC signature of each delivery. It is only returned
+	// when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Wants reports whether the webhook subscribes to eventType
+func (wh *Webhook) Wants(eventType string) bool {
+	return len(wh.Events) == 0 || containsString(wh.Events, eventType)
+}
+
+// WebhookRequest represents the request payload for registering a webhook
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// Normalize trims the URL and drops blank and duplicate event types
+func (wr *WebhookRequest) Normalize() {
+	wr.URL = strings.TrimSpace(wr.URL)
+	var events []string
+	for _, e := range wr.Events {
+		e = strings.TrimSpace(e)
+		if e != "" && !containsString(events, e) {
+			events = append(events, e)
+		}
+	}
+	wr.Events = events
+}
+
+// Validate validates the webhook request
+func (wr *WebhookRequest) Validate() error {
+	if wr.URL == "" {
+		return &ValidationError{Field: "url", Message: "url is required"}
+	}
+	if len(wr.URL) > maxWebhookURLLength {
+		return &ValidationError{Field: "url", Message: fmt.Sprintf("url must be at most %d characters", maxWebhookURLLength)}
+	}
+	u, err := url.Parse(wr.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &ValidationError{Field: "url", Message: "url must be an absolute http or https URL"}
+	}
+	for _, e := range wr.Events {
+		if !containsString(WebhookEvents, e) {
+			return &ValidationError{Field: "events", Message: "events must be among: " + strings.Join(WebhookEvents, ", ")}
+		}
+	}
+	return nil
+}
+
+// Webhook returns an unsaved webhook built from the request, with a new
+// random secret
+func (wr *WebhookRequest) Webhook() (*Webhook, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	events := wr.Events
+	if events == nil {
+		events = []string{}
+	}
+	return &Webhook{URL: wr.URL, Events: events, Secret: "whsec_" + hex.EncodeToString(secret)}, nil
+}
+
+// webhookColumns is the column list shared by every webhook SELECT
+const webhookColumns = "id, url, events, secret, created_at"
+
+func scanWebhook(row rowScanner) (Webhook, error) {
+	var wh Webhook
+	var events string
+	if err := row.Scan(&wh.ID, &wh.URL, &events, &wh.Secret, &wh.CreatedAt); err != nil {
+		return wh, err
+	}
+	wh.Events = []string{}
+	if events != "" {
+		wh.Events = strings.Split(events, ",")
+	}
+	return wh, nil
+}
+
+// ListWebhooks returns every webhook, oldest first
+func (r *SQLiteTaskRepository) ListWebhooks() ([]Webhook, error) {
+	rows, err := r.db.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook returns a webhook by ID, or nil when it does not exist
+func (r *SQLiteTaskRepository) GetWebhook(id int) (*Webhook, error) {
+	wh, err := scanWebhook(r.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wh, nil
+}
+
+// CreateWebhook stores a new webhook
+func (r *SQLiteTaskRepository) CreateWebhook(req *WebhookRequest) (*Webhook, error) {
+	wh, err := req.Webhook()
+	if err != nil {
+		return nil, err
+	}
+	wh.CreatedAt = r.clock.Now()
+	result, err := r.db.Exec(`INSERT INTO webhooks (url, events, secret, created_at) VALUES (?, ?, ?, ?)`,
+		wh.URL, strings.Join(wh.Events, ","), wh.Secret, wh.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	wh.ID = int(id)
+	return wh, nil
+}
+
+// DeleteWebhook removes a webhook. It returns sql.ErrNoRows when the
+// webhook does not exist.
+func (r *SQLiteTaskRepository) DeleteWebhook(id int) error {
+	result, err := r.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
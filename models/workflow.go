@@ -0,0 +1,44 @@
+package models
+
+import "fmt"
+
+// statusTransitions lists the statuses each status may move to. Tasks move
+// forward through the workflow, may step back from in_progress to pending,
+// and a completed task can only be reopened to pending.
+var statusTransitions = map[string][]string{
+	"pending":     {"in_progress", "completed"},
+	"in_progress": {"pending", "completed"},
+	"completed":   {"pending"},
+}
+
+// CheckTransition reports whether a task may move from one status to another.
+// Staying in the same status is always allowed.
+func CheckTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &ValidationError{Field: "status", Message: fmt.Sprintf("cannot move a task from %s to %s", from, to)}
+}
+
+// TransitionRequest represents the request payload for moving many tasks to
+// a new status
+type TransitionRequest struct {
+	IDs    []int  `json:"ids"`
+	Status string `json:"status"`
+}
+
+// Validate validates the transition request
+func (tr *TransitionRequest) Validate(maxIDs int) error {
+	if len(tr.IDs) == 0 || len(tr.IDs) > maxIDs {
+		return &ValidationError{Field: "ids", Message: fmt.Sprintf("ids must contain between 1 and %d task IDs", maxIDs)}
+	}
+	if !isValidStatus(tr.Status) {
+		return &ValidationError{Field: "status", Message: "status must be one of: pending, in_progress, completed"}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	"fmt"
+	"time"
+)
+
+// Deprecation describes a route slated for removal
+type Deprecation struct {
+	// Since is when the route was deprecated; required
+	Since time.Time
+	// Sunset is when the route may stop working
+	Sunset time.Time
+	// Successor is the path that replaces the route, if any
+	Successor string
+	// Migration tells clients what to do instead, for the document
+	Migration string
+	// Info links to documentation of the deprecation, if any
+	Info string
+}
+
+// Deprecations lists the routes slated for removal, keyed like summaries.
+// They are marked deprecated in the document and answered with
+// Deprecation, Sunset and Link headers (middleware.Deprecations).
+var Deprecations = map[string]Deprecation{
+	"POST /api/tasks/archive-completed": {
+		Since:     time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, time.April, 15, 0, 0, 0, 0, time.UTC),
+		Successor: "/api/tasks/bulk",
+		Migration: `Send PATCH /api/tasks/bulk with {"filter": {"status": "completed"}, "changes": {"archived": true}}, which /api/undo reverts the same way.`,
+		Info:      "/docs",
+	},
+}
+
+// describe explains a deprecation in an operation's description
+func (d Deprecation) describe() string {
+	text := fmt.Sprintf("Deprecated since %s.", d.Since.Format("2006-01-02"))
+	if !d.Sunset.IsZero() {
+		text += fmt.Sprintf(" May be removed after %s.", d.Sunset.Format("2006-01-02"))
+	}
+	if d.Migration != "" {
+		text += " " + d.Migration
+	} else if d.Successor != "" {
+		text += " Use " + d.Successor + " instead."
+	}
+	return text
+}
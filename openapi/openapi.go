@@ -0,0 +1,215 @@
+// Package openapi builds an OpenAPI 3.1 description of the API by walking the
+// router, so every registered route is documented and the document can't
+// drift from the handlers. Request and response bodies reference the
+// published JSON Schemas, which OpenAPI 3.1 accepts unchanged.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"to-do-api/schemas"
+
+	"github.com/gorilla/mux"
+)
+
+// Version is the API version reported in the document
+const Version = "1.0.0"
+
+// pathVariable matches a mux path variable with an optional pattern
+var pathVariable = regexp.MustCompile(`\{([^{}:]+)(?::([^{}]+))?\}`)
+
+// Build describes every route in router that is restricted to methods.
+// bodies maps "METHOD /path/template" to the schema of the request body, as
+// schemas.RequestBodies does.
+func Build(router *mux.Router, bodies map[string]string) (map[string]interface{}, error) {
+	paths := map[string]map[string]interface{}{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Prefix routes such as static files are not part of the API
+			return nil
+		}
+
+		path := pathVariable.ReplaceAllString(template, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		for _, method := range methods {
+			paths[path][strings.ToLower(method)] = operation(method, template, path, bodies)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	components, err := componentSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "To-Do API",
+			"version":     Version,
+			"description": "Task management API. Successful responses wrap their payload in a SuccessResponse envelope; errors use ErrorResponse.",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": components},
+	}, nil
+}
+
+// operation describes one method on a route
+func operation(method, template, path string, bodies map[string]string) map[string]interface{} {
+	key := method + " " + template
+	op := map[string]interface{}{
+		"operationId": operationID(method, path),
+		"responses":   responses(path),
+	}
+	if summary, ok := summaries[key]; ok {
+		op["summary"] = summary
+	}
+	if d, ok := Deprecations[key]; ok {
+		op["deprecated"] = true
+		op["description"] = d.describe()
+	}
+	if t := tag(path); t != "" {
+		op["tags"] = []string{t}
+	}
+
+	var params []map[string]interface{}
+	for _, m := range pathVariable.FindAllStringSubmatch(template, -1) {
+		schema := map[string]interface{}{"type": "string"}
+		if m[2] == "[0-9]+" {
+			schema = map[string]interface{}{"type": "integer", "minimum": 0}
+		} else if m[2] != "" {
+			schema["pattern"] = "^" + m[2] + "$"
+		}
+		params = append(params, map[string]interface{}{"name": m[1], "in": "path", "required": true, "schema": schema})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if name, ok := bodies[key]; ok {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": ref(name)}},
+		}
+	}
+	return op
+}
+
+// responses describes the envelopes API routes answer with; other routes
+// only document success
+func responses(path string) map[string]interface{} {
+	if !strings.HasPrefix(path, "/api/") {
+		return map[string]interface{}{"200": map[string]interface{}{"description": "OK"}}
+	}
+	return map[string]interface{}{
+		"2XX": map[string]interface{}{
+			"description": "Success",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("success-response")}},
+		},
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("error-response")}},
+		},
+	}
+}
+
+// operationID derives a stable identifier such as "patch_tasks_id"
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.Split(strings.TrimPrefix(path, "/api"), "/") {
+		part = strings.Trim(part, "{}")
+		if part != "" {
+			id += "_" + strings.NewReplacer("-", "_", ".", "_").Replace(part)
+		}
+	}
+	return id
+}
+
+// tag groups API operations by the first path segment after /api; other
+// routes and documents such as /api/openapi.json are left untagged
+func tag(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return ""
+	}
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+	if strings.Contains(segment, ".") {
+		return ""
+	}
+	return segment
+}
+
+// ref points at a component schema
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// componentSchemas loads every published schema, rewriting the references
+// between schema files into component references
+func componentSchemas() (map[string]interface{}, error) {
+	components := map[string]interface{}{}
+	for _, name := range schemas.Names() {
+		data, _ := schemas.Get(name)
+		var schema map[string]interface{}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, err
+		}
+		delete(schema, "$schema")
+		delete(schema, "$id")
+		rewriteRefs(schema)
+		components[name] = schema
+	}
+	return components, nil
+}
+
+// rewriteRefs turns "$ref": "task.json" into a component reference
+func rewriteRefs(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && key == "$ref" && strings.HasSuffix(s, ".json") {
+				v[key] = "#/components/schemas/" + strings.TrimSuffix(s, ".json")
+				continue
+			}
+			rewriteRefs(value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			rewriteRefs(item)
+		}
+	}
+}
+
+// Handler serves the document for router as JSON. It is built on the first
+// request, once every route has been registered.
+func Handler(router *mux.Router, bodies map[string]string) http.Handler {
+	var once sync.Once
+	var doc []byte
+	var buildErr error
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var spec map[string]interface{}
+			if spec, buildErr = Build(router, bodies); buildErr == nil {
+				doc, buildErr = json.Marshal(spec)
+			}
+		})
+		if buildErr != nil {
+			http.Error(w, "Failed to build OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+}
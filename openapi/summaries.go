@@ -0,0 +1,65 @@
+package openapi
+
+// summaries gives operations a one-line summary, keyed like
+// schemas.RequestBodies. Routes missing here are still documented, just
+// without a summary.
+var summaries = map[string]string{
+	"GET /health":                                         "Health check",
+	"GET /debug/vars":                                     "Runtime metrics (expvar)",
+	"GET /debug/events":                                   "Event bus subscribers and drops",
+	"GET /":                                               "Web UI",
+	"GET /docs":                                           "Interactive API documentation",
+	"GET /api/openapi.json":                               "This OpenAPI document",
+	"GET /api/tasks":                                      "List tasks with filters, sorting and pagination",
+	"POST /api/tasks":                                     "Create a task",
+	"DELETE /api/tasks":                                   "Delete tasks by IDs or filter",
+	"GET /api/tasks/overdue":                              "List open tasks past their due date",
+	"GET /api/tasks/today":                                "List open tasks due by the end of today",
+	"GET /api/tasks/upcoming":                             "List open tasks due in the next days",
+	"POST /api/tasks/bulk":                                "Create up to 100 tasks",
+	"PATCH /api/tasks/bulk":                               "Update tasks by IDs or filter",
+	"POST /api/tasks/archive-completed":                   "Archive every completed task",
+	"POST /api/tasks/transition":                          "Move tasks to a status, checking the workflow",
+	"POST /api/tasks/complete-all":                        "Complete every open task matching the list filters",
+	"PUT /api/tasks/reorder":                              "Set the manual order of tasks",
+	"GET /api/tasks/{id:[0-9]+}":                          "Get a task",
+	"PUT /api/tasks/{id:[0-9]+}":                          "Replace a task",
+	"PATCH /api/tasks/{id:[0-9]+}":                        "Update some fields of a task",
+	"DELETE /api/tasks/{id:[0-9]+}":                       "Delete a task",
+	"POST /api/tasks/{id:[0-9]+}/move":                    "Move a task to a position",
+	"POST /api/tasks/{id:[0-9]+}/duplicate":               "Copy a task",
+	"POST /api/tasks/{id:[0-9]+}/pin":                     "Toggle whether a task is pinned",
+	"POST /api/tasks/{id:[0-9]+}/snooze":                  "Push a task's due date back",
+	"GET /api/tasks/{id:[0-9]+}/links":                    "List a task's links",
+	"POST /api/tasks/{id:[0-9]+}/links":                   "Attach a link to a task",
+	"PUT /api/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}":    "Replace a link",
+	"DELETE /api/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}": "Remove a link",
+	"GET /api/tasks/{id:[0-9]+}/notes":                    "List a task's notes",
+	"POST /api/tasks/{id:[0-9]+}/notes":                   "Add a note to a task",
+	"PUT /api/tasks/external/{source}/{externalID}":       "Create or update a task imported from another system",
+	"GET /api/tasks/uuid/{uuid}":                          "Get a task by its client-generated UUID",
+	"POST /api/undo":                                      "Undo the last delete or bulk change",
+	"POST /api/batch":                                     "Run several API requests in one transaction",
+	"POST /api/sync":                                      "Push offline changes and pull everyone else's",
+	"GET /api/planner/today":                              "Printable HTML plan for today",
+	"GET /api/board":                                      "Kanban board grouped by status",
+	"GET /api/changes":                                    "Tasks created, updated or deleted since a cursor",
+	"GET /api/stats":                                      "Task counts and recent activity",
+	"GET /api/stats/completions":                          "Completed tasks per day or week",
+	"GET /api/schedules":                                  "List recurring task schedules",
+	"POST /api/schedules":                                 "Create a recurring task schedule",
+	"GET /api/schedules/{id:[0-9]+}":                      "Get a schedule",
+	"DELETE /api/schedules/{id:[0-9]+}":                   "Delete a schedule",
+	"GET /api/schemas":                                    "List the published JSON Schemas",
+	"GET /api/schemas/{name}":                             "Get a JSON Schema",
+	"GET /api/events":                                     "Live task events as Server-Sent Events",
+	"GET /api/ws":                                         "Live task events over a WebSocket",
+	"GET /api/webhooks":                                   "List webhooks",
+	"POST /api/webhooks":                                  "Register a webhook",
+	"GET /api/webhooks/{id:[0-9]+}":                       "Get a webhook",
+	"DELETE /api/webhooks/{id:[0-9]+}":                    "Delete a webhook",
+	"GET /api/webhooks/{id:[0-9]+}/deliveries":            "Recent delivery attempts of a webhook",
+	"GET /api/webhooks/keys":                              "Public keys for verifying webhook signatures",
+	"GET /api/workspace":                                  "Workspace settings and quota",
+	"GET /api/admin/diagnostics":                          "Live operational checks",
+}
@@ -0,0 +1,129 @@
+// Package recurring materializes occurrences of recurring task schedules as
+// ordinary tasks ahead of time, so views of upcoming work include them.
+package recurring
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+	"to-do-api/models"
+)
+
+// Defaults for NewGenerator
+const (
+	// DefaultHorizon is how far ahead occurrences are materialized
+	DefaultHorizon = 14 * 24 * time.Hour
+	// DefaultInterval is how often schedules are checked
+	DefaultInterval = time.Minute
+	// maxPerPass bounds the occurrences one schedule may create per pass,
+	// so a short interval over a long horizon catches up gradually
+	maxPerPass = 100
+)
+
+// Generator periodically creates the tasks for schedule occurrences that
+// fall within the horizon. Each occurrence becomes a task with source
+// "schedule" and an external ID naming the schedule and due date, created
+// through UpsertExternal so an occurrence is never materialized twice.
+type Generator struct {
+	repo     models.TaskRepository
+	clock    models.Clock
+	horizon  time.Duration
+	interval time.Duration
+	wake     chan struct{}
+	// lock, when set, is held for each pass
+	lock sync.Locker
+}
+
+// NewGenerator creates a generator for the schedules in repo. Zero horizon
+// or interval select the defaults.
+func NewGenerator(repo models.TaskRepository, horizon, interval time.Duration) *Generator {
+	if horizon <= 0 {
+		horizon = DefaultHorizon
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Generator{repo: repo, clock: models.SystemClock, horizon: horizon, interval: interval, wake: make(chan struct{}, 1)}
+}
+
+// SetClock replaces the clock used to decide which occurrences are due
+func (g *Generator) SetClock(clock models.Clock) {
+	g.clock = clock
+}
+
+// SetLock sets a lock held for the whole of each pass, so passes can be
+// kept off the repository while no one else may use it
+func (g *Generator) SetLock(lock sync.Locker) {
+	g.lock = lock
+}
+
+// Run generates occurrences every interval, and whenever Wake is called,
+// until ctx is cancelled
+func (g *Generator) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := g.Generate(); err != nil {
+			log.Printf("Error generating recurring tasks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-g.wake:
+		}
+	}
+}
+
+// Wake asks Run to generate occurrences now, for example after a schedule
+// was created. It never blocks.
+func (g *Generator) Wake() {
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Generate makes one pass over every schedule and returns the number of
+// tasks created. Occurrences due before the schedule was created are
+// skipped rather than back-filled as overdue tasks.
+func (g *Generator) Generate() (int, error) {
+	if g.lock != nil {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+	}
+
+	schedules, err := g.repo.ListSchedules()
+	if err != nil {
+		return 0, err
+	}
+
+	until := g.clock.Now().Add(g.horizon)
+	created := 0
+	for i := range schedules {
+		s := &schedules[i]
+		generated := s.Generated
+		for n := 0; n < maxPerPass; n++ {
+			due := s.Occurrence(generated)
+			if s.Ended(due) || due.After(until) {
+				break
+			}
+			if !due.Before(s.CreatedAt) {
+				_, isNew, err := g.repo.UpsertExternal(models.ScheduleSource, s.OccurrenceID(due), s.TaskRequest(due))
+				if err != nil {
+					return created, err
+				}
+				if isNew {
+					created++
+				}
+			}
+			generated++
+			// Record progress per occurrence so a failure never repeats one
+			if err := g.repo.SetScheduleGenerated(s.ID, generated); err != nil {
+				return created, err
+			}
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,24 @@
+package schemas
+
+// RequestBodies maps the routes that take a JSON body to the schema the body
+// must match. Keys are "METHOD /path/template" as registered with the
+// router. Used for SCHEMA_VALIDATION and the OpenAPI document.
+var RequestBodies = map[string]string{
+	"POST /api/tasks":                                  "task-create",
+	"PUT /api/tasks/{id:[0-9]+}":                       "task-request",
+	"PATCH /api/tasks/{id:[0-9]+}":                     "task-patch",
+	"POST /api/tasks/bulk":                             "bulk-create",
+	"PATCH /api/tasks/bulk":                            "bulk-update",
+	"DELETE /api/tasks":                                "bulk-delete",
+	"POST /api/tasks/transition":                       "transition",
+	"POST /api/schedules":                              "schedule",
+	"POST /api/webhooks":                               "webhook",
+	"POST /api/tasks/{id:[0-9]+}/move":                 "move",
+	"POST /api/tasks/{id:[0-9]+}/duplicate":            "duplicate",
+	"POST /api/tasks/{id:[0-9]+}/snooze":               "snooze",
+	"POST /api/tasks/{id:[0-9]+}/links":                "link",
+	"PUT /api/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}": "link",
+	"POST /api/tasks/{id:[0-9]+}/notes":                "note",
+	"PUT /api/tasks/reorder":                           "reorder",
+	"PUT /api/tasks/external/{source}/{externalID}":    "task-create",
+}
@@ -0,0 +1,62 @@
+// Package schemas embeds the JSON Schemas describing the API's request and
+// response bodies.
+package schemas
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed *.json
+var files embed.FS
+
+// baseURL anchors the relative $id and $ref values used by the schemas
+const baseURL = "https://to-do-api/schemas/"
+
+// Names returns the names of all published schemas, without the .json suffix
+func Names() []string {
+	entries, _ := fs.ReadDir(files, ".")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the raw schema document for name
+func Get(name string) ([]byte, bool) {
+	data, err := files.ReadFile(name + ".json")
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Compile compiles the named schemas, resolving references between them
+func Compile(names ...string) (map[string]*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft2020
+	c.AssertFormat = true
+	for _, name := range Names() {
+		data, _ := Get(name)
+		if err := c.AddResource(baseURL+name+".json", bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	compiled := make(map[string]*jsonschema.Schema, len(names))
+	for _, name := range names {
+		schema, err := c.Compile(baseURL + name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		compiled[name] = schema
+	}
+	return compiled, nil
+}
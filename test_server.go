@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
+	"to-do-api/diagnostics"
+	"to-do-api/events"
 	"to-do-api/handlers"
+	"to-do-api/i18n"
+	"to-do-api/mcp"
 	"to-do-api/middleware"
 	"to-do-api/models"
+	"to-do-api/openapi"
+	"to-do-api/recurring"
+	"to-do-api/schemas"
+	"to-do-api/webhooks"
 
 	"github.com/gorilla/mux"
 )
@@ -16,17 +32,177 @@ import (
 // InMemoryTaskRepository implements TaskRepository using in-memory storage
 // This is used for testing purposes to avoid database dependencies
 type InMemoryTaskRepository struct {
-	tasks  map[int]*models.Task
-	nextID int
-	mutex  sync.RWMutex
+	tasks      map[int]*models.Task
+	nextID     int
+	links      map[int][]models.Link
+	nextLinkID int
+	notes      map[int][]models.Note
+	nextNoteID int
+	clock      models.Clock
+	ids        models.IDGenerator
+	mutex      sync.RWMutex
+	// upserts serializes UpsertExternal so its lookup and create don't race
+	upserts sync.Mutex
+	// schedules holds recurring task schedules by ID
+	schedules      map[int]*models.Schedule
+	nextScheduleID int
+	webhooks       map[int]*models.Webhook
+	nextWebhookID  int
+	// changes holds each task's latest change by task ID
+	changes   map[int]models.Change
+	changeSeq int64
+	// saved is the state to go back to while a transaction is open
+	saved *InMemoryTaskRepository
 }
 
 // NewInMemoryTaskRepository creates a new in-memory task repository
 func NewInMemoryTaskRepository() *InMemoryTaskRepository {
 	return &InMemoryTaskRepository{
-		tasks:  make(map[int]*models.Task),
-		nextID: 1,
+		tasks:      make(map[int]*models.Task),
+		nextID:     1,
+		links:      make(map[int][]models.Link),
+		nextLinkID: 1,
+		notes:      make(map[int][]models.Note),
+		nextNoteID: 1,
+		clock:      models.SystemClock,
+
+		schedules:      make(map[int]*models.Schedule),
+		nextScheduleID: 1,
+		webhooks:       make(map[int]*models.Webhook),
+		nextWebhookID:  1,
+		changes:        make(map[int]models.Change),
+	}
+}
+
+// recordChange makes a change the latest change of task id; the caller
+// holds the write lock
+func (r *InMemoryTaskRepository) recordChange(id int, changeType string) {
+	r.changeSeq++
+	r.changes[id] = models.Change{Seq: r.changeSeq, Type: changeType, TaskID: id, ChangedAt: time.Now().UTC()}
+}
+
+// Begin opens a transaction: later calls change the data as usual, and
+// Rollback puts back the data as it is now
+func (r *InMemoryTaskRepository) Begin() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.saved != nil {
+		return models.ErrTransactionOpen
+	}
+	r.saved = &InMemoryTaskRepository{
+		tasks:          make(map[int]*models.Task, len(r.tasks)),
+		links:          make(map[int][]models.Link, len(r.links)),
+		notes:          make(map[int][]models.Note, len(r.notes)),
+		schedules:      make(map[int]*models.Schedule, len(r.schedules)),
+		webhooks:       make(map[int]*models.Webhook, len(r.webhooks)),
+		changes:        make(map[int]models.Change, len(r.changes)),
+		nextID:         r.nextID,
+		nextLinkID:     r.nextLinkID,
+		nextNoteID:     r.nextNoteID,
+		nextScheduleID: r.nextScheduleID,
+		nextWebhookID:  r.nextWebhookID,
+		changeSeq:      r.changeSeq,
+	}
+	for id, task := range r.tasks {
+		copied := *task
+		r.saved.tasks[id] = &copied
+	}
+	for id, links := range r.links {
+		r.saved.links[id] = append([]models.Link(nil), links...)
+	}
+	for id, notes := range r.notes {
+		r.saved.notes[id] = append([]models.Note(nil), notes...)
+	}
+	for id, schedule := range r.schedules {
+		copied := *schedule
+		r.saved.schedules[id] = &copied
+	}
+	for id, webhook := range r.webhooks {
+		copied := *webhook
+		r.saved.webhooks[id] = &copied
+	}
+	for id, change := range r.changes {
+		r.saved.changes[id] = change
+	}
+	return nil
+}
+
+// Commit ends the transaction, keeping its changes
+func (r *InMemoryTaskRepository) Commit() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.saved == nil {
+		return models.ErrNoTransaction
+	}
+	r.saved = nil
+	return nil
+}
+
+// Rollback ends the transaction, putting back the data saved by Begin
+func (r *InMemoryTaskRepository) Rollback() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	saved := r.saved
+	if saved == nil {
+		return models.ErrNoTransaction
+	}
+	r.saved = nil
+	r.tasks, r.links, r.notes = saved.tasks, saved.links, saved.notes
+	r.schedules, r.webhooks, r.changes = saved.schedules, saved.webhooks, saved.changes
+	r.nextID, r.nextLinkID, r.nextNoteID = saved.nextID, saved.nextLinkID, saved.nextNoteID
+	r.nextScheduleID, r.nextWebhookID, r.changeSeq = saved.nextScheduleID, saved.nextWebhookID, saved.changeSeq
+	return nil
+}
+
+// GetChange returns the latest change of a task, without the task, or nil
+// when it has none
+func (r *InMemoryTaskRepository) GetChange(taskID int) (*models.Change, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	change, exists := r.changes[taskID]
+	if !exists {
+		return nil, nil
+	}
+	return &change, nil
+}
+
+// ListChanges returns up to limit changes after since, in order
+func (r *InMemoryTaskRepository) ListChanges(since int64, limit int) ([]models.Change, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	changes := []models.Change{}
+	for _, change := range r.changes {
+		if change.Seq > since {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	for i := range changes {
+		if task, exists := r.tasks[changes[i].TaskID]; exists && changes[i].Type != models.ChangeDeleted {
+			copied := *task
+			changes[i].Task = &copied
+		}
 	}
+	return changes, nil
+}
+
+// SetClock replaces the clock used for created_at and updated_at
+func (r *InMemoryTaskRepository) SetClock(clock models.Clock) {
+	r.clock = clock
+}
+
+// SetIDGenerator makes new tasks take their IDs from gen instead of the
+// internal counter; nil restores the default
+func (r *InMemoryTaskRepository) SetIDGenerator(gen models.IDGenerator) {
+	r.ids = gen
 }
 
 // Create creates a new task
@@ -39,23 +215,161 @@ func (r *InMemoryTaskRepository) Create(taskReq *models.TaskRequest) (*models.Ta
 		status = "pending"
 	}
 
-	now := time.Now()
+	id := 0
+	if r.ids != nil {
+		id = r.ids.NextID()
+	}
+	if id <= 0 {
+		id = r.nextID
+	} else if _, exists := r.tasks[id]; exists {
+		return nil, fmt.Errorf("%w: %d", models.ErrDuplicateID, id)
+	}
+	if taskReq.UUID != "" {
+		for _, task := range r.tasks {
+			if task.UUID == taskReq.UUID {
+				return nil, fmt.Errorf("%w: %s", models.ErrUUIDTaken, taskReq.UUID)
+			}
+		}
+	}
+
+	progress := 0
+	if taskReq.Progress != nil {
+		progress = *taskReq.Progress
+	}
+
+	now := r.clock.Now()
 	task := &models.Task{
-		ID:          r.nextID,
+		ID:          id,
 		Title:       taskReq.Title,
 		Description: taskReq.Description,
+		StartDate:   taskReq.StartDate,
 		DueDate:     taskReq.DueDate,
 		Status:      status,
+		Progress:    progress,
+		Color:       taskReq.Color,
+		Encryption:  taskReq.Encryption,
+		Location:    taskReq.Location,
+		UUID:        taskReq.UUID,
+		Position:    r.nextPosition(),
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	task.MarkCompletion(now)
 
-	r.tasks[r.nextID] = task
-	r.nextID++
+	r.tasks[id] = task
+	if id >= r.nextID {
+		r.nextID = id + 1
+	}
+	r.recordChange(id, models.ChangeCreated)
 
 	return task, nil
 }
 
+// CreateBatch creates several tasks
+func (r *InMemoryTaskRepository) CreateBatch(taskReqs []*models.TaskRequest) ([]models.Task, error) {
+	tasks := make([]models.Task, 0, len(taskReqs))
+	for _, taskReq := range taskReqs {
+		task, err := r.Create(taskReq)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, nil
+}
+
+// UpdateBatch applies a change set to the selected tasks
+func (r *InMemoryTaskRepository) UpdateBatch(ids []int, filter models.TaskFilter, changes models.TaskChanges) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var selected []*models.Task
+	if len(ids) > 0 {
+		seen := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			if task, exists := r.tasks[id]; exists && !seen[id] {
+				seen[id] = true
+				selected = append(selected, task)
+			}
+		}
+	} else {
+		for _, task := range r.tasks {
+			if filter.Matches(*task) {
+				selected = append(selected, task)
+			}
+		}
+	}
+
+	for _, task := range selected {
+		startDate, dueDate := task.StartDate, task.DueDate
+		if changes.StartDate != nil {
+			startDate = changes.StartDate
+		}
+		if changes.DueDate != nil {
+			dueDate = changes.DueDate
+		}
+		if startDate != nil && dueDate != nil && startDate.After(*dueDate) {
+			return 0, &models.ValidationError{Field: "changes", Message: "change would set start_date after due_date on one or more tasks"}
+		}
+	}
+
+	now := r.clock.Now()
+	for _, task := range selected {
+		if changes.Status != nil {
+			task.Status = *changes.Status
+		}
+		if changes.StartDate != nil {
+			task.StartDate = changes.StartDate
+		}
+		if changes.DueDate != nil {
+			task.DueDate = changes.DueDate
+		}
+		if changes.Archived != nil {
+			task.Archived = *changes.Archived
+		}
+		task.UpdatedAt = now
+		task.MarkCompletion(now)
+		r.recordChange(task.ID, models.ChangeUpdated)
+	}
+
+	return len(selected), nil
+}
+
+// DeleteBatch deletes the selected tasks, or only counts them when dryRun is set
+func (r *InMemoryTaskRepository) DeleteBatch(ids []int, filter models.TaskFilter, dryRun bool) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var selected []int
+	if len(ids) > 0 {
+		seen := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			if _, exists := r.tasks[id]; exists && !seen[id] {
+				seen[id] = true
+				selected = append(selected, id)
+			}
+		}
+	} else {
+		for id, task := range r.tasks {
+			if filter.Matches(*task) {
+				selected = append(selected, id)
+			}
+		}
+	}
+
+	if !dryRun && len(selected) > 0 {
+		for _, id := range selected {
+			delete(r.tasks, id)
+			delete(r.links, id)
+			delete(r.notes, id)
+			r.recordChange(id, models.ChangeDeleted)
+		}
+		r.compactPositions()
+	}
+
+	return len(selected), nil
+}
+
 // GetAll retrieves all tasks
 func (r *InMemoryTaskRepository) GetAll() ([]models.Task, error) {
 	r.mutex.RLock()
@@ -83,7 +397,7 @@ func (r *InMemoryTaskRepository) GetByID(id int) (*models.Task, error) {
 }
 
 // Update updates a task
-func (r *InMemoryTaskRepository) Update(id int, taskReq *models.TaskRequest) (*models.Task, error) {
+func (r *InMemoryTaskRepository) Update(id int, taskReq *models.TaskRequest, check models.VersionCheck) (*models.Task, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -91,38 +405,97 @@ func (r *InMemoryTaskRepository) Update(id int, taskReq *models.TaskRequest) (*m
 	if !exists {
 		return nil, nil
 	}
+	if err := check.Verify(task); err != nil {
+		return nil, err
+	}
+	if err := taskReq.ValidateUpdate(); err != nil {
+		return nil, err
+	}
+
+	// Work on a copy so a failed check leaves the stored task untouched
+	updated := *task
+	startDate, dueDate := updated.StartDate, updated.DueDate
+	if taskReq.StartDate != nil {
+		startDate = taskReq.StartDate
+	}
+	if taskReq.DueDate != nil {
+		dueDate = taskReq.DueDate
+	}
+	if startDate != nil && dueDate != nil && startDate.After(*dueDate) {
+		return nil, &models.ValidationError{Field: "start_date", Message: "start_date must not be after due_date"}
+	}
 
-	// Update fields if provided
 	if taskReq.Title != "" {
-		task.Title = taskReq.Title
+		updated.Title = taskReq.Title
 	}
 	if taskReq.Description != "" {
-		task.Description = taskReq.Description
-	}
-	if taskReq.DueDate != nil {
-		task.DueDate = taskReq.DueDate
+		updated.Description = taskReq.Description
 	}
+	updated.StartDate = startDate
+	updated.DueDate = dueDate
+	updated.Location = taskReq.Location
 	if taskReq.Status != "" {
-		task.Status = taskReq.Status
+		updated.Status = taskReq.Status
 	}
+	if taskReq.Progress != nil {
+		updated.Progress = *taskReq.Progress
+	}
+	if taskReq.Color != "" {
+		updated.Color = taskReq.Color
+	}
+	updated.Encryption = taskReq.Encryption
 
-	task.UpdatedAt = time.Now()
-	r.tasks[id] = task
+	updated.UpdatedAt = r.clock.Now()
+	updated.MarkCompletion(updated.UpdatedAt)
+	r.tasks[id] = &updated
+	r.recordChange(id, models.ChangeUpdated)
 
-	return task, nil
+	return &updated, nil
+}
+
+// Patch applies a partial update to a task
+func (r *InMemoryTaskRepository) Patch(id int, patch *models.TaskPatch, check models.VersionCheck) (*models.Task, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	task, exists := r.tasks[id]
+	if !exists {
+		return nil, nil
+	}
+	if err := check.Verify(task); err != nil {
+		return nil, err
+	}
+
+	updated := *task
+	if err := patch.Apply(&updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = r.clock.Now()
+	updated.MarkCompletion(updated.UpdatedAt)
+	r.tasks[id] = &updated
+	r.recordChange(id, models.ChangeUpdated)
+
+	return &updated, nil
 }
 
 // Delete deletes a task
-func (r *InMemoryTaskRepository) Delete(id int) error {
+func (r *InMemoryTaskRepository) Delete(id int, check models.VersionCheck) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	_, exists := r.tasks[id]
+	task, exists := r.tasks[id]
 	if !exists {
 		return nil // Return nil for not found to match SQL behavior
 	}
+	if err := check.Verify(task); err != nil {
+		return err
+	}
 
 	delete(r.tasks, id)
+	delete(r.links, id)
+	delete(r.notes, id)
+	r.recordChange(id, models.ChangeDeleted)
+	r.compactPositions()
 	return nil
 }
 
@@ -142,38 +515,652 @@ func (r *InMemoryTaskRepository) GetByStatus(status string) ([]models.Task, erro
 }
 
 // GetAllPaginated retrieves tasks with optional filtering, sorting, and pagination
-func (r *InMemoryTaskRepository) GetAllPaginated(filterStatus *string, limit int, offset int, sortBy string, sortOrder string) ([]models.Task, error) {
+func (r *InMemoryTaskRepository) GetAllPaginated(filter models.TaskFilter, limit int, offset int, sortBy string, sortOrder string) ([]models.Task, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	// For simplicity in test mode, we'll just return all tasks with basic filtering
-	// In a real implementation, we would implement proper pagination and sorting
-	
 	var tasks []models.Task
 	for _, task := range r.tasks {
-		// Apply status filter if provided
-		if filterStatus != nil && *filterStatus != "" && task.Status != *filterStatus {
+		// Apply filters if provided
+		if !filter.Matches(*task) {
 			continue
 		}
 		
 		tasks = append(tasks, *task)
 	}
 
-	// Apply basic sorting (by ID for simplicity)
-	// In a real implementation, we would sort by the specified field and order
-	
-	// Apply pagination
-	if offset < len(tasks) {
-		end := offset + limit
-		if end > len(tasks) {
-			end = len(tasks)
+	models.SortTasks(tasks, sortBy, sortOrder)
+	tasks = models.PaginateTasks(tasks, limit, offset)
+
+	return tasks, nil
+}
+
+// Count returns the number of tasks matching the filter
+func (r *InMemoryTaskRepository) Count(filter models.TaskFilter) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	count := 0
+	for _, task := range r.tasks {
+		if filter.Matches(*task) {
+			count++
 		}
-		tasks = tasks[offset:end]
-	} else {
-		tasks = []models.Task{}
 	}
+	return count, nil
+}
 
-	return tasks, nil
+// Stats computes task statistics from a full scan
+func (r *InMemoryTaskRepository) Stats(now time.Time) (*models.TaskStats, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tasks := make([]models.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		tasks = append(tasks, *task)
+	}
+	return models.ComputeStats(tasks, now), nil
+}
+
+// CompletionTimes scans for tasks completed in [from, to)
+func (r *InMemoryTaskRepository) CompletionTimes(from, to time.Time) ([]time.Time, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tasks := make([]models.Task, 0, len(r.tasks))
+	for _, task := range r.tasks {
+		tasks = append(tasks, *task)
+	}
+	return models.FilterCompletionTimes(tasks, from, to), nil
+}
+
+// nextPosition returns the position after the last task, so tasks created
+// after a delete don't share a position; callers must hold the lock
+func (r *InMemoryTaskRepository) nextPosition() int {
+	last := 0
+	for _, task := range r.tasks {
+		last = max(last, task.Position)
+	}
+	return last + 1
+}
+
+// orderedIDs returns task IDs sorted by position; callers must hold the lock
+func (r *InMemoryTaskRepository) orderedIDs() []int {
+	ids := make([]int, 0, len(r.tasks))
+	for id := range r.tasks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := r.tasks[ids[i]], r.tasks[ids[j]]
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.ID < b.ID
+	})
+	return ids
+}
+
+// compactPositions renumbers the tasks 1..n in position order, closing the
+// gaps deleted tasks leave. The caller holds the write lock.
+func (r *InMemoryTaskRepository) compactPositions() {
+	for i, id := range r.orderedIDs() {
+		if r.tasks[id].Position != i+1 {
+			r.tasks[id].Position = i + 1
+			r.recordChange(id, models.ChangeUpdated)
+		}
+	}
+}
+
+// TogglePin flips a task's pinned flag
+func (r *InMemoryTaskRepository) TogglePin(id int) (*models.Task, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	task, exists := r.tasks[id]
+	if !exists {
+		return nil, nil
+	}
+
+	updated := *task
+	updated.Pinned = !updated.Pinned
+	updated.UpdatedAt = r.clock.Now()
+	r.tasks[id] = &updated
+	r.recordChange(id, models.ChangeUpdated)
+
+	return &updated, nil
+}
+
+// Move places a task at the given 1-based position
+func (r *InMemoryTaskRepository) Move(id int, position int) (*models.Task, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	task, exists := r.tasks[id]
+	if !exists {
+		return nil, nil
+	}
+
+	ids := r.orderedIDs()
+	rest := make([]int, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			rest = append(rest, other)
+		}
+	}
+	if position < 1 {
+		position = 1
+	}
+	if position > len(ids) {
+		position = len(ids)
+	}
+	ordered := append(append(append([]int{}, rest[:position-1]...), id), rest[position-1:]...)
+	task.UpdatedAt = r.clock.Now()
+	for i, other := range ordered {
+		if r.tasks[other].Position != i+1 || other == id {
+			r.tasks[other].Position = i + 1
+			r.recordChange(other, models.ChangeUpdated)
+		}
+	}
+
+	return task, nil
+}
+
+// Reorder assigns positions to the given task IDs in order
+func (r *InMemoryTaskRepository) Reorder(ids []int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	listed := make(map[int]bool, len(ids))
+	ordered := make([]int, 0, len(r.tasks))
+	for _, id := range ids {
+		if _, exists := r.tasks[id]; !exists {
+			return sql.ErrNoRows
+		}
+		if !listed[id] {
+			listed[id] = true
+			ordered = append(ordered, id)
+		}
+	}
+	for _, id := range r.orderedIDs() {
+		if !listed[id] {
+			ordered = append(ordered, id)
+		}
+	}
+	for i, id := range ordered {
+		if r.tasks[id].Position != i+1 {
+			r.tasks[id].Position = i + 1
+			r.recordChange(id, models.ChangeUpdated)
+		}
+	}
+
+	return nil
+}
+
+// UpsertExternal updates the task imported from source under externalID, or
+// creates it when there is none yet, and reports whether it was created
+func (r *InMemoryTaskRepository) UpsertExternal(source, externalID string, taskReq *models.TaskRequest) (*models.Task, bool, error) {
+	r.upserts.Lock()
+	defer r.upserts.Unlock()
+
+	r.mutex.RLock()
+	id := 0
+	for _, task := range r.tasks {
+		if task.Source == source && task.ExternalID == externalID {
+			id = task.ID
+			break
+		}
+	}
+	r.mutex.RUnlock()
+
+	if id != 0 {
+		task, err := r.Update(id, taskReq, nil)
+		return task, false, err
+	}
+
+	task, err := r.Create(taskReq)
+	if err != nil {
+		return nil, false, err
+	}
+	r.mutex.Lock()
+	task.Source, task.ExternalID = source, externalID
+	r.mutex.Unlock()
+	return task, true, nil
+}
+
+// RestoreTasks writes snapshots back. Tasks that still exist are
+// overwritten; deleted ones are recreated with their original IDs, links
+// and notes.
+func (r *InMemoryTaskRepository) RestoreTasks(snapshots []models.TaskSnapshot) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, snapshot := range snapshots {
+		task := snapshot.Task
+		changeType := models.ChangeCreated
+		if _, exists := r.tasks[task.ID]; exists {
+			changeType = models.ChangeUpdated
+		}
+		r.tasks[task.ID] = &task
+		if task.ID >= r.nextID {
+			r.nextID = task.ID + 1
+		}
+		r.recordChange(task.ID, changeType)
+
+		// IDs grow over time, so sorting by them restores insertion order
+		if len(snapshot.Links) > 0 {
+			links := append([]models.Link(nil), r.links[task.ID]...)
+			for _, link := range snapshot.Links {
+				if !containsLink(links, link.ID) {
+					links = append(links, link)
+				}
+			}
+			sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+			r.links[task.ID] = links
+		}
+		if len(snapshot.Notes) > 0 {
+			notes := append([]models.Note(nil), r.notes[task.ID]...)
+			for _, note := range snapshot.Notes {
+				if !containsNote(notes, note.ID) {
+					notes = append(notes, note)
+				}
+			}
+			sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+			r.notes[task.ID] = notes
+		}
+	}
+	return nil
+}
+
+// ReplaceAll drops everything held and loads the backup in its place,
+// keeping every record's ID
+func (r *InMemoryTaskRepository) ReplaceAll(backup *models.Backup) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// The changes feed is kept: every task replaced is deleted as far as
+	// clients syncing from it know, and the restored ones created
+	for id := range r.tasks {
+		r.recordChange(id, models.ChangeDeleted)
+	}
+	r.tasks = make(map[int]*models.Task, len(backup.Tasks))
+	r.links = make(map[int][]models.Link)
+	r.notes = make(map[int][]models.Note)
+	r.schedules = make(map[int]*models.Schedule, len(backup.Schedules))
+	r.webhooks = make(map[int]*models.Webhook, len(backup.Webhooks))
+	r.nextID, r.nextLinkID, r.nextNoteID, r.nextScheduleID, r.nextWebhookID = 1, 1, 1, 1, 1
+
+	for _, snapshot := range backup.Tasks {
+		task := snapshot.Task
+		r.tasks[task.ID] = &task
+		r.nextID = max(r.nextID, task.ID+1)
+		r.recordChange(task.ID, models.ChangeCreated)
+		for _, link := range snapshot.Links {
+			r.links[task.ID] = append(r.links[task.ID], link)
+			r.nextLinkID = max(r.nextLinkID, link.ID+1)
+		}
+		for _, note := range snapshot.Notes {
+			r.notes[task.ID] = append(r.notes[task.ID], note)
+			r.nextNoteID = max(r.nextNoteID, note.ID+1)
+		}
+	}
+	for i := range backup.Schedules {
+		schedule := backup.Schedules[i]
+		r.schedules[schedule.ID] = &schedule
+		r.nextScheduleID = max(r.nextScheduleID, schedule.ID+1)
+	}
+	for i := range backup.Webhooks {
+		webhook := backup.Webhooks[i]
+		r.webhooks[webhook.ID] = &webhook
+		r.nextWebhookID = max(r.nextWebhookID, webhook.ID+1)
+	}
+	return nil
+}
+
+func containsLink(links []models.Link, id int) bool {
+	for _, link := range links {
+		if link.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsNote(notes []models.Note, id int) bool {
+	for _, note := range notes {
+		if note.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ListSchedules returns every schedule, oldest first
+func (r *InMemoryTaskRepository) ListSchedules() ([]models.Schedule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schedules := make([]models.Schedule, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		schedules = append(schedules, *schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule by ID, or nil when it does not exist
+func (r *InMemoryTaskRepository) GetSchedule(id int) (*models.Schedule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schedule, ok := r.schedules[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *schedule
+	return &copied, nil
+}
+
+// CreateSchedule stores a new schedule
+func (r *InMemoryTaskRepository) CreateSchedule(req *models.ScheduleRequest) (*models.Schedule, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	schedule := req.Schedule()
+	schedule.ID = r.nextScheduleID
+	schedule.CreatedAt = r.clock.Now()
+	schedule.SetNextDueDate()
+	r.nextScheduleID++
+	r.schedules[schedule.ID] = schedule
+	copied := *schedule
+	return &copied, nil
+}
+
+// DeleteSchedule removes a schedule; tasks it already generated are kept
+func (r *InMemoryTaskRepository) DeleteSchedule(id int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.schedules[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.schedules, id)
+	return nil
+}
+
+// SetScheduleGenerated records how many occurrences of a schedule have been
+// materialized
+func (r *InMemoryTaskRepository) SetScheduleGenerated(id, generated int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if schedule, ok := r.schedules[id]; ok {
+		schedule.Generated = generated
+		schedule.SetNextDueDate()
+	}
+	return nil
+}
+
+// ListWebhooks returns every webhook, oldest first
+func (r *InMemoryTaskRepository) ListWebhooks() ([]models.Webhook, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	webhooks := make([]models.Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, *webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook by ID, or nil when it does not exist
+func (r *InMemoryTaskRepository) GetWebhook(id int) (*models.Webhook, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *webhook
+	return &copied, nil
+}
+
+// CreateWebhook stores a new webhook
+func (r *InMemoryTaskRepository) CreateWebhook(req *models.WebhookRequest) (*models.Webhook, error) {
+	webhook, err := req.Webhook()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	webhook.ID = r.nextWebhookID
+	webhook.CreatedAt = r.clock.Now()
+	r.nextWebhookID++
+	r.webhooks[webhook.ID] = webhook
+	copied := *webhook
+	return &copied, nil
+}
+
+// DeleteWebhook removes a webhook
+func (r *InMemoryTaskRepository) DeleteWebhook(id int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.webhooks, id)
+	return nil
+}
+
+// ListLinks returns a task's links in the order they were added
+func (r *InMemoryTaskRepository) ListLinks(taskID int) ([]models.Link, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, exists := r.tasks[taskID]; !exists {
+		return nil, nil
+	}
+	return append([]models.Link{}, r.links[taskID]...), nil
+}
+
+// CreateLink attaches a new link to a task
+func (r *InMemoryTaskRepository) CreateLink(taskID int, req *models.LinkRequest) (*models.Link, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.tasks[taskID]; !exists {
+		return nil, nil
+	}
+
+	link := models.Link{
+		ID:        r.nextLinkID,
+		TaskID:    taskID,
+		Title:     req.Title,
+		URL:       req.URL,
+		CreatedAt: r.clock.Now(),
+	}
+	r.nextLinkID++
+	r.links[taskID] = append(r.links[taskID], link)
+
+	return &link, nil
+}
+
+// UpdateLink replaces a link's title and URL
+func (r *InMemoryTaskRepository) UpdateLink(taskID, linkID int, req *models.LinkRequest) (*models.Link, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	links := r.links[taskID]
+	for i := range links {
+		if links[i].ID == linkID {
+			updated := append([]models.Link{}, links...)
+			updated[i].Title = req.Title
+			updated[i].URL = req.URL
+			r.links[taskID] = updated
+			link := updated[i]
+			return &link, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteLink removes a link from a task
+func (r *InMemoryTaskRepository) DeleteLink(taskID, linkID int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	links := r.links[taskID]
+	for i := range links {
+		if links[i].ID == linkID {
+			r.links[taskID] = append(append([]models.Link{}, links[:i]...), links[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+// ListNotes returns a task's notes oldest first
+func (r *InMemoryTaskRepository) ListNotes(taskID int) ([]models.Note, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, exists := r.tasks[taskID]; !exists {
+		return nil, nil
+	}
+	return append([]models.Note{}, r.notes[taskID]...), nil
+}
+
+// AddNote appends a note to a task
+func (r *InMemoryTaskRepository) AddNote(taskID int, req *models.NoteRequest) (*models.Note, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.tasks[taskID]; !exists {
+		return nil, nil
+	}
+
+	note := models.Note{
+		ID:        r.nextNoteID,
+		TaskID:    taskID,
+		Body:      req.Body,
+		CreatedAt: r.clock.Now(),
+	}
+	r.nextNoteID++
+	r.notes[taskID] = append(r.notes[taskID], note)
+
+	return &note, nil
+}
+
+// memorySnapshot is the on-disk JSON format for the in-memory repository
+type memorySnapshot struct {
+	NextID int           `json:"next_id"`
+	Tasks  []models.Task `json:"tasks"`
+	Links  []models.Link `json:"links,omitempty"`
+	Notes  []models.Note `json:"notes,omitempty"`
+}
+
+// SaveSnapshot writes all tasks to path as JSON, replacing the file atomically
+func (r *InMemoryTaskRepository) SaveSnapshot(path string) error {
+	r.mutex.RLock()
+	snapshot := memorySnapshot{NextID: r.nextID, Tasks: make([]models.Task, 0, len(r.tasks))}
+	for _, id := range r.orderedIDs() {
+		snapshot.Tasks = append(snapshot.Tasks, *r.tasks[id])
+		snapshot.Links = append(snapshot.Links, r.links[id]...)
+		snapshot.Notes = append(snapshot.Notes, r.notes[id]...)
+	}
+	r.mutex.RUnlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSnapshot replaces the repository contents with a snapshot from path.
+// It reports false without error when the file does not exist.
+func (r *InMemoryTaskRepository) LoadSnapshot(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.tasks = make(map[int]*models.Task, len(snapshot.Tasks))
+	r.nextID = snapshot.NextID
+	for i := range snapshot.Tasks {
+		task := snapshot.Tasks[i]
+		r.tasks[task.ID] = &task
+		if task.ID >= r.nextID {
+			r.nextID = task.ID + 1
+		}
+		r.recordChange(task.ID, models.ChangeCreated)
+	}
+
+	r.links = make(map[int][]models.Link)
+	r.nextLinkID = 1
+	for _, link := range snapshot.Links {
+		r.links[link.TaskID] = append(r.links[link.TaskID], link)
+		if link.ID >= r.nextLinkID {
+			r.nextLinkID = link.ID + 1
+		}
+	}
+
+	r.notes = make(map[int][]models.Note)
+	r.nextNoteID = 1
+	for _, note := range snapshot.Notes {
+		r.notes[note.TaskID] = append(r.notes[note.TaskID], note)
+		if note.ID >= r.nextNoteID {
+			r.nextNoteID = note.ID + 1
+		}
+	}
+
+	return true, nil
+}
+
+// startSnapshots saves the repository to path every interval and once more
+// on SIGINT/SIGTERM before exiting
+func startSnapshots(repo *InMemoryTaskRepository, path string, interval time.Duration) {
+	save := func() {
+		if err := repo.SaveSnapshot(path); err != nil {
+			log.Printf("Error saving snapshot: %v", err)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			save()
+		}
+	}()
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		save()
+		log.Printf("Snapshot saved to %s", path)
+		os.Exit(0)
+	}()
 }
 
 func main() {
@@ -181,7 +1168,71 @@ func main() {
 
 	// Initialize in-memory repository
 	taskRepo := NewInMemoryTaskRepository()
-	taskHandler := handlers.NewTaskHandler(taskRepo)
+	eventBus := events.NewBus()
+	publishingRepo := events.NewPublishingTaskRepository(taskRepo, eventBus)
+	taskHandler := handlers.NewTaskHandler(publishingRepo)
+
+	// Publish the webhook signing keys so receivers can verify deliveries
+	webhookKeys, err := webhooks.ParseKeySet(os.Getenv("WEBHOOK_SIGNING_KEYS"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_SIGNING_KEYS: %v", err)
+	}
+	taskHandler.SetWebhookKeys(webhookKeys)
+
+	// Workspace policy for client-side encrypted task content
+	encryptionMode, err := models.ParseEncryptionMode(os.Getenv("ENCRYPTION_MODE"))
+	if err != nil {
+		log.Fatalf("Invalid ENCRYPTION_MODE: %v", err)
+	}
+	taskHandler.SetEncryptionMode(encryptionMode)
+
+	// Optional cap on the number of tasks, with warnings from 90%
+	taskHandler.SetEventBus(eventBus)
+	if v := os.Getenv("TASK_QUOTA"); v != "" {
+		quota, err := strconv.Atoi(v)
+		if err != nil || quota < 0 {
+			log.Fatalf("Invalid TASK_QUOTA %q: must be a non-negative number", v)
+		}
+		taskHandler.SetTaskQuota(quota)
+	}
+
+	// Refuse task writes that don't say which version they overwrite
+	if require, _ := strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH")); require {
+		taskHandler.SetRequireIfMatch(true)
+	}
+
+	// Materialize upcoming occurrences of recurring schedules in the background
+	generator := recurring.NewGenerator(publishingRepo, 0, 0)
+	taskHandler.SetRecurringGenerator(generator)
+	go generator.Run(context.Background())
+
+	// Deliver task events to registered webhooks in the background
+	dispatcher := webhooks.NewDispatcher(publishingRepo, eventBus, webhookKeys)
+	taskHandler.SetWebhookDispatcher(dispatcher)
+	go dispatcher.Run(context.Background())
+
+	// Optionally persist the repository to a JSON snapshot
+	// (SNAPSHOT_PATH, SNAPSHOT_INTERVAL e.g. "30s", default 1m)
+	restored := false
+	if snapshotPath := os.Getenv("SNAPSHOT_PATH"); snapshotPath != "" {
+		var err error
+		if restored, err = taskRepo.LoadSnapshot(snapshotPath); err != nil {
+			log.Fatalf("Failed to load snapshot: %v", err)
+		}
+		if restored {
+			log.Printf("Restored tasks from snapshot %s", snapshotPath)
+		}
+
+		interval := time.Minute
+		if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				log.Fatalf("Invalid SNAPSHOT_INTERVAL %q", v)
+			}
+			interval = d
+		}
+		startSnapshots(taskRepo, snapshotPath, interval)
+	}
 
 	// Create some sample data
 	sampleTasks := []*models.TaskRequest{
@@ -202,8 +1253,10 @@ func main() {
 		},
 	}
 
-	for _, taskReq := range sampleTasks {
-		taskRepo.Create(taskReq)
+	if !restored {
+		for _, taskReq := range sampleTasks {
+			taskRepo.Create(taskReq)
+		}
 	}
 
 	// Create router
@@ -212,20 +1265,132 @@ func main() {
 	// Apply middleware
 	router.Use(middleware.CORS)
 	router.Use(middleware.Logging)
+	router.Use(middleware.ContentNegotiation)
+	router.Use(middleware.UTF8)
+
+	// Serialize timestamps in the configured format (TIMESTAMP_FORMAT)
+	timestampFormat, err := middleware.TimestampFormat(os.Getenv("TIMESTAMP_FORMAT"))
+	if err != nil {
+		log.Fatalf("Invalid TIMESTAMP_FORMAT: %v", err)
+	}
+	router.Use(timestampFormat)
+
+	// Error bodies as JSON:API error documents for JSON:API clients
+	router.Use(middleware.JSONAPIErrors)
+
+	// Success bodies with or without the {message, data} wrapper
+	// (RESPONSE_ENVELOPE, overridden per request by ?envelope=)
+	envelope := true
+	if v := os.Getenv("RESPONSE_ENVELOPE"); v != "" {
+		if envelope, err = strconv.ParseBool(v); err != nil {
+			log.Fatalf("Invalid RESPONSE_ENVELOPE: %v", err)
+		}
+	}
+	router.Use(middleware.Envelope(envelope))
+
+	// Messages in the client's Accept-Language, from the built-in locales
+	// and any in LOCALES_DIR
+	catalog, err := i18n.New()
+	if err != nil {
+		log.Fatalf("Failed to load locales: %v", err)
+	}
+	if dir := os.Getenv("LOCALES_DIR"); dir != "" {
+		if err := catalog.LoadDir(dir); err != nil {
+			log.Fatalf("Failed to load LOCALES_DIR: %v", err)
+		}
+	}
+	router.Use(middleware.Localize(catalog))
+
+	// Deprecation, Sunset and Link headers on routes slated for removal
+	router.Use(middleware.Deprecations(openapi.Deprecations))
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
 
 	// Task routes
-	api.HandleFunc("/tasks", taskHandler.CreateTask).Methods("POST")
+	api.HandleFunc("/tasks", taskHandler.Idempotent(taskHandler.CreateTask)).Methods("POST")
 	api.HandleFunc("/tasks", taskHandler.GetTasks).Methods("GET")
+	api.HandleFunc("/tasks/overdue", taskHandler.GetOverdueTasks).Methods("GET")
+	api.HandleFunc("/tasks/today", taskHandler.GetTodayTasks).Methods("GET")
+	api.HandleFunc("/tasks/upcoming", taskHandler.GetUpcomingTasks).Methods("GET")
+	api.HandleFunc("/tasks/export.md", taskHandler.ExportMarkdown).Methods("GET")
+	api.HandleFunc("/planner/today", taskHandler.GetPlannerToday).Methods("GET")
+	api.HandleFunc("/feed.atom", taskHandler.GetActivityFeed).Methods("GET")
+	api.HandleFunc("/board", taskHandler.GetBoard).Methods("GET")
+	api.HandleFunc("/changes", taskHandler.GetChanges).Methods("GET")
+	api.HandleFunc("/stats", taskHandler.GetStats).Methods("GET")
+	api.HandleFunc("/stats/completions", taskHandler.GetCompletionStats).Methods("GET")
+	api.HandleFunc("/tasks", taskHandler.BulkDeleteTasks).Methods("DELETE")
+	api.HandleFunc("/tasks/bulk", taskHandler.Idempotent(taskHandler.BulkCreateTasks)).Methods("POST")
+	api.HandleFunc("/tasks/import", taskHandler.ImportTasks).Methods("POST")
+	api.HandleFunc("/tasks/import/todoist", taskHandler.ImportTodoist).Methods("POST")
+	api.HandleFunc("/tasks/bulk", taskHandler.BulkUpdateTasks).Methods("PATCH")
+	api.HandleFunc("/tasks/archive-completed", taskHandler.ArchiveCompletedTasks).Methods("POST")
+	api.HandleFunc("/tasks/transition", taskHandler.TransitionTasks).Methods("POST")
+	api.HandleFunc("/tasks/complete-all", taskHandler.CompleteAllTasks).Methods("POST")
+	api.HandleFunc("/undo", taskHandler.Undo).Methods("POST")
+	api.HandleFunc("/batch", taskHandler.Idempotent(taskHandler.Batch)).Methods("POST")
+	api.HandleFunc("/sync", taskHandler.Sync).Methods("POST")
+	api.HandleFunc("/tasks/external/{source}/{externalID}", taskHandler.UpsertExternalTask).Methods("PUT")
+	api.HandleFunc("/tasks/uuid/{uuid}", taskHandler.GetTaskByUUID).Methods("GET")
 	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.GetTask).Methods("GET")
 	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.UpdateTask).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.PatchTask).Methods("PATCH")
 	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.DeleteTask).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/move", taskHandler.MoveTask).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/duplicate", taskHandler.Idempotent(taskHandler.DuplicateTask)).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/pin", taskHandler.TogglePinTask).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/snooze", taskHandler.SnoozeTask).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links", taskHandler.ListTaskLinks).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links", taskHandler.CreateTaskLink).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}", taskHandler.UpdateTaskLink).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}/links/{linkID:[0-9]+}", taskHandler.DeleteTaskLink).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/notes", taskHandler.ListTaskNotes).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/notes", taskHandler.AddTaskNote).Methods("POST")
+	api.HandleFunc("/tasks/reorder", taskHandler.ReorderTasks).Methods("PUT")
+
+	// Recurring task schedules
+	api.HandleFunc("/schedules", taskHandler.ListSchedules).Methods("GET")
+	api.HandleFunc("/schedules", taskHandler.CreateSchedule).Methods("POST")
+	api.HandleFunc("/schedules/{id:[0-9]+}", taskHandler.GetSchedule).Methods("GET")
+	api.HandleFunc("/schedules/{id:[0-9]+}", taskHandler.DeleteSchedule).Methods("DELETE")
+
+	// Schema routes
+	api.HandleFunc("/schemas", taskHandler.ListSchemas).Methods("GET")
+	api.HandleFunc("/schemas/{name}", taskHandler.GetSchema).Methods("GET")
+
+	// Webhook routes
+	api.HandleFunc("/webhooks/keys", taskHandler.GetWebhookKeys).Methods("GET")
+	api.HandleFunc("/webhooks", taskHandler.ListWebhooks).Methods("GET")
+	api.HandleFunc("/webhooks", taskHandler.CreateWebhook).Methods("POST")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", taskHandler.GetWebhook).Methods("GET")
+	api.HandleFunc("/webhooks/{id:[0-9]+}", taskHandler.DeleteWebhook).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", taskHandler.ListWebhookDeliveries).Methods("GET")
+
+	// Live task events
+	api.HandleFunc("/ws", taskHandler.TaskEventsWebSocket).Methods("GET")
+	api.HandleFunc("/events", taskHandler.TaskEventsStream).Methods("GET")
+
+	// Workspace settings
+	api.HandleFunc("/workspace", taskHandler.GetWorkspace).Methods("GET")
+
+	// Operational runbook checks; in-memory storage has none of its own
+	diag := diagnostics.NewRunner()
+	diag.Add(diagnostics.EventBus(eventBus))
+	api.Handle("/admin/diagnostics", diag.Handler()).Methods("GET")
+	api.HandleFunc("/admin/backup", taskHandler.GetBackup).Methods("GET")
+	api.HandleFunc("/admin/restore", taskHandler.RestoreBackup).Methods("POST")
+
+	// OpenAPI document generated from the routes, with Swagger UI
+	api.Handle("/openapi.json", openapi.Handler(router, schemas.RequestBodies)).Methods("GET")
+	router.Handle("/docs", openapi.DocsHandler("/api/openapi.json")).Methods("GET")
 
 	// Health check route
 	router.HandleFunc("/health", taskHandler.HealthCheck).Methods("GET")
 
+	// Event bus subscribers with their lag and dropped events
+	router.Handle("/debug/events", eventBus.Handler()).Methods("GET")
+
 	// Root route for basic info
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -248,6 +1413,19 @@ func main() {
 		}`))
 	}).Methods("GET")
 
+	// Batch operations go through the router like any other request. Every
+	// other way in waits while a batch writes, as storage then runs every
+	// call in the batch's transaction.
+	taskHandler.SetBatchRouter(router)
+
+	// CalDAV sits beside the API router: it has its own methods, OPTIONS
+	// answers and XML bodies, which the API middleware would get in the way of
+	root := http.NewServeMux()
+	root.Handle("/caldav/", middleware.Logging(taskHandler.BatchLock(http.HandlerFunc(taskHandler.CalDAV))))
+	root.Handle("/.well-known/caldav", http.RedirectHandler("/caldav/", http.StatusMovedPermanently))
+	root.Handle("/", taskHandler.BatchLock(middleware.Methods(router)))
+	root.Handle("/mcp/", middleware.Logging(mcp.NewServer(taskHandler.BatchLock(router)).SSEHandler("/mcp")))
+
 	// Get port from environment variable or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -257,9 +1435,11 @@ func main() {
 	log.Printf("Server starting on port %s", port)
 	log.Printf("Health check: http://localhost:%s/health", port)
 	log.Printf("API documentation: http://localhost:%s/", port)
-	log.Printf("Sample tasks have been created for testing")
+	if !restored {
+		log.Printf("Sample tasks have been created for testing")
+	}
 
-	if err := http.ListenAndServe(":"+port, router); err != nil {
+	if err := http.ListenAndServe(":"+port, root); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
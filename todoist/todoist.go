@@ -0,0 +1,308 @@
+// Package todoist reads tasks exported from Todoist, or fetched with a
+// Todoist API token, and maps them onto this API's tasks.
+//
+// Todoist has concepts tasks here lack. Priorities become colors, matching
+// the ones Todoist shows (p1 red, p2 orange, p3 blue). Projects and labels
+// are kept as a note on the task. Recurrence, sub-task nesting and sections
+// are lost and reported as warnings. Deleted and empty items are skipped.
+package todoist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"to-do-api/models"
+)
+
+// Source is the external source imported tasks are upserted under, so a
+// second import updates the tasks of the first
+const Source = "todoist"
+
+// APIBaseURL is the Todoist API that tokens are used against
+var APIBaseURL = "https://api.todoist.com/api/v1"
+
+// fetchTimeout bounds fetching everything from the Todoist API
+const fetchTimeout = 30 * time.Second
+
+// ID is a Todoist ID. Older exports use numbers, newer ones strings.
+type ID string
+
+// UnmarshalJSON accepts a string or a number
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = ID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("todoist: ID must be a string or number, got %s", data)
+	}
+	*id = ID(n)
+	return nil
+}
+
+// Project is a Todoist project
+type Project struct {
+	ID   ID     `json:"id"`
+	Name string `json:"name"`
+}
+
+// Due is a Todoist due date. Date is a day (2024-01-05), a floating time
+// (2024-01-05T10:00:00) or a UTC time (2024-01-05T10:00:00Z).
+type Due struct {
+	Date        string `json:"date"`
+	Datetime    string `json:"datetime"`
+	Timezone    string `json:"timezone"`
+	IsRecurring bool   `json:"is_recurring"`
+}
+
+// Item is a Todoist task. Both the checked/is_deleted fields of exports and
+// the is_completed field of the REST API are understood.
+type Item struct {
+	ID          ID       `json:"id"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	ProjectID   ID       `json:"project_id"`
+	SectionID   ID       `json:"section_id"`
+	ParentID    ID       `json:"parent_id"`
+	Labels      []string `json:"labels"`
+	// Priority runs from 1 (normal) to 4 (urgent, shown as p1)
+	Priority    int  `json:"priority"`
+	Due         *Due `json:"due"`
+	Checked     bool `json:"checked"`
+	IsCompleted bool `json:"is_completed"`
+	IsDeleted   bool `json:"is_deleted"`
+}
+
+// Export is a Todoist backup or API dump: projects plus tasks, which are
+// called items in exports and tasks in the API
+type Export struct {
+	Projects []Project `json:"projects"`
+	Items    []Item    `json:"items"`
+	Tasks    []Item    `json:"tasks"`
+}
+
+// ParseExport reads an Export, or a bare array of tasks
+func ParseExport(data []byte) (*Export, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []Item
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		return &Export{Items: items}, nil
+	}
+	var export Export
+	if err := json.Unmarshal(trimmed, &export); err != nil {
+		return nil, err
+	}
+	export.Items = append(export.Items, export.Tasks...)
+	export.Tasks = nil
+	return &export, nil
+}
+
+// Fetch reads the user's projects and open tasks from the Todoist API.
+// Completed tasks are not listed by the API; use an export to bring them
+// along.
+func Fetch(ctx context.Context, token string) (*Export, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	export := &Export{}
+	if err := fetchAll(ctx, token, "/projects", &export.Projects); err != nil {
+		return nil, err
+	}
+	if err := fetchAll(ctx, token, "/tasks", &export.Items); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ErrUnauthorized reports a token that Todoist refused
+var ErrUnauthorized = errors.New("todoist: the API token was refused")
+
+// fetchAll follows the API's cursor pagination and decodes the results of
+// every page into out, a pointer to a slice
+func fetchAll(ctx context.Context, token, path string, out interface{}) error {
+	var results []json.RawMessage
+	cursor := ""
+	for {
+		u := APIBaseURL + path + "?limit=200"
+		if cursor != "" {
+			u += "&cursor=" + url.QueryEscape(cursor)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("todoist: %w", err)
+		}
+		var page struct {
+			Results    []json.RawMessage `json:"results"`
+			NextCursor string            `json:"next_cursor"`
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			err = ErrUnauthorized
+		case resp.StatusCode != http.StatusOK:
+			err = fmt.Errorf("todoist: %s returned %s", path, resp.Status)
+		default:
+			err = json.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		results = append(results, page.Results...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// priorityColors maps Todoist priorities to the colors Todoist shows them in
+var priorityColors = map[int]string{4: "red", 3: "orange", 2: "blue"}
+
+// Task is a Todoist item mapped onto this API
+type Task struct {
+	ExternalID string
+	Request    models.TaskRequest
+	// Note records the project and labels, which tasks have no field for
+	Note string
+}
+
+// Skipped is an item that was not imported
+type Skipped struct {
+	ID      string `json:"id"`
+	Content string `json:"content,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Convert maps the items of export onto tasks. Items it can't map are
+// returned as skipped, and warnings describe what was lost on the way.
+func Convert(export *Export) ([]Task, []Skipped, []string) {
+	projects := make(map[ID]string, len(export.Projects))
+	for _, p := range export.Projects {
+		projects[p.ID] = p.Name
+	}
+
+	var tasks []Task
+	var skipped []Skipped
+	var recurring, subtasks, sectioned int
+	for _, item := range export.Items {
+		switch {
+		case item.IsDeleted:
+			skipped = append(skipped, Skipped{ID: string(item.ID), Content: item.Content, Reason: "deleted in Todoist"})
+			continue
+		case item.ID == "":
+			skipped = append(skipped, Skipped{Content: item.Content, Reason: "has no ID"})
+			continue
+		case strings.TrimSpace(item.Content) == "":
+			skipped = append(skipped, Skipped{ID: string(item.ID), Reason: "has no content"})
+			continue
+		}
+
+		req := models.TaskRequest{
+			Title:       item.Content,
+			Description: item.Description,
+			Status:      "pending",
+			Color:       priorityColors[item.Priority],
+		}
+		if item.Checked || item.IsCompleted {
+			req.Status = "completed"
+		}
+		if item.Due != nil {
+			due, err := item.Due.Time()
+			if err != nil {
+				skipped = append(skipped, Skipped{ID: string(item.ID), Content: item.Content, Reason: err.Error()})
+				continue
+			}
+			req.DueDate = &due
+			if item.Due.IsRecurring {
+				recurring++
+			}
+		}
+		if item.ParentID != "" {
+			subtasks++
+		}
+		if item.SectionID != "" {
+			sectioned++
+		}
+
+		var note []string
+		if name, ok := projects[item.ProjectID]; ok {
+			note = append(note, "Todoist project: "+name)
+		}
+		if len(item.Labels) > 0 {
+			labels := append([]string(nil), item.Labels...)
+			sort.Strings(labels)
+			note = append(note, "Todoist labels: "+strings.Join(labels, ", "))
+		}
+		tasks = append(tasks, Task{ExternalID: string(item.ID), Request: req, Note: strings.Join(note, "\n")})
+	}
+
+	var warnings []string
+	if recurring > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d recurring tasks were imported with their next due date only", recurring))
+	}
+	if subtasks > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d sub-tasks were imported as top-level tasks", subtasks))
+	}
+	if sectioned > 0 {
+		warnings = append(warnings, fmt.Sprintf("sections were dropped from %d tasks", sectioned))
+	}
+	return tasks, skipped, warnings
+}
+
+// Time resolves the due date. Whole days become midnight UTC, which is how
+// all-day tasks are stored; floating times are read in the due date's time
+// zone, or UTC without one.
+func (d *Due) Time() (time.Time, error) {
+	if d.Datetime != "" {
+		if t, err := time.Parse(time.RFC3339, d.Datetime); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, d.Date); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", d.Date); err == nil {
+		return t, nil
+	}
+	loc := time.UTC
+	if d.Timezone != "" {
+		if l, err := time.LoadLocation(d.Timezone); err == nil {
+			loc = l
+		}
+	}
+	floating := d.Date
+	if floating == "" {
+		floating = d.Datetime
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", floating, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("due date %q is not a date Todoist uses", d.Date)
+}
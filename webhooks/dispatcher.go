@@ -0,0 +1,329 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"to-do-api/events"
+	"to-do-api/models"
+)
+
+// Delivery settings
+const (
+	// MaxAttempts is the number of times a delivery is tried before it is
+	// given up
+	MaxAttempts = 6
+	// firstRetry is the delay before the first retry; each later retry
+	// waits twice as long, up to maxRetryDelay
+	firstRetry    = 5 * time.Second
+	maxRetryDelay = 5 * time.Minute
+	// deliveryTimeout bounds a single attempt
+	deliveryTimeout = 10 * time.Second
+	// deliveryWorkers is the number of deliveries made concurrently
+	deliveryWorkers = 4
+	// queueSize is the number of deliveries waiting for a worker before
+	// new ones are dropped
+	queueSize = 1024
+	// logSize is the number of attempts kept per webhook
+	logSize = 50
+)
+
+// Delivery headers
+const (
+	EventHeader    = "Webhook-Event"
+	DeliveryHeader = "Webhook-Delivery"
+)
+
+// dispatchStats exposes delivery counters under /debug/vars
+var dispatchStats = expvar.NewMap("webhooks")
+
+// Store is the part of the task repository the dispatcher reads webhooks
+// from
+type Store interface {
+	ListWebhooks() ([]models.Webhook, error)
+	GetWebhook(id int) (*models.Webhook, error)
+}
+
+// Attempt records one delivery attempt
+type Attempt struct {
+	// DeliveryID is shared by every attempt to deliver the same event
+	DeliveryID  string     `json:"delivery_id"`
+	Event       string     `json:"event"`
+	Seq         uint64     `json:"seq"`
+	Attempt     int        `json:"attempt"`
+	Success     bool       `json:"success"`
+	StatusCode  int        `json:"status_code,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	DurationMs  int64      `json:"duration_ms"`
+	AttemptedAt time.Time  `json:"attempted_at"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+}
+
+// delivery is an event on its way to one webhook
+type delivery struct {
+	id        string
+	webhookID int
+	event     string
+	seq       uint64
+	body      []byte
+	attempt   int
+}
+
+// Dispatcher delivers bus events to the registered webhooks. Each delivery
+// is a signed JSON POST, retried with exponential backoff on network
+// errors, 5xx, 408 and 429. Pending deliveries are held in memory and are
+// lost on restart.
+type Dispatcher struct {
+	store  Store
+	bus    *events.Bus
+	keys   *KeySet
+	client *http.Client
+	queue  chan *delivery
+	nextID atomic.Uint64
+
+	mu   sync.Mutex
+	logs map[int][]Attempt
+
+	// storeLock, when set, is held while reading store
+	storeLock sync.Locker
+}
+
+// NewDispatcher creates a dispatcher for the webhooks in store. Deliveries
+// carry an HMAC signature with the webhook's secret and, when keys has a
+// current key, an Ed25519 signature too.
+func NewDispatcher(store Store, bus *events.Bus, keys *KeySet) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		bus:    bus,
+		keys:   keys,
+		client: &http.Client{Timeout: deliveryTimeout},
+		queue:  make(chan *delivery, queueSize),
+		logs:   make(map[int][]Attempt),
+	}
+}
+
+// SetLock sets a lock held while webhooks are read from the store, so
+// reads can be kept out while the store must not be used
+func (d *Dispatcher) SetLock(lock sync.Locker) {
+	d.storeLock = lock
+}
+
+// listWebhooks reads every webhook from the store
+func (d *Dispatcher) listWebhooks() ([]models.Webhook, error) {
+	if d.storeLock != nil {
+		d.storeLock.Lock()
+		defer d.storeLock.Unlock()
+	}
+	return d.store.ListWebhooks()
+}
+
+// getWebhook reads a webhook from the store
+func (d *Dispatcher) getWebhook(id int) (*models.Webhook, error) {
+	if d.storeLock != nil {
+		d.storeLock.Lock()
+		defer d.storeLock.Unlock()
+	}
+	return d.store.GetWebhook(id)
+}
+
+// Run subscribes to the bus and delivers events until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	// Webhooks should not lose events to a brief burst, so the bus waits
+	// a little for room before dropping
+	sub := d.bus.Subscribe(events.Options{Name: "webhooks", Buffer: queueSize, Policy: events.Block})
+	defer sub.Close()
+
+	for i := 0; i < deliveryWorkers; i++ {
+		go d.work(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			d.dispatch(ctx, e)
+		}
+	}
+}
+
+// dispatch queues e for every webhook subscribed to it
+func (d *Dispatcher) dispatch(ctx context.Context, e events.Event) {
+	types := []string{e.Type}
+	if e.CompletesTask() {
+		types = append(types, events.TaskCompleted)
+	}
+
+	hooks, err := d.listWebhooks()
+	if err != nil {
+		log.Printf("Webhooks: failed to list webhooks: %v", err)
+		return
+	}
+	for _, eventType := range types {
+		payload := e
+		payload.Type = eventType
+		body, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("Webhooks: failed to encode %s event: %v", eventType, err)
+			continue
+		}
+		for _, hook := range hooks {
+			if !hook.Wants(eventType) {
+				continue
+			}
+			d.enqueue(ctx, &delivery{
+				id:        "dlv_" + strconv.FormatUint(d.nextID.Add(1), 10),
+				webhookID: hook.ID,
+				event:     eventType,
+				seq:       e.Seq,
+				body:      body,
+				attempt:   1,
+			})
+		}
+	}
+}
+
+// enqueue hands dl to a worker, dropping it when the queue is full
+func (d *Dispatcher) enqueue(ctx context.Context, dl *delivery) {
+	if ctx.Err() != nil {
+		return
+	}
+	select {
+	case d.queue <- dl:
+	default:
+		dispatchStats.Add("dropped", 1)
+		d.record(dl.webhookID, Attempt{DeliveryID: dl.id, Event: dl.event, Seq: dl.seq, Attempt: dl.attempt, Error: "delivery queue full", AttemptedAt: time.Now().UTC()})
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case dl := <-d.queue:
+			d.deliver(ctx, dl)
+		}
+	}
+}
+
+// deliver makes one attempt and schedules a retry when it fails with a
+// retryable error
+func (d *Dispatcher) deliver(ctx context.Context, dl *delivery) {
+	hook, err := d.getWebhook(dl.webhookID)
+	if err != nil {
+		log.Printf("Webhooks: failed to load webhook %d: %v", dl.webhookID, err)
+		return
+	}
+	if hook == nil {
+		// Deleted since the event was queued
+		return
+	}
+
+	start := time.Now()
+	attempt := Attempt{DeliveryID: dl.id, Event: dl.event, Seq: dl.seq, Attempt: dl.attempt, AttemptedAt: start.UTC()}
+	status, err := d.post(ctx, hook, dl, start)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	attempt.StatusCode = status
+
+	retryable := false
+	switch {
+	case err != nil:
+		attempt.Error = err.Error()
+		retryable = true
+	case status >= 200 && status < 300:
+		attempt.Success = true
+	default:
+		attempt.Error = fmt.Sprintf("receiver responded %d", status)
+		retryable = status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+	}
+
+	switch {
+	case attempt.Success:
+		dispatchStats.Add("delivered", 1)
+	case retryable && dl.attempt < MaxAttempts && ctx.Err() == nil:
+		delay := retryDelay(dl.attempt)
+		next := start.Add(delay).UTC()
+		attempt.NextRetryAt = &next
+		dispatchStats.Add("retried", 1)
+		retry := *dl
+		retry.attempt++
+		time.AfterFunc(delay, func() { d.enqueue(ctx, &retry) })
+	default:
+		dispatchStats.Add("failed", 1)
+	}
+	d.record(hook.ID, attempt)
+}
+
+// post sends the delivery and returns the response status
+func (d *Dispatcher) post(ctx context.Context, hook *models.Webhook, dl *delivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "to-do-api-webhooks/1")
+	req.Header.Set(EventHeader, dl.event)
+	req.Header.Set(DeliveryHeader, dl.id)
+	req.Header.Set(HMACSignatureHeader, SignHMAC(hook.Secret, dl.body, now))
+	if key, ok := d.keys.Current(); ok {
+		req.Header.Set(SignatureHeader, Sign(key, dl.body, now))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// retryDelay is the wait after the given failed attempt
+func retryDelay(attempt int) time.Duration {
+	delay := firstRetry << (attempt - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// record appends an attempt to a webhook's log, keeping the latest logSize
+func (d *Dispatcher) record(webhookID int, attempt Attempt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	attempts := append(d.logs[webhookID], attempt)
+	if len(attempts) > logSize {
+		attempts = attempts[len(attempts)-logSize:]
+	}
+	d.logs[webhookID] = attempts
+}
+
+// Attempts returns a webhook's recent delivery attempts, newest first
+func (d *Dispatcher) Attempts(webhookID int) []Attempt {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	logged := d.logs[webhookID]
+	attempts := make([]Attempt, len(logged))
+	for i, a := range logged {
+		attempts[len(logged)-1-i] = a
+	}
+	return attempts
+}
+
+// Forget drops the delivery log of a deleted webhook
+func (d *Dispatcher) Forget(webhookID int) {
+	d.mu.Lock()
+	delete(d.logs, webhookID)
+	d.mu.Unlock()
+}
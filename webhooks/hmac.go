@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HMACSignatureHeader carries the HMAC-SHA256 signature of a delivery made
+// with the webhook's own secret:
+//
+//	t=1700000000,v1=<hex HMAC-SHA256 of "<t>.<body>">
+const HMACSignatureHeader = "Webhook-HMAC-Signature"
+
+// SignHMAC returns the HMAC signature header value for body using secret at
+// time now
+func SignHMAC(secret string, body []byte, now time.Time) string {
+	ts := now.Unix()
+	return "t=" + strconv.FormatInt(ts, 10) + ",v1=" + hex.EncodeToString(hmacSum(secret, ts, body))
+}
+
+// VerifyHMAC checks an HMAC signature header against body at time now,
+// allowing tolerance of clock drift (zero means DefaultTolerance)
+func VerifyHMAC(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts int64
+	var sig []byte
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedSignature
+		}
+		var err error
+		switch name {
+		case "t":
+			ts, err = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			sig, err = hex.DecodeString(value)
+		}
+		if err != nil {
+			return ErrMalformedSignature
+		}
+	}
+	if ts == 0 || len(sig) != sha256.Size {
+		return ErrMalformedSignature
+	}
+
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	signedAt := time.Unix(ts, 0)
+	if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+		return ErrTimestampOutOfRange
+	}
+	if !hmac.Equal(sig, hmacSum(secret, ts, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// hmacSum computes the HMAC-SHA256 of the signed payload
+func hmacSum(secret string, timestamp int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(signedPayload(timestamp, body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Key is a named Ed25519 signing key
+type Key struct {
+	ID      string
+	Private ed25519.PrivateKey
+}
+
+// PublicKey describes a verification key as published to receivers
+type PublicKey struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Key       string `json:"public_key"`
+}
+
+// KeySet holds the signing keys. The first key signs new deliveries; the
+// rest remain published so receivers can verify deliveries made before a
+// rotation.
+type KeySet struct {
+	keys []Key
+}
+
+// ParseKeySet parses a comma-separated list of "kid:base64-seed" entries,
+// as found in WEBHOOK_SIGNING_KEYS. An empty string yields an empty set.
+func ParseKeySet(spec string) (*KeySet, error) {
+	set := &KeySet{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("webhook key %q must be kid:base64-seed", entry)
+		}
+		seed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("webhook key %q must have a base64 %d-byte seed", id, ed25519.SeedSize)
+		}
+		set.keys = append(set.keys, Key{ID: id, Private: ed25519.NewKeyFromSeed(seed)})
+	}
+	return set, nil
+}
+
+// Current returns the key used to sign new deliveries
+func (s *KeySet) Current() (Key, bool) {
+	if s == nil || len(s.keys) == 0 {
+		return Key{}, false
+	}
+	return s.keys[0], true
+}
+
+// PublicKeys lists every key's public half for publication
+func (s *KeySet) PublicKeys() []PublicKey {
+	if s == nil {
+		return []PublicKey{}
+	}
+	keys := make([]PublicKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		pub := k.Private.Public().(ed25519.PublicKey)
+		keys = append(keys, PublicKey{ID: k.ID, Algorithm: "Ed25519", Key: base64.StdEncoding.EncodeToString(pub)})
+	}
+	return keys
+}
+
+// Verifier returns a Verifier that accepts signatures from any key in the set
+func (s *KeySet) Verifier() *Verifier {
+	v := &Verifier{Keys: make(map[string]ed25519.PublicKey)}
+	if s != nil {
+		for _, k := range s.keys {
+			v.Keys[k.ID] = k.Private.Public().(ed25519.PublicKey)
+		}
+	}
+	return v
+}
@@ -0,0 +1,160 @@
+// Package webhooks delivers task events to registered URLs, signs the
+// deliveries and verifies their signatures.
+//
+// Every delivery carries an HMAC-SHA256 signature made with the webhook's
+// own secret (see HMACSignatureHeader). When signing keys are configured,
+// deliveries also carry a Webhook-Signature header of the form
+//
+//	t=1700000000,kid=2024-01,sig=<base64 Ed25519 signature>
+//
+// where the signature covers "<t>.<body>". Receivers check it against the
+// public keys published at /api/webhooks/keys, reject timestamps outside a
+// tolerance window and, with a ReplayGuard, signatures they have already seen.
+package webhooks
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignatureHeader is the HTTP header carrying the delivery signature
+const SignatureHeader = "Webhook-Signature"
+
+// DefaultTolerance is how far a signature timestamp may drift from the
+// receiver's clock before the delivery is rejected
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMalformedSignature  = errors.New("webhook signature header is malformed")
+	ErrUnknownKey          = errors.New("webhook signature uses an unknown key")
+	ErrInvalidSignature    = errors.New("webhook signature does not match payload")
+	ErrTimestampOutOfRange = errors.New("webhook signature timestamp is outside the tolerance window")
+	ErrReplayed            = errors.New("webhook signature has already been used")
+)
+
+// signedPayload is the message covered by a signature
+func signedPayload(timestamp int64, body []byte) []byte {
+	prefix := strconv.FormatInt(timestamp, 10) + "."
+	return append([]byte(prefix), body...)
+}
+
+// Sign returns the signature header value for body using key at time now
+func Sign(key Key, body []byte, now time.Time) string {
+	ts := now.Unix()
+	sig := ed25519.Sign(key.Private, signedPayload(ts, body))
+	return "t=" + strconv.FormatInt(ts, 10) + ",kid=" + key.ID + ",sig=" + base64.StdEncoding.EncodeToString(sig)
+}
+
+// parsedSignature holds the fields of a signature header
+type parsedSignature struct {
+	timestamp int64
+	keyID     string
+	sig       []byte
+}
+
+// parseSignature splits a signature header into its fields
+func parseSignature(header string) (parsedSignature, error) {
+	var p parsedSignature
+	var haveTS bool
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return p, ErrMalformedSignature
+		}
+		switch name {
+		case "t":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return p, ErrMalformedSignature
+			}
+			p.timestamp, haveTS = ts, true
+		case "kid":
+			p.keyID = value
+		case "sig":
+			sig, err := base64.StdEncoding.DecodeString(value)
+			if err != nil || len(sig) != ed25519.SignatureSize {
+				return p, ErrMalformedSignature
+			}
+			p.sig = sig
+		}
+	}
+	if !haveTS || p.keyID == "" || p.sig == nil {
+		return p, ErrMalformedSignature
+	}
+	return p, nil
+}
+
+// Verifier checks delivery signatures against a set of public keys
+type Verifier struct {
+	// Keys maps key IDs to public keys; keep retired keys here until their
+	// last deliveries have aged out of the tolerance window
+	Keys map[string]ed25519.PublicKey
+	// Tolerance bounds clock drift; zero means DefaultTolerance
+	Tolerance time.Duration
+	// Replay, when set, rejects signatures that have been seen before
+	Replay *ReplayGuard
+}
+
+// Verify checks header against body at time now
+func (v *Verifier) Verify(header string, body []byte, now time.Time) error {
+	p, err := parseSignature(header)
+	if err != nil {
+		return err
+	}
+
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	signedAt := time.Unix(p.timestamp, 0)
+	if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+		return ErrTimestampOutOfRange
+	}
+
+	pub, ok := v.Keys[p.keyID]
+	if !ok {
+		return ErrUnknownKey
+	}
+	if !ed25519.Verify(pub, signedPayload(p.timestamp, body), p.sig) {
+		return ErrInvalidSignature
+	}
+
+	if v.Replay != nil && !v.Replay.remember(string(p.sig), signedAt.Add(tolerance), now) {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// ReplayGuard remembers verified signatures until they could no longer pass
+// the tolerance check, so each delivery is accepted at most once
+type ReplayGuard struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewReplayGuard creates an empty ReplayGuard
+func NewReplayGuard() *ReplayGuard {
+	return &ReplayGuard{seen: make(map[string]time.Time)}
+}
+
+// remember records sig until expiry and reports whether it was new
+func (g *ReplayGuard) remember(sig string, expiry time.Time, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for s, exp := range g.seen {
+		if now.After(exp) {
+			delete(g.seen, s)
+		}
+	}
+	if _, ok := g.seen[sig]; ok {
+		return false
+	}
+	g.seen[sig] = expiry
+	return true
+}